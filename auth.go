@@ -0,0 +1,87 @@
+package mcpio
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/auth"
+)
+
+// identityContextKey is the context key for the identity WithHTTPAuth's validator
+// returned for a request
+type identityContextKey struct{}
+
+// identityTokenInfoKey is the key the identity is stored under in the SDK's token
+// info, which the SDK carries from each HTTP request to the tool calls it contains
+const identityTokenInfoKey = "mcpio.identity"
+
+// IdentityFromContext returns the identity the WithHTTPAuth validator returned for
+// the caller, so tools can make per-user authorization decisions. It reports false
+// outside an authenticated HTTP or WebSocket request.
+func IdentityFromContext(ctx context.Context) (any, bool) {
+	if identity, ok := ctx.Value(identityContextKey{}).(identityValue); ok {
+		return identity.value, true
+	}
+	req, ok := RequestFromContext(ctx)
+	if !ok || req.Extra == nil || req.Extra.TokenInfo == nil {
+		return nil, false
+	}
+	identity, ok := req.Extra.TokenInfo.Extra[identityTokenInfoKey].(identityValue)
+	return identity.value, ok
+}
+
+// identityValue wraps an identity so a nil identity is still distinguishable from
+// an unauthenticated request
+type identityValue struct {
+	value any
+}
+
+// authorize checks the request's bearer token with the validator set by WithHTTPAuth,
+// returning the request with the validator's identity in its context. It writes a
+// 401 response and returns false when the token is missing or rejected. Without a
+// validator every request is allowed.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	if h.httpAuth == nil {
+		return r, true
+	}
+
+	if token, ok := bearerToken(r); ok {
+		if identity, err := h.httpAuth(token); err == nil {
+			ctx := context.WithValue(r.Context(), identityContextKey{}, identityValue{value: identity})
+			return r.WithContext(ctx), true
+		}
+	}
+
+	// The validator's error is not sent, so it can't leak why a token was rejected
+	w.Header().Set("WWW-Authenticate", `Bearer`)
+	http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+	return nil, false
+}
+
+// carryIdentity passes the identity authorize stored in each request's context on
+// to the SDK's token info, since the SDK runs tool calls outside the HTTP request's
+// context
+func carryIdentity(next http.Handler) http.Handler {
+	verifier := func(ctx context.Context, _ string, _ *http.Request) (*auth.TokenInfo, error) {
+		identity, _ := ctx.Value(identityContextKey{}).(identityValue)
+		return &auth.TokenInfo{
+			// The token was already validated; the SDK requires an expiration
+			Expiration: time.Now().Add(time.Minute),
+			Extra:      map[string]any{identityTokenInfoKey: identity},
+		}, nil
+	}
+	return auth.RequireBearerToken(verifier, nil)(next)
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header.
+// The scheme is matched case-insensitively, as RFC 6750 allows.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,202 @@
+package mcpio
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestWithHTTPAuth(t *testing.T) {
+	validate := func(token string) (any, error) {
+		if token != "secret" {
+			return nil, errors.New("token is not valid")
+		}
+		return "user-1", nil
+	}
+	handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc), WithHTTPAuth(validate))
+	require.NoError(t, err)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`
+
+	tests := []struct {
+		name          string
+		authorization string
+	}{
+		{"missing header", ""},
+		{"invalid token", "Bearer wrong"},
+		{"wrong scheme", "Basic c2VjcmV0"},
+		{"empty token", "Bearer "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(initialize))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "application/json, text/event-stream")
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+			assert.Equal(t, "Bearer", resp.Header.Get("WWW-Authenticate"))
+		})
+	}
+
+	t.Run("valid token reaches handler", func(t *testing.T) {
+		client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+		session, err := client.Connect(context.Background(), &mcp.StreamableClientTransport{
+			Endpoint:   server.URL,
+			HTTPClient: &http.Client{Transport: headerTransport{header: http.Header{"Authorization": {"Bearer secret"}}}},
+		}, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			assert.NoError(t, session.Close())
+		})
+
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "echo",
+			Arguments: map[string]any{"text": "hi"},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+	})
+
+	t.Run("websocket requires a token", func(t *testing.T) {
+		serverURL, results := serveWebSocket(t, handler)
+		wsURL := "ws" + strings.TrimPrefix(serverURL, "http")
+
+		_, err := websocket.Dial(wsURL, "", serverURL)
+		require.Error(t, err)
+		assert.ErrorIs(t, <-results, ErrUnauthorized)
+
+		config, err := websocket.NewConfig(wsURL, serverURL)
+		require.NoError(t, err)
+		config.Header.Set("Authorization", "Bearer secret")
+		conn, err := websocket.DialConfig(config)
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+	})
+
+	t.Run("scheme is case-insensitive", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Authorization", "bearer secret")
+		token, ok := bearerToken(req)
+		assert.True(t, ok)
+		assert.Equal(t, "secret", token)
+	})
+
+	t.Run("nil validator", func(t *testing.T) {
+		_, err := NewHandler(WithHTTPAuth(nil))
+		require.ErrorIs(t, err, ErrNilFunction)
+	})
+}
+
+// caller is the identity returned by the test validator
+type caller struct {
+	UserID string
+	Admin  bool
+}
+
+type WhoAmIOutput struct {
+	UserID string `json:"user_id"`
+	Admin  bool   `json:"admin"`
+}
+
+func TestIdentityFromContext(t *testing.T) {
+	tokens := map[string]caller{
+		"alice-token": {UserID: "alice", Admin: true},
+		"bob-token":   {UserID: "bob"},
+	}
+	validate := func(token string) (any, error) {
+		identity, ok := tokens[token]
+		if !ok {
+			return nil, errors.New("unknown token")
+		}
+		return identity, nil
+	}
+	whoami := func(ctx context.Context, input EchoInput) (WhoAmIOutput, error) {
+		identity, ok := IdentityFromContext(ctx)
+		if !ok {
+			return WhoAmIOutput{}, NewToolError("no identity")
+		}
+		c := identity.(caller)
+		return WhoAmIOutput{UserID: c.UserID, Admin: c.Admin}, nil
+	}
+
+	handler, err := NewHandler(WithTool("whoami", "Report the caller", whoami), WithHTTPAuth(validate))
+	require.NoError(t, err)
+
+	callWhoAmI := func(t *testing.T, session *mcp.ClientSession) string {
+		t.Helper()
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "whoami",
+			Arguments: map[string]any{"text": ""},
+		})
+		require.NoError(t, err)
+		require.False(t, result.IsError, resultText(t, result))
+		return resultText(t, result)
+	}
+
+	t.Run("http", func(t *testing.T) {
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+
+		for token, want := range map[string]string{
+			"alice-token": `{"user_id": "alice", "admin": true}`,
+			"bob-token":   `{"user_id": "bob", "admin": false}`,
+		} {
+			client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+			session, err := client.Connect(context.Background(), &mcp.StreamableClientTransport{
+				Endpoint:   server.URL,
+				HTTPClient: &http.Client{Transport: headerTransport{header: http.Header{"Authorization": {"Bearer " + token}}}},
+			}, nil)
+			require.NoError(t, err)
+			assert.JSONEq(t, want, callWhoAmI(t, session))
+			require.NoError(t, session.Close())
+		}
+	})
+
+	t.Run("websocket", func(t *testing.T) {
+		serverURL, _ := serveWebSocket(t, handler)
+		config, err := websocket.NewConfig("ws"+strings.TrimPrefix(serverURL, "http"), serverURL)
+		require.NoError(t, err)
+		config.Header.Set("Authorization", "Bearer bob-token")
+		conn, err := websocket.DialConfig(config)
+		require.NoError(t, err)
+
+		client := mcp.NewClient(&mcp.Implementation{Name: "ws-client", Version: "1.0.0"}, nil)
+		session, err := client.Connect(context.Background(), &WebSocketTransport{Conn: conn}, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			assert.NoError(t, session.Close())
+		})
+		assert.JSONEq(t, `{"user_id": "bob", "admin": false}`, callWhoAmI(t, session))
+	})
+
+	t.Run("unauthenticated transport", func(t *testing.T) {
+		session := connectTestClient(t, handler)
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "whoami",
+			Arguments: map[string]any{"text": ""},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+
+		_, ok := IdentityFromContext(context.Background())
+		assert.False(t, ok)
+	})
+}
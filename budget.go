@@ -0,0 +1,37 @@
+package mcpio
+
+import (
+	"context"
+	"time"
+)
+
+// Budget divides a context's remaining time among the sequential steps of a tool,
+// e.g. several calls to external services
+type Budget struct {
+	ctx context.Context
+}
+
+// DeadlineBudget returns a budget over the time remaining until ctx's deadline
+func DeadlineBudget(ctx context.Context) *Budget {
+	return &Budget{ctx: ctx}
+}
+
+// Next returns a context for the next step with a fraction of the time remaining
+// when it is called, so each step's share is taken from what earlier steps left.
+// Fractions are clamped to [0, 1]. The step's context is always bound by the overall
+// deadline, and has no deadline of its own when the budget's context has none.
+func (b *Budget) Next(fraction float64) (context.Context, context.CancelFunc) {
+	deadline, ok := b.ctx.Deadline()
+	if !ok {
+		return context.WithCancel(b.ctx)
+	}
+
+	switch {
+	case fraction > 1:
+		fraction = 1
+	case !(fraction > 0): // Also catches NaN
+		fraction = 0
+	}
+	remaining := time.Until(deadline)
+	return context.WithTimeout(b.ctx, time.Duration(float64(remaining)*fraction))
+}
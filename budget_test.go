@@ -0,0 +1,81 @@
+package mcpio
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadlineBudget(t *testing.T) {
+	const total = 10 * time.Second
+	// Allow for the time the test itself takes between steps
+	const slack = 50 * time.Millisecond
+
+	stepTime := func(t *testing.T, ctx context.Context) time.Duration {
+		t.Helper()
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok)
+		return time.Until(deadline)
+	}
+
+	t.Run("successive steps shrink", func(t *testing.T) {
+		const short = 400 * time.Millisecond
+		ctx, cancel := context.WithTimeout(context.Background(), short)
+		defer cancel()
+		budget := DeadlineBudget(ctx)
+
+		first, cancelFirst := budget.Next(0.5)
+		defer cancelFirst()
+		assert.InDelta(t, float64(short/2), float64(stepTime(t, first)), float64(slack))
+
+		// The first step uses its whole share, leaving half the budget
+		<-first.Done()
+		second, cancelSecond := budget.Next(0.5)
+		defer cancelSecond()
+		assert.InDelta(t, float64(short/4), float64(stepTime(t, second)), float64(slack))
+	})
+
+	t.Run("steps respect the overall deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), total)
+		defer cancel()
+		overall, _ := ctx.Deadline()
+		budget := DeadlineBudget(ctx)
+
+		for _, fraction := range []float64{0.25, 1, 2, math.Inf(1)} {
+			step, cancelStep := budget.Next(fraction)
+			deadline, ok := step.Deadline()
+			require.True(t, ok)
+			assert.False(t, deadline.After(overall), "fraction %v", fraction)
+			cancelStep()
+		}
+
+		cancel()
+		step, cancelStep := budget.Next(0.5)
+		defer cancelStep()
+		assert.ErrorIs(t, step.Err(), context.Canceled)
+	})
+
+	t.Run("non-positive fractions expire immediately", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), total)
+		defer cancel()
+
+		for _, fraction := range []float64{0, -1, math.NaN()} {
+			step, cancelStep := DeadlineBudget(ctx).Next(fraction)
+			<-step.Done()
+			assert.ErrorIs(t, step.Err(), context.DeadlineExceeded, "fraction %v", fraction)
+			cancelStep()
+		}
+	})
+
+	t.Run("no deadline", func(t *testing.T) {
+		step, cancelStep := DeadlineBudget(context.Background()).Next(0.5)
+		_, ok := step.Deadline()
+		assert.False(t, ok)
+		cancelStep()
+		assert.ErrorIs(t, step.Err(), context.Canceled)
+	})
+}
@@ -0,0 +1,72 @@
+package mcpio
+
+import "github.com/google/jsonschema-go/jsonschema"
+
+// Builder assembles handler configuration step by step, as an alternative to
+// passing every option to NewHandler at once. It is convenient when tools are
+// added conditionally or in loops:
+//
+//	b := mcpio.NewBuilder().Name("files").Version("1.0.0")
+//	if cfg.AllowWrites {
+//		b.Tool("write_file", "Write a file", writeFile)
+//	}
+//	handler, err := b.Build()
+//
+// Every method records an Option; nothing is validated until Build.
+type Builder struct {
+	opts []Option
+}
+
+// NewBuilder returns an empty Builder
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Name sets the server name, like WithName
+func (b *Builder) Name(name string) *Builder {
+	return b.With(WithName(name))
+}
+
+// Version sets the server version, like WithVersion
+func (b *Builder) Version(version string) *Builder {
+	return b.With(WithVersion(version))
+}
+
+// Instructions sets the server instructions, like WithInstructions
+func (b *Builder) Instructions(instructions string) *Builder {
+	return b.With(WithInstructions(instructions))
+}
+
+// Tool adds a typed tool. fn must have the shape of a ToolFunc:
+// func(context.Context, TIn) (TOut, error). As with Handler.RegisterTool, the
+// input and output types are read from fn with reflection.
+func (b *Builder) Tool(name, description string, fn any) *Builder {
+	return b.With(withReflectTool(name, description, fn))
+}
+
+// RawTool adds a raw JSON tool, like WithRawTool
+func (b *Builder) RawTool(name, description string, inputSchema *jsonschema.Schema, fn RawToolFunc) *Builder {
+	return b.With(WithRawTool(name, description, inputSchema, fn))
+}
+
+// Resource adds a resource, like WithResource
+func (b *Builder) Resource(uri, name, description, mimeType string, read ResourceReadFunc) *Builder {
+	return b.With(WithResource(uri, name, description, mimeType, read))
+}
+
+// Prompt adds a prompt, like WithPrompt
+func (b *Builder) Prompt(name, description string, args []PromptArg, fn PromptFunc) *Builder {
+	return b.With(WithPrompt(name, description, args, fn))
+}
+
+// With adds arbitrary options, for settings that have no dedicated method
+func (b *Builder) With(opts ...Option) *Builder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Build validates the accumulated configuration and creates the handler. It
+// returns the same errors NewHandler would for the equivalent options.
+func (b *Builder) Build() (*Handler, error) {
+	return NewHandler(b.opts...)
+}
@@ -0,0 +1,87 @@
+package mcpio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder(t *testing.T) {
+	build := func(withCalculator bool) (*Handler, error) {
+		b := NewBuilder().
+			Name("builder-server").
+			Version("2.0.0").
+			Tool("echo", "Echo input", echoFunc)
+		if withCalculator {
+			b.Tool("calculate", "Perform arithmetic", calculateFunc)
+		}
+		return b.Build()
+	}
+
+	toolNames := func(h *Handler) []string {
+		names := make([]string, 0)
+		for _, tool := range h.Tools() {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+
+	t.Run("conditional tool included", func(t *testing.T) {
+		handler, err := build(true)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"echo", "calculate"}, toolNames(handler))
+
+		session := connectTestClient(t, handler)
+		assert.Equal(t, "builder-server", session.InitializeResult().ServerInfo.Name)
+		assert.Equal(t, "2.0.0", session.InitializeResult().ServerInfo.Version)
+
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "calculate",
+			Arguments: map[string]any{"operation": "multiply", "a": 4, "b": 5},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+		assert.JSONEq(t, `{"result": 20}`, resultText(t, result))
+	})
+
+	t.Run("conditional tool excluded", func(t *testing.T) {
+		handler, err := build(false)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"echo"}, toolNames(handler))
+	})
+
+	t.Run("tools added in a loop", func(t *testing.T) {
+		b := NewBuilder()
+		for _, name := range []string{"first", "second", "third"} {
+			b.RawTool(name, "Process "+name, CreateObjectSchema("Raw input", nil, nil), rawFunc)
+		}
+		handler, err := b.With(WithToolTimeout("second", time.Second)).Build()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"first", "second", "third"}, toolNames(handler))
+	})
+
+	t.Run("errors are reported on build", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			builder *Builder
+			wantErr error
+		}{
+			{"empty name", NewBuilder().Name(""), ErrEmptyName},
+			{"invalid tool function", NewBuilder().Tool("bad", "Bad tool", func() {}), ErrInvalidToolFunc},
+			{"nil tool function", NewBuilder().Tool("bad", "Bad tool", nil), ErrNilFunction},
+			{"duplicate tool", NewBuilder().Tool("echo", "Echo", echoFunc).Tool("echo", "Echo", echoFunc), ErrDuplicateTool},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				handler, err := tt.builder.Build()
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, handler)
+			})
+		}
+	})
+}
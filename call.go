@@ -0,0 +1,341 @@
+package mcpio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// requestContextKey is the context key for the tool call request being served
+type requestContextKey struct{}
+
+// withRequest returns a context carrying the tool call request, so the call chain
+// can inspect it even though tool functions only receive their decoded input
+func withRequest(ctx context.Context, req *mcp.CallToolRequest) context.Context {
+	return context.WithValue(ctx, requestContextKey{}, req)
+}
+
+// RequestFromContext returns the tool call request being served, for tool functions
+// that need request fields their typed input doesn't carry, such as the tool name
+// or the progress token in the request metadata. It reports false outside a tool
+// call.
+func RequestFromContext(ctx context.Context) (*mcp.CallToolRequest, bool) {
+	req, ok := ctx.Value(requestContextKey{}).(*mcp.CallToolRequest)
+	return req, ok && req != nil
+}
+
+// ClientCapabilitiesFromContext returns the capabilities the calling client
+// advertised during initialization, so a tool can adapt to what the client
+// supports, e.g. whether it can handle sampling requests. It returns nil outside a
+// tool call or if the client sent no capabilities.
+func ClientCapabilitiesFromContext(ctx context.Context) *mcp.ClientCapabilities {
+	req, ok := RequestFromContext(ctx)
+	if !ok || req.Session == nil {
+		return nil
+	}
+	params := req.Session.InitializeParams()
+	if params == nil {
+		return nil
+	}
+	return params.Capabilities
+}
+
+// callFunc executes a single tool invocation and returns the tool's output
+type callFunc func(ctx context.Context) (any, error)
+
+// callChain applies per-call behavior, such as timeouts, around every tool function.
+// It is built once from the handler configuration and shared by all tools.
+type callChain struct {
+	timeouts       map[string]time.Duration // Per-tool timeouts, keyed by tool name
+	defaultTimeout time.Duration            // Applies to tools without a per-tool timeout; zero disables it
+	stats          *toolRecorder
+	calls          *callTracker // In-flight calls, drained by Handler.Shutdown
+	// inputValidation reports whether raw tool arguments are validated against
+	// the tool's input schema before the tool function is called
+	inputValidation bool
+	logger          *slog.Logger // Optional; nil disables logging
+	metrics         Metrics      // Optional; nil disables metrics
+	tracer          trace.Tracer // Optional; nil disables tracing
+	quota           QuotaStore   // Optional; nil disables quotas
+	clientID        func(ctx context.Context) string
+	rateLimiter     *rateLimiter // Optional; nil disables rate limits
+	codec           Codec        // Optional; nil passes raw tool payloads as JSON
+	events          chan<- Event // Optional; nil publishes no events
+	// slots holds one token per running call when concurrency is limited; nil
+	// disables the limit
+	slots          chan struct{}
+	rejectOverload bool // Fail calls when no slot is free instead of waiting
+}
+
+// newCallChain builds the call chain from the handler configuration
+func newCallChain(cfg *handlerConfig) *callChain {
+	var slots chan struct{}
+	if cfg.maxConcurrentCalls > 0 {
+		slots = make(chan struct{}, cfg.maxConcurrentCalls)
+	}
+	clientID := cfg.clientIdentifier
+	if clientID == nil {
+		clientID = SessionClientID
+	}
+	return &callChain{
+		timeouts:        cfg.toolTimeouts,
+		defaultTimeout:  cfg.defaultToolTimeout,
+		stats:           newToolRecorder(),
+		calls:           newCallTracker(),
+		inputValidation: !cfg.disableInputValidation,
+		logger:          cfg.logger,
+		metrics:         cfg.metrics,
+		tracer:          cfg.tracer,
+		quota:           cfg.quota,
+		clientID:        clientID,
+		rateLimiter:     newRateLimiter(cfg.rateLimits, cfg.defaultRateLimit),
+		codec:           cfg.codec,
+		events:          cfg.events,
+		slots:           slots,
+		rejectOverload:  cfg.rejectOverload,
+	}
+}
+
+// call runs the named tool's invocation through the chain
+func (c *callChain) call(ctx context.Context, name string, next callFunc) (any, error) {
+	ctx, span := c.startSpan(ctx, name)
+	defer span.End()
+
+	var output any
+	var err error
+	ctx, callID, accepted := c.calls.enter(ctx)
+	done := func() {}
+	if accepted {
+		defer c.calls.exit(callID)
+		done = c.calls.done
+	} else {
+		err = NewToolErrorWithCode(fmt.Sprintf("tool %q rejected: server is shutting down", name), "SHUTTING_DOWN")
+	}
+
+	c.logStart(ctx, name)
+	publishEvent(c.events, Event{Type: EventCallStarted, Tool: name, CallID: callID})
+	if c.metrics != nil {
+		c.metrics.IncCall(name)
+	}
+	start := time.Now()
+
+	if err == nil && c.quota != nil {
+		err = checkQuota(ctx, c.quota, c.clientID(ctx), name)
+	}
+	if err == nil && c.rateLimiter != nil {
+		err = c.rateLimiter.check(c.clientID(ctx), name)
+	}
+	var release func()
+	if err == nil {
+		release, err = c.acquire(ctx, name)
+	}
+	if err == nil {
+		// The slot is released and the call marked finished when the tool returns
+		// rather than when a timeout gives up on it, so a tool still running past
+		// its timeout keeps counting against the limit and Shutdown waits for it
+		run := func(ctx context.Context) (any, error) {
+			defer done()
+			defer release()
+			return next(ctx)
+		}
+		if timeout := c.timeout(name); timeout > 0 {
+			output, err = callWithTimeout(ctx, name, timeout, run)
+		} else {
+			output, err = run(ctx)
+		}
+	} else {
+		done()
+	}
+	if err != nil && errors.Is(context.Cause(ctx), ErrCallCancelled) {
+		err = NewToolErrorWithCode(fmt.Sprintf("tool %q call %s was cancelled", name, callID), "CANCELLED")
+	}
+
+	duration := time.Since(start)
+	c.stats.record(name, duration, err)
+	c.logEnd(ctx, name, duration, err)
+	finished := Event{Type: EventCallFinished, Tool: name, CallID: callID, Duration: duration, Err: err}
+	if err != nil {
+		finished.ErrorKind = errorKind(err)
+	}
+	publishEvent(c.events, finished)
+	if c.metrics != nil {
+		c.metrics.ObserveLatency(name, duration)
+		if err != nil {
+			c.metrics.IncError(name, errorKind(err))
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("mcp.tool.error_kind", errorKind(err)))
+	}
+	return output, err
+}
+
+// startSpan starts a span named after the tool as a child of any span in ctx.
+// Without a tracer it returns ctx unchanged and a no-op span.
+func (c *callChain) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	if c.tracer == nil {
+		return ctx, noop.Span{}
+	}
+
+	attrs := []attribute.KeyValue{attribute.String("mcp.tool.name", name)}
+	if req, ok := RequestFromContext(ctx); ok && req.Params != nil {
+		attrs = append(attrs, attribute.Int("mcp.tool.input_size", len(req.Params.Arguments)))
+	}
+	return c.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// Error kinds reported to logs and metrics
+const (
+	errorKindTool     = "tool"     // A ToolError, returned to the model as an IsError result
+	errorKindProtocol = "protocol" // Any other error, returned to the client as a JSON-RPC error
+)
+
+// errorKind classifies an error returned by a tool function
+func errorKind(err error) string {
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) {
+		return errorKindTool
+	}
+	return errorKindProtocol
+}
+
+// logStart records the start of a tool call
+func (c *callChain) logStart(ctx context.Context, name string) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.DebugContext(ctx, "tool call started", logAttrs(ctx, name)...)
+}
+
+// logEnd records the end of a tool call, classifying any error as a tool error
+// (returned to the model) or a protocol error (returned to the client)
+func (c *callChain) logEnd(ctx context.Context, name string, duration time.Duration, err error) {
+	if c.logger == nil {
+		return
+	}
+	attrs := append(logAttrs(ctx, name), "duration", duration)
+	if err == nil {
+		c.logger.InfoContext(ctx, "tool call finished", attrs...)
+		return
+	}
+
+	kind := errorKind(err)
+	level := slog.LevelError
+	if kind == errorKindTool {
+		level = slog.LevelWarn
+	}
+	c.logger.Log(ctx, level, "tool call finished", append(attrs, "error_type", kind, "error", err)...)
+}
+
+// logAttrs returns the attributes identifying a tool call in log records
+func logAttrs(ctx context.Context, name string) []any {
+	if id, ok := CallIDFromContext(ctx); ok {
+		return []any{"tool", name, "call_id", id}
+	}
+	return []any{"tool", name}
+}
+
+// validateInput validates tool arguments like the package-level validateInput, and
+// logs the cause when the schema itself couldn't be applied
+func (c *callChain) validateInput(ctx context.Context, name string, inputSchema *jsonschema.Resolved, inputJSON []byte) *ToolError {
+	toolErr := validateInput(inputSchema, inputJSON)
+	if toolErr != nil && errors.Is(toolErr, ErrSchemaResolution) && c.logger != nil {
+		c.logger.ErrorContext(ctx, "tool input schema could not be applied", "tool", name, "error", toolErr.Err)
+	}
+	return toolErr
+}
+
+// acquire claims a slot for a call when concurrency is limited, waiting for one
+// to free up unless overload rejection is enabled. The returned function releases
+// the slot.
+func (c *callChain) acquire(ctx context.Context, name string) (func(), error) {
+	if c.slots == nil {
+		return func() {}, nil
+	}
+	release := func() { <-c.slots }
+
+	if c.rejectOverload {
+		select {
+		case c.slots <- struct{}{}:
+			return release, nil
+		default:
+			return nil, NewToolErrorWithCode(
+				fmt.Sprintf("tool %q rejected: server is at its limit of %d concurrent calls", name, cap(c.slots)),
+				"OVERLOADED")
+		}
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// timeout returns the timeout for the named tool; a per-tool timeout takes
+// precedence over the default
+func (c *callChain) timeout(name string) time.Duration {
+	if timeout, ok := c.timeouts[name]; ok {
+		return timeout
+	}
+	return c.defaultTimeout
+}
+
+// callWithTimeout runs next in a goroutine bounded by timeout. If the deadline fires
+// first, the tool's context is cancelled and a TIMEOUT tool error is returned; the
+// in-flight result is discarded when the goroutine finishes.
+func callWithTimeout(parent context.Context, name string, timeout time.Duration, next callFunc) (any, error) {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	type callResult struct {
+		output any
+		err    error
+	}
+	// Buffered so the goroutine can always deliver its result and exit
+	done := make(chan callResult, 1)
+	go func() {
+		output, err := next(ctx)
+		done <- callResult{output: output, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.output, result.err
+	case <-ctx.Done():
+		// A cancelled or expired parent is the caller's error, not a tool timeout
+		if err := parent.Err(); err != nil {
+			return nil, err
+		}
+		return nil, NewToolErrorWithCode(fmt.Sprintf("tool %q exceeded %s timeout", name, timeout), "TIMEOUT")
+	}
+}
+
+// wrapToolFunc runs a tool function through the call chain for the named tool
+func wrapToolFunc[TIn, TOut any](chain *callChain, name string, fn ToolFunc[TIn, TOut]) ToolFunc[TIn, TOut] {
+	return func(ctx context.Context, input TIn) (TOut, error) {
+		output, err := chain.call(ctx, name, func(ctx context.Context) (any, error) {
+			return fn(ctx, input)
+		})
+		if err != nil {
+			var zero TOut
+			return zero, err
+		}
+		// A nil output from a tool whose TOut is an interface asserts to nothing,
+		// so fall back to the zero value instead of panicking
+		out, _ := output.(TOut)
+		return out, nil
+	}
+}
@@ -0,0 +1,99 @@
+package mcpio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Client calls the tools of an MCP server, such as one built with Handler. It is
+// safe for concurrent use.
+type Client struct {
+	session *mcp.ClientSession
+}
+
+// NewClient connects to the MCP server on the other end of transport, e.g. an
+// mcp.StreamableClientTransport for a server at a URL
+func NewClient(transport mcp.Transport) (*Client, error) {
+	if transport == nil {
+		return nil, ErrNilTransport
+	}
+
+	// The session outlives any single call, so it isn't tied to a caller's context
+	client := mcp.NewClient(&mcp.Implementation{Name: "mcpio-client", Version: "1.0.0"}, nil)
+	session, err := client.Connect(context.Background(), transport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to server: %w", err)
+	}
+	return &Client{session: session}, nil
+}
+
+// ConnectInMemory connects a Client to h over an in-memory transport, so tests can
+// exercise the full MCP round trip, including initialization, without sockets
+func ConnectInMemory(h *Handler) (*Client, error) {
+	if h.closed.Load() {
+		return nil, ErrHandlerClosed
+	}
+
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	if _, err := h.server.Connect(context.Background(), serverTransport, nil); err != nil {
+		return nil, fmt.Errorf("connecting in-memory session: %w", err)
+	}
+	return NewClient(clientTransport)
+}
+
+// CallTool calls the named tool with args, which are encoded as JSON, and returns
+// its output: the structured content if the tool returned any, or else its text
+// content, which is returned as a JSON string if it isn't JSON itself. Output with
+// other kinds of content, such as images, returns ErrUnsupportedContent. A tool
+// that reports an error returns a *ToolError with the error's message and code.
+func (c *Client) CallTool(ctx context.Context, name string, args any) (json.RawMessage, error) {
+	res, err := c.session.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		return nil, err
+	}
+
+	text, textOnly := resultTextContent(res)
+	if res.IsError {
+		code, _ := res.Meta["errorCode"].(string)
+		return nil, &ToolError{Message: text, Code: code}
+	}
+
+	if res.StructuredContent != nil {
+		output, err := json.Marshal(res.StructuredContent)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling structured content: %w", err)
+		}
+		return output, nil
+	}
+	if !textOnly {
+		return nil, fmt.Errorf("%w: tool %q returned content other than text", ErrUnsupportedContent, name)
+	}
+	if json.Valid([]byte(text)) {
+		return json.RawMessage(text), nil
+	}
+	return json.Marshal(text)
+}
+
+// ListTools returns every tool the server offers, following pagination
+func (c *Client) ListTools(ctx context.Context) ([]ToolInfo, error) {
+	var tools []ToolInfo
+	for tool, err := range c.session.Tools(ctx, nil) {
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, ToolInfo{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.InputSchema,
+		})
+	}
+	return tools, nil
+}
+
+// Close ends the session with the server
+func (c *Client) Close() error {
+	return c.session.Close()
+}
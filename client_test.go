@@ -0,0 +1,129 @@
+package mcpio
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	handler, err := NewHandler(
+		WithTool("echo", "Echo input", echoFunc),
+		WithTool("calculate", "Perform arithmetic", calculateFunc),
+		WithRawTool("process", "Process raw data", CreateObjectSchema("Raw input", nil, nil), rawFunc),
+	)
+	require.NoError(t, err)
+
+	client, err := ConnectInMemory(handler)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, client.Close())
+	})
+	ctx := context.Background()
+
+	t.Run("list tools", func(t *testing.T) {
+		tools, err := client.ListTools(ctx)
+		require.NoError(t, err)
+
+		names := make([]string, len(tools))
+		for i, tool := range tools {
+			names[i] = tool.Name
+		}
+		assert.ElementsMatch(t, []string{"echo", "calculate", "process"}, names)
+		for _, tool := range tools {
+			if tool.Name == "echo" {
+				assert.Equal(t, "Echo input", tool.Description)
+				require.NotNil(t, tool.InputSchema)
+				assert.Contains(t, tool.InputSchema.Properties, "text")
+			}
+		}
+	})
+
+	tests := []struct {
+		name string
+		tool string
+		args any
+		want string
+	}{
+		{"structured output", "echo", EchoInput{Text: "hi"}, `{"message": "hi"}`},
+		{"map arguments", "calculate", map[string]any{"operation": "add", "a": 1, "b": 2}, `{"result": 3}`},
+		{"raw JSON output", "process", map[string]any{}, `{"result": "processed"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := client.CallTool(ctx, tt.tool, tt.args)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(output))
+		})
+	}
+
+	t.Run("tool error", func(t *testing.T) {
+		_, err := client.CallTool(ctx, "calculate", map[string]any{"operation": "modulo", "a": 1, "b": 2})
+		var toolErr *ToolError
+		require.ErrorAs(t, err, &toolErr)
+		assert.Equal(t, "VALIDATION_ERROR", toolErr.Code)
+		assert.Equal(t, "unsupported operation: modulo", toolErr.Message)
+	})
+
+	t.Run("unknown tool", func(t *testing.T) {
+		_, err := client.CallTool(ctx, "missing", nil)
+		require.Error(t, err)
+		var toolErr *ToolError
+		assert.False(t, errors.As(err, &toolErr))
+	})
+
+	t.Run("nil transport", func(t *testing.T) {
+		_, err := NewClient(nil)
+		require.ErrorIs(t, err, ErrNilTransport)
+	})
+}
+
+func TestConnectInMemory(t *testing.T) {
+	handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc))
+	require.NoError(t, err)
+
+	client, err := ConnectInMemory(handler)
+	require.NoError(t, err)
+	output, err := client.CallTool(context.Background(), "echo", map[string]any{"text": "hello"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"message": "hello"}`, string(output))
+	require.NoError(t, client.Close())
+
+	t.Run("closed handler", func(t *testing.T) {
+		require.NoError(t, handler.Close())
+		_, err := ConnectInMemory(handler)
+		require.ErrorIs(t, err, ErrHandlerClosed)
+	})
+}
+
+func TestClientContent(t *testing.T) {
+	// Servers not built with mcpio may return plain text or other content
+	server := mcp.NewServer(&mcp.Implementation{Name: "other", Version: "1.0.0"}, nil)
+	schema := CreateObjectSchema("No input", nil, nil)
+	server.AddTool(&mcp.Tool{Name: "plain", InputSchema: schema}, func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "not json"}}}, nil
+	})
+	server.AddTool(&mcp.Tool{Name: "image", InputSchema: schema}, func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.ImageContent{Data: []byte{1}, MIMEType: "image/png"}}}, nil
+	})
+
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	_, err := server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client, err := NewClient(clientTransport)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, client.Close())
+	})
+
+	output, err := client.CallTool(context.Background(), "plain", nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `"not json"`, string(output))
+
+	_, err = client.CallTool(context.Background(), "image", nil)
+	require.ErrorIs(t, err, ErrUnsupportedContent)
+}
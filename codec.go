@@ -0,0 +1,73 @@
+package mcpio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// payloadContentTypeMetaKey is the tool _meta key advertising the encoding a raw
+// tool's function reads and writes when a codec is set
+const payloadContentTypeMetaKey = "payloadContentType"
+
+// Codec encodes the payloads raw tool functions receive and return, for tools
+// backed by systems that speak a binary format such as MessagePack or CBOR. Clients
+// still exchange JSON; the handler converts at the boundary. Implementations must be
+// safe for concurrent use.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+	// ContentType is the MIME type of the encoded payloads, e.g. "application/msgpack"
+	ContentType() string
+}
+
+// encodePayload converts JSON tool arguments to the codec's format. Whole numbers
+// are encoded as integers, since binary formats distinguish them from floats.
+func encodePayload(codec Codec, inputJSON []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(inputJSON))
+	decoder.UseNumber()
+	var input any
+	if err := decoder.Decode(&input); err != nil {
+		return nil, err
+	}
+	payload, err := codec.Marshal(convertNumbers(input))
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s payload: %w", codec.ContentType(), err)
+	}
+	return payload, nil
+}
+
+// convertNumbers replaces the json.Number values in a decoded JSON value with int64
+// or float64
+func convertNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, value := range v {
+			v[key] = convertNumbers(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = convertNumbers(value)
+		}
+	}
+	return v
+}
+
+// decodePayload converts a raw tool's output from the codec's format to JSON
+func decodePayload(codec Codec, payload []byte) ([]byte, error) {
+	var output any
+	if err := codec.Unmarshal(payload, &output); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidPayload, codec.ContentType(), err)
+	}
+	outputJSON, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidPayload, codec.ContentType(), err)
+	}
+	return outputJSON, nil
+}
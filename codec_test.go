@@ -0,0 +1,101 @@
+package mcpio
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// msgpackCodec encodes raw tool payloads as MessagePack
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v any) ([]byte, error)      { return msgpack.Marshal(v) }
+func (msgpackCodec) Unmarshal(data []byte, v any) error { return msgpack.Unmarshal(data, v) }
+func (msgpackCodec) ContentType() string                { return "application/msgpack" }
+
+type scoreRequest struct {
+	Player string `msgpack:"player"`
+	Points int    `msgpack:"points"`
+}
+
+type scoreResponse struct {
+	Player string `msgpack:"player"`
+	Total  int    `msgpack:"total"`
+}
+
+func TestWithPayloadCodec(t *testing.T) {
+	score := func(ctx context.Context, input []byte) ([]byte, error) {
+		var req scoreRequest
+		if err := msgpack.Unmarshal(input, &req); err != nil {
+			return nil, ValidationError("input is not MessagePack: " + err.Error())
+		}
+		if req.Points < 0 {
+			return nil, ValidationError("points must not be negative")
+		}
+		return msgpack.Marshal(scoreResponse{Player: req.Player, Total: req.Points * 10})
+	}
+	garbage := func(ctx context.Context, input []byte) ([]byte, error) {
+		return []byte{0xc1}, nil // Never used in MessagePack
+	}
+
+	schema := CreateDynamicSchema([]FieldDef{
+		{Name: "player", Type: "string", Required: true},
+		{Name: "points", Type: "number", Required: true},
+	})
+	handler, err := NewHandler(
+		WithPayloadCodec(msgpackCodec{}),
+		WithRawTool("score", "Score points", schema, score),
+		WithRawTool("garbage", "Returns invalid MessagePack", CreateObjectSchema("", nil, nil), garbage),
+		WithTool("echo", "Echo input", echoFunc),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	t.Run("round trip", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "score",
+			Arguments: map[string]any{"player": "ada", "points": 4},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError, resultText(t, result))
+		assert.JSONEq(t, `{"player": "ada", "total": 40}`, resultText(t, result))
+		assert.NotNil(t, result.StructuredContent)
+	})
+
+	t.Run("tool error", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "score",
+			Arguments: map[string]any{"player": "ada", "points": -1},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "must not be negative")
+	})
+
+	t.Run("undecodable output", func(t *testing.T) {
+		_, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "garbage"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), ErrInvalidPayload.Error())
+	})
+
+	t.Run("content type is advertised on raw tools", func(t *testing.T) {
+		list, err := session.ListTools(context.Background(), nil)
+		require.NoError(t, err)
+		for _, tool := range list.Tools {
+			if tool.Name == "echo" {
+				assert.NotContains(t, tool.Meta, payloadContentTypeMetaKey)
+				continue
+			}
+			assert.Equal(t, "application/msgpack", tool.Meta[payloadContentTypeMetaKey], tool.Name)
+		}
+	})
+
+	t.Run("nil codec", func(t *testing.T) {
+		_, err := NewHandler(WithPayloadCodec(nil))
+		require.ErrorIs(t, err, ErrNilCodec)
+	})
+}
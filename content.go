@@ -0,0 +1,121 @@
+package mcpio
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ContentToolFunc is the function signature for tools that build their own result
+// content, such as a text summary followed by an image. Input is typed as with
+// ToolFunc; the returned blocks are sent in order.
+type ContentToolFunc[TIn any] func(context.Context, TIn) ([]mcp.Content, error)
+
+// AudioToolFunc is the function signature for tools that return audio, such as
+// speech synthesis. Input is typed as with ToolFunc; the result is sent as a single
+// audio content block.
+type AudioToolFunc[TIn any] func(context.Context, TIn) (*mcp.AudioContent, error)
+
+// TextContent creates a text content block
+func TextContent(text string) *mcp.TextContent {
+	return &mcp.TextContent{Text: text}
+}
+
+// ImageContent creates an image content block from raw image data, e.g. "image/png".
+// The data is base64-encoded on the wire.
+func ImageContent(data []byte, mimeType string) *mcp.ImageContent {
+	return &mcp.ImageContent{Data: data, MIMEType: mimeType}
+}
+
+// AudioContent creates an audio content block from raw audio data, e.g. "audio/wav"
+// or "audio/mpeg". The data is base64-encoded on the wire.
+func AudioContent(data []byte, mimeType string) *mcp.AudioContent {
+	return &mcp.AudioContent{Data: data, MIMEType: mimeType}
+}
+
+// EmbeddedResourceContent creates a content block that embeds a resource's data,
+// for use in tool results and prompt messages. Textual MIME types are embedded as
+// text, anything else as a base64-encoded blob.
+func EmbeddedResourceContent(uri, mimeType string, data []byte) *mcp.EmbeddedResource {
+	contents := &mcp.ResourceContents{URI: uri, MIMEType: mimeType}
+	if isTextMIMEType(mimeType, data) {
+		contents.Text = string(data)
+	} else {
+		contents.Blob = data
+	}
+	return &mcp.EmbeddedResource{Resource: contents}
+}
+
+// WithContentTool adds a tool with a typed input that returns a list of content
+// blocks instead of JSON, e.g. text, images, audio, or embedded resources. Use
+// WithTool when a single JSON result is enough.
+func WithContentTool[TIn any](name, description string, fn ContentToolFunc[TIn]) Option {
+	return withContentTool(name, description, ToolFunc[TIn, []mcp.Content](fn), func(content []mcp.Content) ([]mcp.Content, error) {
+		if len(content) == 0 {
+			return nil, fmt.Errorf("tool %q returned no content", name)
+		}
+		return content, nil
+	})
+}
+
+// WithAudioTool adds a tool with a typed input whose result is audio content
+// instead of JSON
+func WithAudioTool[TIn any](name, description string, fn AudioToolFunc[TIn]) Option {
+	return withContentTool(name, description, ToolFunc[TIn, *mcp.AudioContent](fn), func(audio *mcp.AudioContent) ([]mcp.Content, error) {
+		if audio == nil {
+			return nil, fmt.Errorf("tool %q returned no audio content", name)
+		}
+		return []mcp.Content{audio}, nil
+	})
+}
+
+// withContentTool adds a tool with a typed input whose output is converted to
+// content blocks by toContent
+func withContentTool[TIn, TOut any](
+	name, description string,
+	fn ToolFunc[TIn, TOut],
+	toContent func(TOut) ([]mcp.Content, error),
+) Option {
+	return func(cfg *handlerConfig) error {
+		if name == "" {
+			return ErrEmptyToolName
+		}
+		if fn == nil {
+			return ErrNilFunction
+		}
+
+		inputSchema, err := generateInputSchema[TIn]()
+		if err != nil {
+			return fmt.Errorf("%w: tool %q: %w", ErrInvalidSchema, name, err)
+		}
+
+		tool := &mcp.Tool{
+			Name:        name,
+			Description: description,
+			InputSchema: inputSchema,
+		}
+
+		registerFunc := func(server *mcp.Server, tool *mcp.Tool, chain *callChain) {
+			typed := createTypedHandler(wrapToolFunc(chain, name, fn))
+			// An output type of any leaves the tool without an output schema or
+			// structured content, so only the content blocks are returned
+			handler := func(ctx context.Context, req *mcp.CallToolRequest, input TIn) (*mcp.CallToolResult, any, error) {
+				_, output, err := typed(ctx, req, input)
+				if err != nil {
+					return nil, nil, err
+				}
+				content, err := toContent(output)
+				if err != nil {
+					return nil, nil, err
+				}
+				return &mcp.CallToolResult{Content: content}, nil, nil
+			}
+			mcp.AddTool(server, tool, handler)
+		}
+
+		cfg.tools = append(cfg.tools, &toolEntry{tool: tool, register: registerFunc})
+
+		return nil
+	}
+}
@@ -0,0 +1,234 @@
+package mcpio
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type SpeakInput struct {
+	Text string `json:"text" jsonschema:"Text to speak"`
+}
+
+func TestAudioContent(t *testing.T) {
+	data := []byte("RIFF\x24\x00\x00\x00WAVEfmt ")
+	audio := AudioContent(data, "audio/wav")
+	assert.Equal(t, data, audio.Data)
+	assert.Equal(t, "audio/wav", audio.MIMEType)
+}
+
+func TestWithAudioTool(t *testing.T) {
+	wav := []byte("RIFF\x24\x00\x00\x00WAVEfmt \x10\x00\x00\x00")
+
+	speak := func(ctx context.Context, input SpeakInput) (*mcp.AudioContent, error) {
+		switch input.Text {
+		case "":
+			return nil, ValidationError("text is required")
+		case "silence":
+			return nil, nil
+		}
+		return AudioContent(wav, "audio/wav"), nil
+	}
+
+	handler, err := NewHandler(WithAudioTool("speak", "Synthesize speech", speak))
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	list, err := session.ListTools(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, list.Tools, 1)
+	assert.Nil(t, list.Tools[0].OutputSchema)
+
+	t.Run("returns audio", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "speak",
+			Arguments: map[string]any{"text": "hello"},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+		assert.Nil(t, result.StructuredContent)
+		require.Len(t, result.Content, 1)
+
+		audio, ok := result.Content[0].(*mcp.AudioContent)
+		require.True(t, ok, "expected audio content, got %T", result.Content[0])
+		assert.Equal(t, "audio/wav", audio.MIMEType)
+		assert.Equal(t, wav, audio.Data)
+	})
+
+	t.Run("tool error", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "speak",
+			Arguments: map[string]any{"text": ""},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "text is required")
+		assert.Equal(t, "VALIDATION_ERROR", result.Meta["errorCode"])
+	})
+
+	t.Run("nil audio", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "speak",
+			Arguments: map[string]any{"text": "silence"},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "returned no audio content")
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := NewHandler(WithAudioTool("", "Synthesize speech", speak))
+		require.ErrorIs(t, err, ErrEmptyToolName)
+
+		_, err = NewHandler(WithAudioTool[SpeakInput]("speak", "Synthesize speech", nil))
+		require.ErrorIs(t, err, ErrNilFunction)
+	})
+}
+
+type ChartInput struct {
+	Metric string `json:"metric" jsonschema:"Metric to chart"`
+}
+
+func TestWithContentTool(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	chart := func(ctx context.Context, input ChartInput) ([]mcp.Content, error) {
+		switch input.Metric {
+		case "":
+			return nil, ValidationError("metric is required")
+		case "none":
+			return nil, nil
+		}
+		return []mcp.Content{
+			TextContent("Chart of " + input.Metric),
+			ImageContent(png, "image/png"),
+		}, nil
+	}
+
+	handler, err := NewHandler(
+		WithContentTool("chart", "Chart a metric", chart),
+		WithTool("echo", "Echo input", echoFunc),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	t.Run("text and image", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "chart",
+			Arguments: map[string]any{"metric": "latency"},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+		assert.Nil(t, result.StructuredContent)
+		require.Len(t, result.Content, 2)
+
+		text, ok := result.Content[0].(*mcp.TextContent)
+		require.True(t, ok, "expected text content, got %T", result.Content[0])
+		assert.Equal(t, "Chart of latency", text.Text)
+
+		image, ok := result.Content[1].(*mcp.ImageContent)
+		require.True(t, ok, "expected image content, got %T", result.Content[1])
+		assert.Equal(t, "image/png", image.MIMEType)
+		assert.Equal(t, png, image.Data)
+	})
+
+	t.Run("typed tools unchanged", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "echo",
+			Arguments: map[string]any{"text": "hi"},
+		})
+		require.NoError(t, err)
+		require.Len(t, result.Content, 1)
+		assert.JSONEq(t, `{"message": "hi"}`, resultText(t, result))
+	})
+
+	t.Run("tool error", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "chart",
+			Arguments: map[string]any{"metric": ""},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "VALIDATION_ERROR", result.Meta["errorCode"])
+	})
+
+	t.Run("no content", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "chart",
+			Arguments: map[string]any{"metric": "none"},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "returned no content")
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := NewHandler(WithContentTool("", "Chart a metric", chart))
+		require.ErrorIs(t, err, ErrEmptyToolName)
+
+		_, err = NewHandler(WithContentTool[ChartInput]("chart", "Chart a metric", nil))
+		require.ErrorIs(t, err, ErrNilFunction)
+	})
+}
+
+func TestPromptEmbeddedResource(t *testing.T) {
+	png := []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a}
+
+	review := func(ctx context.Context, args map[string]string) ([]mcp.PromptMessage, error) {
+		switch args["file"] {
+		case "missing-uri":
+			return []mcp.PromptMessage{{Role: "user", Content: EmbeddedResourceContent("", "text/plain", nil)}}, nil
+		case "nil":
+			return []mcp.PromptMessage{{Role: "user"}}, nil
+		}
+		return []mcp.PromptMessage{
+			{Role: "user", Content: TextContent("Review this file:")},
+			{Role: "user", Content: EmbeddedResourceContent("file:///main.go", "text/x-go", []byte("package main"))},
+			{Role: "user", Content: EmbeddedResourceContent("file:///logo.png", "image/png", png)},
+		}, nil
+	}
+
+	handler, err := NewHandler(WithPrompt("review", "Code review", []PromptArg{{Name: "file"}}, review))
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	t.Run("resources reach the client", func(t *testing.T) {
+		result, err := session.GetPrompt(context.Background(), &mcp.GetPromptParams{
+			Name:      "review",
+			Arguments: map[string]string{"file": "main.go"},
+		})
+		require.NoError(t, err)
+		require.Len(t, result.Messages, 3)
+
+		text, ok := result.Messages[0].Content.(*mcp.TextContent)
+		require.True(t, ok, "expected text content, got %T", result.Messages[0].Content)
+		assert.Equal(t, "Review this file:", text.Text)
+
+		source, ok := result.Messages[1].Content.(*mcp.EmbeddedResource)
+		require.True(t, ok, "expected embedded resource, got %T", result.Messages[1].Content)
+		assert.Equal(t, "file:///main.go", source.Resource.URI)
+		assert.Equal(t, "text/x-go", source.Resource.MIMEType)
+		assert.Equal(t, "package main", source.Resource.Text)
+		assert.Empty(t, source.Resource.Blob)
+
+		image, ok := result.Messages[2].Content.(*mcp.EmbeddedResource)
+		require.True(t, ok, "expected embedded resource, got %T", result.Messages[2].Content)
+		assert.Equal(t, "image/png", image.Resource.MIMEType)
+		assert.Equal(t, png, image.Resource.Blob)
+		assert.Empty(t, image.Resource.Text)
+	})
+
+	t.Run("invalid content", func(t *testing.T) {
+		for _, file := range []string{"missing-uri", "nil"} {
+			_, err := session.GetPrompt(context.Background(), &mcp.GetPromptParams{
+				Name:      "review",
+				Arguments: map[string]string{"file": file},
+			})
+			require.Error(t, err, file)
+			assert.Contains(t, err.Error(), ErrInvalidPromptContent.Error(), file)
+		}
+	})
+}
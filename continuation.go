@@ -0,0 +1,87 @@
+package mcpio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Result and request _meta keys for continued tool calls
+const (
+	partialMetaKey      = "partial"
+	continuationMetaKey = "continuation"
+)
+
+// continuationEnvelope is the output a raw tool with continuation support returns:
+// a page of data, and while partial is true, the token for the next page
+type continuationEnvelope struct {
+	Partial      *bool           `json:"partial"`
+	Continuation string          `json:"continuation"`
+	Data         json.RawMessage `json:"data"`
+}
+
+// ContinuationFromContext returns the continuation token a client passed in the
+// tool call's _meta to resume a partial result. It reports false for a first call
+// and outside a tool call.
+func ContinuationFromContext(ctx context.Context) (string, bool) {
+	req, ok := RequestFromContext(ctx)
+	if !ok || req.Params == nil {
+		return "", false
+	}
+	token, ok := req.Params.Meta[continuationMetaKey].(string)
+	return token, ok && token != ""
+}
+
+// continuationMiddleware unwraps the continuation envelope returned by the named
+// tools. The result carries only the envelope's data, with partial and
+// continuation moved to its _meta so clients can re-call for the rest.
+func continuationMiddleware(tools map[string]bool) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			result, err := next(ctx, method, req)
+
+			call, ok := req.(*mcp.CallToolRequest)
+			if !ok || call.Params == nil || !tools[call.Params.Name] {
+				return result, err
+			}
+			res, ok := result.(*mcp.CallToolResult)
+			if !ok || res == nil || res.IsError {
+				return result, err
+			}
+			text, ok := resultTextContent(res)
+			if !ok {
+				return result, err
+			}
+
+			var envelope continuationEnvelope
+			if json.Unmarshal([]byte(text), &envelope) != nil || envelope.Partial == nil {
+				return result, err
+			}
+			if *envelope.Partial && envelope.Continuation == "" {
+				return nil, fmt.Errorf("%w: tool %q", ErrMissingContinuation, call.Params.Name)
+			}
+
+			data := envelope.Data
+			if data == nil {
+				data = json.RawMessage("null")
+			}
+			res.Content = []mcp.Content{&mcp.TextContent{Text: string(data)}}
+			res.StructuredContent = nil
+			var object map[string]any
+			if json.Unmarshal(data, &object) == nil && object != nil {
+				res.StructuredContent = data
+			}
+
+			if res.Meta == nil {
+				res.Meta = mcp.Meta{}
+			}
+			res.Meta[partialMetaKey] = *envelope.Partial
+			if *envelope.Partial {
+				res.Meta[continuationMetaKey] = envelope.Continuation
+			}
+			return res, err
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package mcpio
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithContinuationSupport(t *testing.T) {
+	pages := func(ctx context.Context, input []byte) ([]byte, error) {
+		token, ok := ContinuationFromContext(ctx)
+		switch {
+		case !ok:
+			return []byte(`{"partial": true, "continuation": "page-2", "data": {"items": [1, 2]}}`), nil
+		case token == "page-2":
+			return []byte(`{"partial": false, "data": {"items": [3]}}`), nil
+		default:
+			return nil, ValidationError("unknown continuation " + token)
+		}
+	}
+	broken := func(ctx context.Context, input []byte) ([]byte, error) {
+		return []byte(`{"partial": true, "data": []}`), nil
+	}
+	schema := CreateObjectSchema("Input", nil, nil)
+
+	handler, err := NewHandler(
+		WithRawTool("pages", "Return results in pages", schema, pages),
+		WithRawTool("broken", "Partial result without a token", schema, broken),
+		WithRawTool("plain", "Ordinary output", schema, rawFunc),
+		WithContinuationSupport("pages"),
+		WithContinuationSupport("broken"),
+		WithContinuationSupport("plain"),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	t.Run("two-step continuation", func(t *testing.T) {
+		first, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "pages"})
+		require.NoError(t, err)
+		require.False(t, first.IsError, resultText(t, first))
+		assert.JSONEq(t, `{"items": [1, 2]}`, resultText(t, first))
+		assert.NotNil(t, first.StructuredContent)
+		assert.Equal(t, true, first.Meta["partial"])
+		token, ok := first.Meta["continuation"].(string)
+		require.True(t, ok)
+		assert.Equal(t, "page-2", token)
+
+		second, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Meta: mcp.Meta{"continuation": token},
+			Name: "pages",
+		})
+		require.NoError(t, err)
+		require.False(t, second.IsError, resultText(t, second))
+		assert.JSONEq(t, `{"items": [3]}`, resultText(t, second))
+		assert.Equal(t, false, second.Meta["partial"])
+		assert.NotContains(t, second.Meta, "continuation")
+	})
+
+	t.Run("tool errors pass through", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Meta: mcp.Meta{"continuation": "stale"},
+			Name: "pages",
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "unknown continuation stale")
+	})
+
+	t.Run("partial result without a token", func(t *testing.T) {
+		_, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "broken"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), ErrMissingContinuation.Error())
+	})
+
+	t.Run("output without an envelope", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "plain"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"result": "processed"}`, resultText(t, result))
+		assert.NotContains(t, result.Meta, "partial")
+	})
+
+	t.Run("option errors", func(t *testing.T) {
+		_, err := NewHandler(WithContinuationSupport(""))
+		require.ErrorIs(t, err, ErrEmptyToolName)
+
+		_, err = NewHandler(WithContinuationSupport("missing"))
+		require.ErrorIs(t, err, ErrToolNotFound)
+
+		_, err = NewHandler(WithTool("echo", "Echo input", echoFunc), WithContinuationSupport("echo"))
+		require.ErrorIs(t, err, ErrNotRawTool)
+	})
+}
@@ -0,0 +1,127 @@
+package mcpio
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures cross-origin access for browser-based clients of the
+// HTTP transport
+type CORSOptions struct {
+	// AllowedOrigins are the origins allowed to call the server, e.g.
+	// "https://app.example.com". "*" allows any origin.
+	AllowedOrigins []string
+	// AllowedMethods defaults to GET, POST, DELETE, and OPTIONS, which cover the
+	// streamable HTTP transport
+	AllowedMethods []string
+	// AllowedHeaders defaults to the headers MCP clients send: Content-Type,
+	// Authorization, Accept, Last-Event-ID, Mcp-Session-Id, and Mcp-Protocol-Version
+	AllowedHeaders []string
+	// AllowCredentials lets browsers send cookies and HTTP authentication. With a
+	// "*" origin, the request's origin is echoed back, since browsers reject a
+	// wildcard for credentialed requests.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response; zero leaves it
+	// to the browser
+	MaxAge time.Duration
+}
+
+// Defaults for CORSOptions fields left empty
+var (
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodOptions}
+	defaultCORSHeaders = []string{
+		"Content-Type", "Authorization", "Accept", "Last-Event-ID", "Mcp-Session-Id", "Mcp-Protocol-Version",
+	}
+)
+
+// corsExposedHeaders are response headers browser clients must be able to read
+const corsExposedHeaders = "Mcp-Session-Id"
+
+// corsPolicy applies CORSOptions to requests
+type corsPolicy struct {
+	anyOrigin   bool
+	origins     []string
+	methods     string
+	headers     string
+	credentials bool
+	maxAge      string
+}
+
+// newCORSPolicy validates opts and fills in defaults
+func newCORSPolicy(opts CORSOptions) (*corsPolicy, error) {
+	if len(opts.AllowedOrigins) == 0 {
+		return nil, fmt.Errorf("%w: no allowed origins", ErrInvalidCORSOptions)
+	}
+	if opts.MaxAge < 0 {
+		return nil, fmt.Errorf("%w: negative max age %s", ErrInvalidCORSOptions, opts.MaxAge)
+	}
+
+	policy := &corsPolicy{
+		anyOrigin:   slices.Contains(opts.AllowedOrigins, "*"),
+		origins:     opts.AllowedOrigins,
+		methods:     strings.Join(orDefault(opts.AllowedMethods, defaultCORSMethods), ", "),
+		headers:     strings.Join(orDefault(opts.AllowedHeaders, defaultCORSHeaders), ", "),
+		credentials: opts.AllowCredentials,
+	}
+	if opts.MaxAge > 0 {
+		policy.maxAge = strconv.Itoa(int(opts.MaxAge.Seconds()))
+	}
+	return policy, nil
+}
+
+// orDefault returns values, or defaults when values is empty
+func orDefault(values, defaults []string) []string {
+	if len(values) == 0 {
+		return defaults
+	}
+	return values
+}
+
+// allowsOrigin reports whether requests from origin are allowed
+func (p *corsPolicy) allowsOrigin(origin string) bool {
+	return p.anyOrigin || slices.Contains(p.origins, origin)
+}
+
+// apply sets the CORS response headers for an allowed origin. It returns true if
+// the request was a preflight, which it has answered.
+func (p *corsPolicy) apply(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	if origin == "" {
+		return false
+	}
+
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	if !p.allowsOrigin(origin) {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		return preflight
+	}
+
+	if p.anyOrigin && !p.credentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		header.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		return false
+	}
+
+	header.Set("Access-Control-Allow-Methods", p.methods)
+	header.Set("Access-Control-Allow-Headers", p.headers)
+	if p.maxAge != "" {
+		header.Set("Access-Control-Max-Age", p.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
@@ -0,0 +1,134 @@
+package mcpio
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCORS(t *testing.T) {
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`
+
+	newServer := func(t *testing.T, opts CORSOptions) *httptest.Server {
+		t.Helper()
+		handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc), WithCORS(opts))
+		require.NoError(t, err)
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+		return server
+	}
+	preflight := func(t *testing.T, serverURL, origin string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodOptions, serverURL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "content-type, mcp-session-id")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp
+	}
+	post := func(t *testing.T, serverURL, origin string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, serverURL, strings.NewReader(initialize))
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp
+	}
+
+	t.Run("origin list", func(t *testing.T) {
+		server := newServer(t, CORSOptions{
+			AllowedOrigins: []string{"https://app.example.com", "https://admin.example.com"},
+			MaxAge:         10 * time.Minute,
+		})
+
+		resp := preflight(t, server.URL, "https://admin.example.com")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, "https://admin.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Contains(t, resp.Header.Get("Access-Control-Allow-Methods"), http.MethodPost)
+		assert.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), "Mcp-Session-Id")
+		assert.Equal(t, "600", resp.Header.Get("Access-Control-Max-Age"))
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Credentials"))
+		assert.Contains(t, resp.Header.Values("Vary"), "Origin")
+
+		resp = post(t, server.URL, "https://app.example.com")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "Mcp-Session-Id", resp.Header.Get("Access-Control-Expose-Headers"))
+		assert.NotEmpty(t, resp.Header.Get("Mcp-Session-Id"))
+	})
+
+	t.Run("disallowed origin", func(t *testing.T) {
+		server := newServer(t, CORSOptions{AllowedOrigins: []string{"https://app.example.com"}})
+
+		resp := preflight(t, server.URL, "https://evil.example.com")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+
+		resp = post(t, server.URL, "https://evil.example.com")
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("wildcard origin", func(t *testing.T) {
+		server := newServer(t, CORSOptions{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{http.MethodPost},
+			AllowedHeaders: []string{"Content-Type"},
+		})
+
+		resp := preflight(t, server.URL, "https://anywhere.example.com")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, http.MethodPost, resp.Header.Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "Content-Type", resp.Header.Get("Access-Control-Allow-Headers"))
+
+		resp = post(t, server.URL, "https://anywhere.example.com")
+		assert.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("wildcard origin with credentials", func(t *testing.T) {
+		server := newServer(t, CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+
+		resp := preflight(t, server.URL, "https://anywhere.example.com")
+		assert.Equal(t, "https://anywhere.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("preflight skips authentication", func(t *testing.T) {
+		handler, err := NewHandler(
+			WithTool("echo", "Echo input", echoFunc),
+			WithCORS(CORSOptions{AllowedOrigins: []string{"*"}}),
+			WithHTTPAuth(func(token string) (any, error) { return nil, ErrUnauthorized }),
+		)
+		require.NoError(t, err)
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+
+		resp := preflight(t, server.URL, "https://app.example.com")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		// Browsers can only read the 401 if it carries CORS headers
+		resp = post(t, server.URL, "https://app.example.com")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := NewHandler(WithCORS(CORSOptions{}))
+		require.ErrorIs(t, err, ErrInvalidCORSOptions)
+
+		_, err = NewHandler(WithCORS(CORSOptions{AllowedOrigins: []string{"*"}, MaxAge: -time.Second}))
+		require.ErrorIs(t, err, ErrInvalidCORSOptions)
+	})
+}
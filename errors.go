@@ -1,8 +1,12 @@
 package mcpio
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
 )
 
 // ToolError represents a tool execution error that should be returned to the client
@@ -10,14 +14,25 @@ import (
 // and potentially retry or self-correct.
 type ToolError struct {
 	Message string
-	Code    string // Optional error code for categorization
+	Code    string       // Optional error code for categorization
+	Errors  []*ToolError // Individual errors aggregated by MultiToolError
+	Err     error        // Optional underlying cause, reachable with errors.Is and errors.As
 }
 
 func (e *ToolError) Error() string {
+	msg := e.Message
+	if e.Err != nil {
+		msg = fmt.Sprintf("%s: %v", msg, e.Err)
+	}
 	if e.Code != "" {
-		return fmt.Sprintf("[%s] %s", e.Code, e.Message)
+		return fmt.Sprintf("[%s] %s", e.Code, msg)
 	}
-	return e.Message
+	return msg
+}
+
+// Unwrap returns the underlying cause, if any
+func (e *ToolError) Unwrap() error {
+	return e.Err
 }
 
 // NewToolError creates a new tool error with the given message
@@ -30,6 +45,12 @@ func NewToolErrorWithCode(message, code string) *ToolError {
 	return &ToolError{Message: message, Code: code}
 }
 
+// WrapToolError creates a tool error with the given message that wraps err, so
+// callers can still match the cause with errors.Is and errors.As
+func WrapToolError(err error, message string) *ToolError {
+	return &ToolError{Message: message, Err: err}
+}
+
 // ValidationError is a convenience function for creating validation tool errors
 func ValidationError(message string) *ToolError {
 	return &ToolError{Message: message, Code: "VALIDATION_ERROR"}
@@ -40,15 +61,162 @@ func ProcessingError(message string) *ToolError {
 	return &ToolError{Message: message, Code: "PROCESSING_ERROR"}
 }
 
+// MultiToolError aggregates several tool errors into one, so a tool that checks many
+// items can report every failure at once. The combined message lists each error and
+// the originals are kept in Errors, whose codes and messages are also sent in the
+// result metadata under "errors". Wrapped causes are left out of both. Nil entries
+// are skipped; a single error is returned as-is, and nil is returned when there
+// are no errors to report.
+func MultiToolError(errs ...*ToolError) *ToolError {
+	collected := make([]*ToolError, 0, len(errs))
+	for _, err := range errs {
+		if err != nil {
+			collected = append(collected, err)
+		}
+	}
+
+	switch len(collected) {
+	case 0:
+		return nil
+	case 1:
+		return collected[0]
+	}
+
+	messages := make([]string, len(collected))
+	for i, err := range collected {
+		// The cause is dropped so it stays internal, as for a single tool error
+		messages[i] = (&ToolError{Message: err.Message, Code: err.Code}).Error()
+	}
+
+	return &ToolError{
+		Message: fmt.Sprintf("%d errors: %s", len(collected), strings.Join(messages, "; ")),
+		Code:    "MULTIPLE_ERRORS",
+		Errors:  collected,
+	}
+}
+
+// RPCCodeInternalError is the JSON-RPC error code for internal errors, used for a
+// ProtocolError that doesn't set RPCCode
+const RPCCodeInternalError = -32603
+
+// ProtocolError represents a tool failure that should be returned to the client as a
+// JSON-RPC error instead of a tool result, e.g. when the request itself can't be served.
+// RPCCode sets the JSON-RPC error code on the wire.
+type ProtocolError struct {
+	Message string
+	RPCCode int   // JSON-RPC error code; zero means RPCCodeInternalError
+	Err     error // Optional underlying cause, reachable with errors.Is and errors.As
+}
+
+func (e *ProtocolError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying cause, if any
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// NewProtocolError creates a new protocol error with the given message and JSON-RPC error code
+func NewProtocolError(message string, rpcCode int) *ProtocolError {
+	return &ProtocolError{Message: message, RPCCode: rpcCode}
+}
+
+// wireError converts the protocol error into the SDK's JSON-RPC error type, which is
+// the only error type the SDK sends with its own code. The SDK does not export that
+// type, so it is obtained by decoding an error response.
+func (e *ProtocolError) wireError() error {
+	code := e.RPCCode
+	if code == 0 {
+		code = RPCCodeInternalError
+	}
+
+	type wireError struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	data, err := json.Marshal(struct {
+		Version string    `json:"jsonrpc"`
+		ID      int       `json:"id"`
+		Error   wireError `json:"error"`
+	}{Version: "2.0", ID: 1, Error: wireError{Code: code, Message: e.Error()}})
+	if err != nil {
+		return e
+	}
+	msg, err := jsonrpc.DecodeMessage(data)
+	if err != nil {
+		return e
+	}
+	resp, ok := msg.(*jsonrpc.Response)
+	if !ok || resp.Error == nil {
+		return e
+	}
+	return resp.Error
+}
+
 // Sentinel errors for configuration validation
 var (
-	ErrEmptyName        = errors.New("name cannot be empty")
-	ErrEmptyVersion     = errors.New("version cannot be empty")
-	ErrEmptyToolName    = errors.New("tool name cannot be empty")
-	ErrNilSchema        = errors.New("schema cannot be nil")
-	ErrNilFunction      = errors.New("function cannot be nil")
-	ErrNilServer        = errors.New("server cannot be nil")
-	ErrDuplicateTool    = errors.New("tool already registered")
-	ErrInvalidOperation = errors.New("invalid operation")
-	ErrInvalidJSON      = errors.New("tool returned invalid JSON")
+	ErrEmptyName               = errors.New("name cannot be empty")
+	ErrEmptyVersion            = errors.New("version cannot be empty")
+	ErrEmptyInstructions       = errors.New("instructions cannot be empty")
+	ErrEmptyToolName           = errors.New("tool name cannot be empty")
+	ErrNilSchema               = errors.New("schema cannot be nil")
+	ErrInvalidSchema           = errors.New("invalid schema")
+	ErrNilFunction             = errors.New("function cannot be nil")
+	ErrNilServer               = errors.New("server cannot be nil")
+	ErrInjectedServer          = errors.New("option cannot be applied to a server injected with WithServer")
+	ErrDuplicateTool           = errors.New("tool already registered")
+	ErrToolNotFound            = errors.New("tool not found")
+	ErrEmptyResourceURI        = errors.New("resource URI cannot be empty")
+	ErrInvalidResourceURI      = errors.New("invalid resource URI")
+	ErrEmptyResourceName       = errors.New("resource name cannot be empty")
+	ErrEmptyPromptName         = errors.New("prompt name cannot be empty")
+	ErrEmptyArgumentName       = errors.New("argument name cannot be empty")
+	ErrMissingPromptArgument   = errors.New("missing required prompt argument")
+	ErrInvalidOperation        = errors.New("invalid operation")
+	ErrInvalidJSON             = errors.New("tool returned invalid JSON")
+	ErrInvalidTimeout          = errors.New("timeout must be positive")
+	ErrNilLogger               = errors.New("logger cannot be nil")
+	ErrEmptyFieldName          = errors.New("field name cannot be empty")
+	ErrEmptyEnvVar             = errors.New("environment variable name cannot be empty")
+	ErrFieldNotFound           = errors.New("field not found")
+	ErrNilMetrics              = errors.New("metrics cannot be nil")
+	ErrNilTracer               = errors.New("tracer cannot be nil")
+	ErrInvalidThreshold        = errors.New("threshold must be positive")
+	ErrInvalidExample          = errors.New("schema example does not match its schema")
+	ErrDuplicateResource       = errors.New("duplicate resource URI")
+	ErrInvalidToolFunc         = errors.New("invalid tool function")
+	ErrNilQuotaStore           = errors.New("quota store cannot be nil")
+	ErrInvalidName             = errors.New("name contains control characters")
+	ErrInvalidVersion          = errors.New("version is not a semantic version")
+	ErrNilServerOptions        = errors.New("server options cannot be nil")
+	ErrHandlerClosed           = errors.New("handler is closed")
+	ErrWebSocketHandshake      = errors.New("websocket handshake failed")
+	ErrNilConnection           = errors.New("connection cannot be nil")
+	ErrSchemaResolution        = errors.New("schema could not be applied")
+	ErrInvalidPropertyOrder    = errors.New("invalid property order")
+	ErrEmptyTag                = errors.New("tag cannot be empty")
+	ErrNotRawTool              = errors.New("tool is not a raw tool")
+	ErrNotInitialized          = errors.New("handler is not initialized")
+	ErrInvalidPromptContent    = errors.New("invalid prompt message content")
+	ErrInvalidConcurrencyLimit = errors.New("concurrency limit must be positive")
+	ErrInvalidByteLimit        = errors.New("byte limit must be positive")
+	ErrInvalidRateLimit        = errors.New("rate limit must be positive")
+	ErrNilCodec                = errors.New("codec cannot be nil")
+	ErrInvalidPayload          = errors.New("tool returned a payload its codec could not decode")
+	ErrUnauthorized            = errors.New("unauthorized")
+	ErrMissingContinuation     = errors.New("partial result has no continuation token")
+	ErrInvalidCORSOptions      = errors.New("invalid CORS options")
+	ErrInvalidSlowClientPolicy = errors.New("invalid slow client policy")
+	ErrSlowClient              = errors.New("client could not keep up with messages")
+	ErrEmptyTitle              = errors.New("title cannot be empty")
+	ErrCallCancelled           = errors.New("tool call cancelled")
+	ErrNilTransport            = errors.New("transport cannot be nil")
+	ErrUnsupportedContent      = errors.New("unsupported content")
+	ErrNilChannel              = errors.New("channel cannot be nil")
+	ErrToolRegistration        = errors.New("tool could not be registered")
+	ErrTypeNameCollision       = errors.New("tool names map to the same type name")
 )
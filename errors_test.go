@@ -1,6 +1,8 @@
 package mcpio
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -33,6 +35,16 @@ func TestToolError_Error(t *testing.T) {
 			toolErr:  &ToolError{Message: "error", Code: ""},
 			expected: "error",
 		},
+		{
+			name:     "message with cause",
+			toolErr:  &ToolError{Message: "lookup failed", Err: errors.New("connection refused")},
+			expected: "lookup failed: connection refused",
+		},
+		{
+			name:     "message with code and cause",
+			toolErr:  &ToolError{Message: "lookup failed", Code: "DB_ERROR", Err: errors.New("connection refused")},
+			expected: "[DB_ERROR] lookup failed: connection refused",
+		},
 	}
 
 	for _, tt := range tests {
@@ -89,3 +101,67 @@ func TestToolErrorImplementsError(t *testing.T) {
 	require.Error(t, err)
 	assert.Equal(t, "test", err.Error())
 }
+
+func TestMultiToolError(t *testing.T) {
+	item3 := ValidationError("item 3: missing name")
+	item7 := ProcessingError("item 7: lookup failed")
+
+	t.Run("aggregates errors", func(t *testing.T) {
+		err := MultiToolError(item3, nil, item7)
+
+		require.Error(t, err)
+		assert.Equal(t, "MULTIPLE_ERRORS", err.Code)
+		assert.Equal(t, []*ToolError{item3, item7}, err.Errors)
+		assert.Contains(t, err.Message, "2 errors")
+		assert.Contains(t, err.Message, "[VALIDATION_ERROR] item 3: missing name")
+		assert.Contains(t, err.Message, "[PROCESSING_ERROR] item 7: lookup failed")
+	})
+
+	t.Run("single error returned as-is", func(t *testing.T) {
+		assert.Same(t, item3, MultiToolError(nil, item3))
+	})
+
+	t.Run("no errors", func(t *testing.T) {
+		assert.Nil(t, MultiToolError())
+		assert.Nil(t, MultiToolError(nil, nil))
+	})
+}
+
+func TestWrapToolError(t *testing.T) {
+	errNotFound := errors.New("record not found")
+	dbErr := fmt.Errorf("query users: %w", errNotFound)
+
+	toolErr := WrapToolError(dbErr, "lookup failed")
+	assert.Equal(t, "lookup failed", toolErr.Message)
+	assert.Empty(t, toolErr.Code)
+	assert.Equal(t, "lookup failed: query users: record not found", toolErr.Error())
+
+	require.ErrorIs(t, toolErr, errNotFound)
+	assert.Equal(t, dbErr, errors.Unwrap(toolErr))
+
+	// The chain survives further wrapping
+	wrapped := fmt.Errorf("tool call: %w", toolErr)
+	require.ErrorIs(t, wrapped, errNotFound)
+	var target *ToolError
+	require.ErrorAs(t, wrapped, &target)
+	assert.Same(t, toolErr, target)
+
+	t.Run("nil cause", func(t *testing.T) {
+		toolErr := WrapToolError(nil, "lookup failed")
+		assert.Equal(t, "lookup failed", toolErr.Error())
+		assert.NoError(t, toolErr.Unwrap())
+	})
+}
+
+func TestProtocolError(t *testing.T) {
+	errUpstream := errors.New("upstream unavailable")
+
+	protoErr := &ProtocolError{Message: "backend failed", RPCCode: -32001, Err: errUpstream}
+	assert.Equal(t, "backend failed: upstream unavailable", protoErr.Error())
+	require.ErrorIs(t, protoErr, errUpstream)
+
+	protoErr = NewProtocolError("rate limited", -32029)
+	assert.Equal(t, "rate limited", protoErr.Error())
+	assert.Equal(t, -32029, protoErr.RPCCode)
+	assert.NoError(t, protoErr.Unwrap())
+}
@@ -0,0 +1,65 @@
+package mcpio
+
+import (
+	"context"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// EventType identifies what an Event reports
+type EventType string
+
+// Event types published by WithEventChannel
+const (
+	EventCallStarted         EventType = "call_started"
+	EventCallFinished        EventType = "call_finished" // Err is set if the call failed
+	EventSessionConnected    EventType = "session_connected"
+	EventSessionDisconnected EventType = "session_disconnected"
+)
+
+// Event is a telemetry event published to the channel set by WithEventChannel
+type Event struct {
+	Type EventType
+	Time time.Time
+
+	// Call events
+	Tool      string
+	CallID    string        // As returned by CallIDFromContext
+	Duration  time.Duration // EventCallFinished only
+	Err       error         // EventCallFinished only; nil on success
+	ErrorKind string        // "tool" or "protocol" when Err is set, as reported to Metrics
+
+	// Session events
+	SessionID string // Empty for transports without session IDs, such as stdio
+}
+
+// publishEvent sends e without blocking, dropping it when ch is full. A nil ch
+// discards every event.
+func publishEvent(ch chan<- Event, e Event) {
+	if ch == nil {
+		return
+	}
+	e.Time = time.Now()
+	select {
+	case ch <- e:
+	default:
+	}
+}
+
+// sessionEventsHandler returns an InitializedHandler that publishes a session's
+// connection once the client has initialized it, and its disconnection once it
+// ends, before calling next, if any
+func sessionEventsHandler(ch chan<- Event, next func(context.Context, *mcp.InitializedRequest)) func(context.Context, *mcp.InitializedRequest) {
+	return func(ctx context.Context, req *mcp.InitializedRequest) {
+		session := req.Session
+		publishEvent(ch, Event{Type: EventSessionConnected, SessionID: session.ID()})
+		go func() {
+			_ = session.Wait()
+			publishEvent(ch, Event{Type: EventSessionDisconnected, SessionID: session.ID()})
+		}()
+		if next != nil {
+			next(ctx, req)
+		}
+	}
+}
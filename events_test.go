@@ -0,0 +1,85 @@
+package mcpio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithEventChannel(t *testing.T) {
+	events := make(chan Event, 16)
+	handler, err := NewHandler(
+		WithTool("echo", "Echo input", echoFunc),
+		WithTool("calculate", "Perform arithmetic", calculateFunc),
+		WithEventChannel(events),
+	)
+	require.NoError(t, err)
+
+	client, err := ConnectInMemory(handler)
+	require.NoError(t, err)
+	ctx := context.Background()
+	_, err = client.CallTool(ctx, "echo", map[string]any{"text": "hi"})
+	require.NoError(t, err)
+	_, err = client.CallTool(ctx, "calculate", map[string]any{"operation": "divide", "a": 1, "b": 0})
+	require.Error(t, err)
+	require.NoError(t, client.Close())
+
+	var received []Event
+	for len(received) < 6 {
+		select {
+		case e := <-events:
+			assert.False(t, e.Time.IsZero())
+			received = append(received, e)
+		case <-time.After(time.Second):
+			t.Fatalf("received %d of 6 events", len(received))
+		}
+	}
+
+	types := make([]EventType, len(received))
+	for i, e := range received {
+		types[i] = e.Type
+	}
+	assert.Equal(t, []EventType{
+		EventSessionConnected,
+		EventCallStarted, EventCallFinished,
+		EventCallStarted, EventCallFinished,
+		EventSessionDisconnected,
+	}, types)
+
+	echoStart, echoEnd := received[1], received[2]
+	assert.Equal(t, "echo", echoStart.Tool)
+	assert.NotEmpty(t, echoStart.CallID)
+	assert.Equal(t, echoStart.CallID, echoEnd.CallID)
+	assert.NoError(t, echoEnd.Err)
+	assert.Empty(t, echoEnd.ErrorKind)
+	assert.Positive(t, echoEnd.Duration)
+
+	calcEnd := received[4]
+	assert.Equal(t, "calculate", calcEnd.Tool)
+	assert.NotEqual(t, echoStart.CallID, calcEnd.CallID)
+	assert.ErrorContains(t, calcEnd.Err, "division by zero")
+	assert.Equal(t, errorKindTool, calcEnd.ErrorKind)
+
+	t.Run("full channel drops events", func(t *testing.T) {
+		full := make(chan Event)
+		handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc), WithEventChannel(full))
+		require.NoError(t, err)
+		client, err := ConnectInMemory(handler)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			assert.NoError(t, client.Close())
+		})
+
+		// No one reads the channel, so the call only completes if events are dropped
+		_, err = client.CallTool(ctx, "echo", map[string]any{"text": "hi"})
+		require.NoError(t, err)
+	})
+
+	t.Run("nil channel", func(t *testing.T) {
+		_, err := NewHandler(WithEventChannel(nil))
+		require.ErrorIs(t, err, ErrNilChannel)
+	})
+}
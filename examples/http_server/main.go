@@ -75,6 +75,10 @@ func main() {
 
 	// Add a simple health check endpoint
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if err := handler.Health(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 		if _, err := fmt.Fprintln(w, "OK"); err != nil {
 			// Log the error but don't fail the health check
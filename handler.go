@@ -6,35 +6,226 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 
+	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // handlerConfig holds the configuration built by options
 type handlerConfig struct {
-	name    string
-	version string
-	tools   []toolRegisterFunc
-	server  *mcp.Server // The MCP-SDK server instance
+	name         string
+	version      string
+	instructions string // Usage guidance returned to clients on initialize
+	tools        []*toolEntry
+	resources    []*resourceEntry
+	prompts      []*promptEntry
+	server       *mcp.Server // The MCP-SDK server instance
+
+	// descriptionDecorator rewrites every tool description at registration time
+	descriptionDecorator func(name, description string) string
+
+	// descriptionTranslator localizes tool descriptions in tools/list results
+	descriptionTranslator func(locale, name, description string) string
+
+	// dynamicEnums compute input field enums when tools are listed, keyed by tool
+	// name and then field name
+	dynamicEnums map[string]map[string]func(context.Context) []string
+
+	// toolModifiers run after all options are applied, so options that target
+	// a tool by name work regardless of the order they are passed in
+	toolModifiers []func(*handlerConfig) error
+
+	// toolTimeouts bounds how long each tool may run, keyed by tool name
+	toolTimeouts map[string]time.Duration
+	// defaultToolTimeout applies to tools without a per-tool timeout
+	defaultToolTimeout time.Duration
+
+	// disableInputValidation skips validating raw tool arguments against their input schema
+	disableInputValidation bool
+
+	// logger receives structured logs for tool calls; nil disables logging
+	logger *slog.Logger
+	// metrics receives call counts, error counts, and latencies for tool calls
+	metrics Metrics
+	// tracer starts a span around every tool call
+	tracer trace.Tracer
+
+	// envDefaults fill absent tool input fields from the environment, keyed by tool name
+	envDefaults map[string][]fieldEnvDefault
+	// fieldMaxBytes caps the UTF-8 byte length of string input fields, keyed by
+	// tool name and then field name
+	fieldMaxBytes map[string]map[string]int
+
+	// resultTiming adds each tool call's duration to the result's _meta
+	resultTiming bool
+
+	// largeResultThresholds are the output sizes, in bytes, above which a tool's
+	// result is returned as a resource link, keyed by tool name
+	largeResultThresholds map[string]int
+
+	// continuationTools are the raw tools whose partial results carry a
+	// continuation token
+	continuationTools map[string]bool
+
+	// quota limits how often each client may call each tool
+	quota QuotaStore
+
+	// clientIdentifier names the client making a call for quotas and rate limits;
+	// nil uses SessionClientID
+	clientIdentifier func(ctx context.Context) string
+
+	// codec encodes raw tool payloads instead of JSON; nil keeps JSON
+	codec Codec
+
+	// rateLimits throttle calls per client, keyed by tool name
+	rateLimits map[string]rateLimit
+	// defaultRateLimit applies to tools without a rate limit of their own
+	defaultRateLimit *rateLimit
+
+	// maxConcurrentCalls bounds how many tool calls run at once across all tools;
+	// zero means unlimited
+	maxConcurrentCalls int
+	// rejectOverload fails calls beyond maxConcurrentCalls instead of queuing them
+	rejectOverload bool
+
+	// validateExamples checks tool schema examples against their schemas
+	validateExamples bool
+
+	// httpAuth validates the bearer token of every HTTP and WebSocket request,
+	// returning the caller's identity
+	httpAuth func(token string) (any, error)
+
+	// cors answers preflight requests and sets CORS headers on HTTP responses
+	cors *corsPolicy
+	// maxRequestBytes caps the size of HTTP request bodies; zero means unlimited
+	maxRequestBytes int64
+
+	// slowClientPolicy bounds the messages queued for each WebSocket session
+	slowClientPolicy *SlowClientPolicy
+
+	// events receives telemetry events; nil publishes none
+	events chan<- Event
+
+	// serverOptions are passed to mcp.NewServer when the server isn't injected
+	serverOptions *mcp.ServerOptions
+
+	// strictValidation turns server name and version warnings into errors
+	strictValidation bool
+
+	// cleanups run when the handler is closed
+	cleanups []func() error
 }
 
 // toolRegisterFunc is an internal function type that registers a tool on an MCP server.
 // This is used internally by the option functions to defer tool registration.
-type toolRegisterFunc func(*mcp.Server)
+// The call chain is passed so the tool function can be wrapped with per-call behavior.
+type toolRegisterFunc func(*mcp.Server, *mcp.Tool, *callChain)
+
+// toolEntry pairs a tool definition with its deferred registration function.
+// Keeping the definition separate lets name-targeted options adjust it before
+// it is registered.
+type toolEntry struct {
+	tool    *mcp.Tool
+	rawFunc RawToolFunc // Set for tools registered with WithRawTool
+	tags    []string    // Local grouping tags from WithToolTags
+	// outputType is TOut of a typed tool whose output schema is left to the SDK,
+	// e.g. a pointer type
+	outputType reflect.Type
+	register   toolRegisterFunc
+}
+
+// resourceEntry pairs a resource definition with the handler that reads it
+type resourceEntry struct {
+	resource *mcp.Resource
+	handler  mcp.ResourceHandler
+}
+
+// promptEntry pairs a prompt definition with the handler that renders it
+type promptEntry struct {
+	prompt  *mcp.Prompt
+	handler mcp.PromptHandler
+}
+
+// findTool returns the tool entry with the given name, or nil if none is registered
+func (cfg *handlerConfig) findTool(name string) *toolEntry {
+	for _, entry := range cfg.tools {
+		if entry.tool.Name == name {
+			return entry
+		}
+	}
+	return nil
+}
+
+// ToolInfo describes a registered tool, for building documentation or capability pages
+type ToolInfo struct {
+	Name        string
+	Description string
+	InputSchema *jsonschema.Schema
+	Tags        []string // Tags from WithToolTags; they aren't sent to clients
+}
+
+// ServerCapabilities is a read-only view of the capabilities the server advertises
+// to clients during initialization
+type ServerCapabilities struct {
+	Tools     bool // tools/list and tools/call are available
+	Resources bool // resources/list and resources/read are available
+	Prompts   bool // prompts/list and prompts/get are available
+	Logging   bool // The server can send log messages to clients
+}
 
 // Handler is the main MCP handler struct
 type Handler struct {
-	server      *mcp.Server
-	httpHandler http.Handler
+	server           *mcp.Server
+	httpHandler      http.Handler
+	chain            *callChain
+	cleanups         []func() error
+	httpAuth         func(token string) (any, error) // Optional; nil allows unauthenticated requests
+	cors             *corsPolicy                     // Optional; nil sets no CORS headers
+	maxRequestBytes  int64                           // Zero allows bodies of any size
+	slowClientPolicy *SlowClientPolicy               // Nil writes to WebSocket clients directly
+
+	// descriptionDecorator is kept so tools registered after construction are decorated too
+	descriptionDecorator func(name, description string) string
+
+	// registerMu serializes changes to the server's tools. It is held while the
+	// server notifies sessions of a change, so readers only take mu.
+	registerMu sync.Mutex
+
+	// mu guards the tool bookkeeping, which changes when tools are registered at runtime
+	mu           sync.RWMutex
+	tools        []ToolInfo
+	entries      map[string]*toolEntry // Registered tool entries, keyed by name
+	capabilities ServerCapabilities
+
+	// configuredTools are the tools given to NewHandler, which Health expects to find
+	configuredTools []string
+
+	// invokeMu guards the in-process client session used by Invoke, created on first use
+	invokeMu      sync.Mutex
+	invokeSession *mcp.ClientSession
+
+	closeOnce sync.Once
+	closed    atomic.Bool
+	closeErr  error
 }
 
 // NewHandler creates a new MCP handler with the given options
 func NewHandler(opts ...Option) (*Handler, error) {
 	cfg := &handlerConfig{
-		name:    "mcp-server",
-		version: "1.0.0",
-		tools:   make([]toolRegisterFunc, 0),
+		name:            "mcp-server",
+		version:         "1.0.0",
+		tools:           make([]*toolEntry, 0),
+		maxRequestBytes: defaultMaxRequestBytes,
 	}
 
 	// Apply all options
@@ -44,33 +235,204 @@ func NewHandler(opts ...Option) (*Handler, error) {
 		}
 	}
 
+	// Apply options that target tools by name, now that all tools are known
+	for _, modify := range cfg.toolModifiers {
+		if err := modify(cfg); err != nil {
+			return nil, fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+
+	if err := validateServerInfo(cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.validateExamples {
+		for _, entry := range cfg.tools {
+			for _, schema := range []*jsonschema.Schema{entry.tool.InputSchema, entry.tool.OutputSchema} {
+				if err := validateExamples(schema, ""); err != nil {
+					return nil, fmt.Errorf("%w: tool %q: %w", ErrInvalidExample, entry.tool.Name, err)
+				}
+			}
+		}
+	}
+
 	// Use injected server or create default
 	var server *mcp.Server
 	if cfg.server != nil {
+		// Server-level settings can't be applied to a server that already exists
+		if cfg.instructions != "" {
+			return nil, fmt.Errorf("%w: WithInstructions", ErrInjectedServer)
+		}
+		if cfg.serverOptions != nil {
+			return nil, fmt.Errorf("%w: WithServerOptions", ErrInjectedServer)
+		}
 		server = cfg.server
 	} else {
 		impl := &mcp.Implementation{
 			Name:    cfg.name,
 			Version: cfg.version,
 		}
-		server = mcp.NewServer(impl, nil)
+		var serverOpts mcp.ServerOptions
+		if cfg.serverOptions != nil {
+			serverOpts = *cfg.serverOptions
+		}
+		if cfg.instructions != "" {
+			serverOpts.Instructions = cfg.instructions
+		}
+		// Large results are stored as resources, which may not exist yet
+		if len(cfg.largeResultThresholds) > 0 {
+			serverOpts.HasResources = true
+		}
+		if cfg.events != nil {
+			serverOpts.InitializedHandler = sessionEventsHandler(cfg.events, serverOpts.InitializedHandler)
+		}
+		server = mcp.NewServer(impl, &serverOpts)
+	}
+
+	h := &Handler{
+		server:               server,
+		chain:                newCallChain(cfg),
+		cleanups:             cfg.cleanups,
+		httpAuth:             cfg.httpAuth,
+		cors:                 cfg.cors,
+		maxRequestBytes:      cfg.maxRequestBytes,
+		slowClientPolicy:     cfg.slowClientPolicy,
+		descriptionDecorator: cfg.descriptionDecorator,
+		tools:                make([]ToolInfo, 0, len(cfg.tools)),
+		entries:              make(map[string]*toolEntry, len(cfg.tools)),
+		configuredTools:      make([]string, 0, len(cfg.tools)),
+		// Mirrors the SDK, which always advertises logging and advertises each
+		// feature set once something is registered for it
+		capabilities: ServerCapabilities{
+			Resources: len(cfg.resources) > 0 || len(cfg.largeResultThresholds) > 0,
+			Prompts:   len(cfg.prompts) > 0,
+			Logging:   true,
+		},
 	}
 
 	// Register all tools
-	for _, toolRegisterFunc := range cfg.tools {
-		toolRegisterFunc(server)
+	for _, entry := range cfg.tools {
+		if err := h.addTool(entry); err != nil {
+			return nil, err
+		}
+		h.configuredTools = append(h.configuredTools, entry.tool.Name)
+	}
+
+	// Middleware added later runs first, so timing wraps the whole call
+	server.AddReceivingMiddleware(toolErrorCodeMiddleware)
+	if cfg.descriptionTranslator != nil {
+		server.AddReceivingMiddleware(descriptionTranslatorMiddleware(cfg.descriptionTranslator))
+	}
+	if len(cfg.dynamicEnums) > 0 {
+		server.AddReceivingMiddleware(dynamicEnumMiddleware(cfg.dynamicEnums))
+	}
+	if len(cfg.fieldMaxBytes) > 0 {
+		server.AddReceivingMiddleware(fieldMaxBytesMiddleware(cfg.fieldMaxBytes))
+	}
+	if len(cfg.envDefaults) > 0 {
+		server.AddReceivingMiddleware(envDefaultsMiddleware(cfg.envDefaults))
+	}
+	// Continuation envelopes are unwrapped before results are sized
+	if len(cfg.continuationTools) > 0 {
+		server.AddReceivingMiddleware(continuationMiddleware(cfg.continuationTools))
+	}
+	if len(cfg.largeResultThresholds) > 0 {
+		server.AddReceivingMiddleware(largeResultMiddleware(cfg.largeResultThresholds, newResultStore()))
+	}
+	if cfg.resultTiming {
+		server.AddReceivingMiddleware(resultTimingMiddleware)
+	}
+
+	// Register all resources
+	for _, entry := range cfg.resources {
+		server.AddResource(entry.resource, entry.handler)
+	}
+
+	// Register all prompts
+	for _, entry := range cfg.prompts {
+		server.AddPrompt(entry.prompt, entry.handler)
 	}
 
 	// Create transport handler
-	httpHandler := mcp.NewStreamableHTTPHandler(
+	h.httpHandler = mcp.NewStreamableHTTPHandler(
 		func(*http.Request) *mcp.Server { return server },
 		nil,
 	)
+	if cfg.httpAuth != nil {
+		h.httpHandler = carryIdentity(h.httpHandler)
+	}
+
+	return h, nil
+}
+
+// addTool records a tool entry and registers it on the server, rejecting names that
+// are already registered. It is safe to call while requests are being served.
+func (h *Handler) addTool(entry *toolEntry) error {
+	h.registerMu.Lock()
+	defer h.registerMu.Unlock()
+
+	name := entry.tool.Name
+	if err := h.recordTool(entry); err != nil {
+		return err
+	}
+
+	// Registering notifies every session, so it happens without holding mu
+	if err := h.registerEntry(entry); err != nil {
+		h.mu.Lock()
+		h.forgetTool(name)
+		h.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// recordTool adds entry to the tool bookkeeping, reserving its name
+func (h *Handler) recordTool(entry *toolEntry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	name := entry.tool.Name
+	if slices.ContainsFunc(h.tools, func(info ToolInfo) bool { return info.Name == name }) {
+		return fmt.Errorf("%w: %q", ErrDuplicateTool, name)
+	}
+
+	if h.descriptionDecorator != nil {
+		entry.tool.Description = h.descriptionDecorator(name, entry.tool.Description)
+	}
+	h.entries[name] = entry
+	h.tools = append(h.tools, ToolInfo{
+		Name:        name,
+		Description: entry.tool.Description,
+		InputSchema: entry.tool.InputSchema,
+		Tags:        entry.tags,
+	})
+	h.capabilities.Tools = true
+	return nil
+}
+
+// forgetTool removes the named tool from the tool bookkeeping, reporting whether it
+// was recorded. The caller must hold mu.
+func (h *Handler) forgetTool(name string) bool {
+	index := slices.IndexFunc(h.tools, func(info ToolInfo) bool { return info.Name == name })
+	if index < 0 {
+		return false
+	}
+	h.tools = slices.Delete(h.tools, index, index+1)
+	delete(h.entries, name)
+	h.capabilities.Tools = len(h.tools) > 0
+	return true
+}
 
-	return &Handler{
-		server:      server,
-		httpHandler: httpHandler,
-	}, nil
+// registerEntry adds entry's tool to the server, returning the panic the SDK raises
+// for an invalid tool as an error
+func (h *Handler) registerEntry(entry *toolEntry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %q: %v", ErrToolRegistration, entry.tool.Name, r)
+		}
+	}()
+	entry.register(h.server, entry.tool, h.chain)
+	return nil
 }
 
 // GetServer returns the underlying MCP server for advanced usage
@@ -78,11 +440,136 @@ func (h *Handler) GetServer() *mcp.Server {
 	return h.server
 }
 
+// Tools returns the tools registered on the handler, in registration order
+func (h *Handler) Tools() []ToolInfo {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return slices.Clone(h.tools)
+}
+
+// ToolsByTag returns the tools tagged with tag by WithToolTags, in registration order
+func (h *Handler) ToolsByTag(tag string) []ToolInfo {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	tools := make([]ToolInfo, 0)
+	for _, tool := range h.tools {
+		if slices.Contains(tool.Tags, tag) {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// InputSchema returns the input schema advertised for the named tool: the schema
+// generated from the input type for typed tools, or the one given for raw tools.
+// It returns ErrToolNotFound if no tool has the given name.
+func (h *Handler) InputSchema(toolName string) (*jsonschema.Schema, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, tool := range h.tools {
+		if tool.Name == toolName {
+			return tool.InputSchema, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrToolNotFound, toolName)
+}
+
+// Capabilities returns the capabilities advertised for what was registered through
+// the handler. Features added directly to an injected server are not reflected.
+func (h *Handler) Capabilities() ServerCapabilities {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.capabilities
+}
+
+// Stats returns call and error counts, latency percentiles, and the most recent
+// error for each tool that has been called. Percentiles cover the most recent calls
+// to each tool.
+func (h *Handler) Stats() map[string]ToolStats {
+	return h.chain.stats.snapshot()
+}
+
+// LastError returns the message and time of the most recent error returned by the
+// named tool, or an empty message and zero time if it hasn't failed
+func (h *Handler) LastError(name string) (string, time.Time) {
+	return h.chain.stats.lastError(name)
+}
+
 // ServeHTTP implements http.Handler for HTTP transport
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Preflight requests carry no credentials, so they are answered before auth
+	if h.cors != nil && h.cors.apply(w, r) {
+		return
+	}
+	if h.closed.Load() {
+		http.Error(w, "mcp handler is closed", http.StatusServiceUnavailable)
+		return
+	}
+	r, ok := h.authorize(w, r)
+	if !ok {
+		return
+	}
+	if r, ok = h.limitBody(w, r); !ok {
+		return
+	}
 	h.httpHandler.ServeHTTP(w, r)
 }
 
+// Close ends every connected session, which stops running transports and closes
+// streaming HTTP clients, then runs the cleanup functions registered with WithCleanup
+// in reverse order. HTTP requests after Close are rejected. Close is safe to call more
+// than once; later calls return the result of the first.
+func (h *Handler) Close() error {
+	h.closeOnce.Do(func() {
+		h.closed.Store(true)
+
+		var errs []error
+		for session := range h.server.Sessions() {
+			if err := session.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		for _, cleanup := range slices.Backward(h.cleanups) {
+			if err := cleanup(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		h.closeErr = errors.Join(errs...)
+	})
+	return h.closeErr
+}
+
+// Health reports whether the handler can serve requests, for health and readiness
+// checks on any transport. It returns nil when the server is initialized and every
+// tool given to NewHandler is registered, and otherwise an error describing the
+// problem: ErrNotInitialized for a handler not built by NewHandler, ErrHandlerClosed
+// after Close, or ErrToolNotFound when a configured tool has been unregistered.
+func (h *Handler) Health() error {
+	if h == nil || h.server == nil {
+		return ErrNotInitialized
+	}
+	if h.closed.Load() {
+		return ErrHandlerClosed
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, name := range h.configuredTools {
+		if h.entries[name] == nil {
+			return fmt.Errorf("%w: configured tool %q is not registered", ErrToolNotFound, name)
+		}
+	}
+	return nil
+}
+
+// HandlerFunc returns the handler as an http.HandlerFunc for routers that mount
+// handler functions (chi, gorilla/mux, echo via echo.WrapHandler). Requests are not
+// routed by path, so the handler can be mounted under any prefix, including behind
+// http.StripPrefix.
+func (h *Handler) HandlerFunc() http.HandlerFunc {
+	return h.ServeHTTP
+}
+
 // ServeSSE implements SSE transport by delegating to ServeHTTP
 // The MCP SDK handles the transport differences internally
 func (h *Handler) ServeSSE(w http.ResponseWriter, r *http.Request) {
@@ -114,14 +601,24 @@ func (h *Handler) ServeStdio(stdin io.Reader, stdout io.Writer) error {
 func createTypedHandler[TIn, TOut any](fn ToolFunc[TIn, TOut]) mcp.ToolHandlerFor[TIn, TOut] {
 	return func(ctx context.Context, req *mcp.CallToolRequest, input TIn) (*mcp.CallToolResult, TOut, error) {
 		// Execute the user-provided tool function
-		output, err := fn(ctx, input)
+		output, err := fn(withRequest(ctx, req), input)
 		if err != nil {
 			// Check if it's a tool error (user-facing error)
 			var toolErr *ToolError
 			if errors.As(err, &toolErr) {
-				// Tool errors are returned as regular errors - the SDK will handle them
+				// The SDK would validate the zero output if a result were returned
+				// here, so the error is returned and toolErrorCodeMiddleware rebuilds
+				// the result with toolErrorResult. The cause is dropped so it stays
+				// internal even without the middleware.
+				recordToolError(ctx, toolErr)
+				var zero TOut
+				return nil, zero, &ToolError{Message: toolErr.Message, Code: toolErr.Code, Errors: toolErr.Errors}
+			}
+			// Protocol errors carry their own JSON-RPC code
+			var protoErr *ProtocolError
+			if errors.As(err, &protoErr) {
 				var zero TOut
-				return nil, zero, err
+				return nil, zero, protoErr.wireError()
 			}
 			// Protocol error (system-level error) - return as Go error
 			var zero TOut
@@ -133,8 +630,10 @@ func createTypedHandler[TIn, TOut any](fn ToolFunc[TIn, TOut]) mcp.ToolHandlerFo
 	}
 }
 
-// createRawHandler wraps a raw function to match the MCP ToolHandler signature
-func createRawHandler(fn RawToolFunc) mcp.ToolHandler {
+// createRawHandler wraps a raw function to match the MCP ToolHandler signature.
+// The SDK does not validate raw tool arguments, so when inputSchema is non-nil the
+// arguments are validated against it before the raw function is called.
+func createRawHandler(chain *callChain, name string, fn RawToolFunc, inputSchema *jsonschema.Resolved) mcp.ToolHandler {
 	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Marshal input arguments to JSON bytes
 		inputJSON, err := json.Marshal(req.Params.Arguments)
@@ -147,23 +646,41 @@ func createRawHandler(fn RawToolFunc) mcp.ToolHandler {
 			}, nil
 		}
 
+		if inputSchema != nil {
+			if toolErr := chain.validateInput(ctx, name, inputSchema, inputJSON); toolErr != nil {
+				return toolErrorResult(toolErr), nil
+			}
+		}
+
+		payload := inputJSON
+		if chain.codec != nil {
+			if payload, err = encodePayload(chain.codec, inputJSON); err != nil {
+				return nil, err
+			}
+		}
+
 		// Execute raw function
-		outputJSON, err := fn(ctx, inputJSON)
+		outputJSON, err := fn(withRequest(ctx, req), payload)
 		if err != nil {
 			// Check if it's a tool error
 			var toolErr *ToolError
 			if errors.As(err, &toolErr) {
-				return &mcp.CallToolResult{
-					Content: []mcp.Content{
-						&mcp.TextContent{Text: toolErr.Message},
-					},
-					IsError: true,
-				}, nil
+				return toolErrorResult(toolErr), nil
+			}
+			// Protocol error, with its own JSON-RPC code if it has one
+			var protoErr *ProtocolError
+			if errors.As(err, &protoErr) {
+				return nil, protoErr.wireError()
 			}
-			// Protocol error
 			return nil, err
 		}
 
+		if chain.codec != nil {
+			if outputJSON, err = decodePayload(chain.codec, outputJSON); err != nil {
+				return nil, err
+			}
+		}
+
 		// Parse output for structured response
 		var output any
 		if err := json.Unmarshal(outputJSON, &output); err != nil {
@@ -171,12 +688,141 @@ func createRawHandler(fn RawToolFunc) mcp.ToolHandler {
 			return nil, errors.Join(ErrInvalidJSON, err)
 		}
 
-		// Return structured output
-		outputJSONStr := string(outputJSON)
-		return &mcp.CallToolResult{
+		// Return the JSON as text for display, and as structured content when it
+		// is an object (the MCP spec requires structured content to be an object)
+		result := &mcp.CallToolResult{
 			Content: []mcp.Content{
-				&mcp.TextContent{Text: outputJSONStr},
+				&mcp.TextContent{Text: string(outputJSON)},
 			},
+		}
+		if _, ok := output.(map[string]any); ok {
+			result.StructuredContent = json.RawMessage(outputJSON)
+		}
+		return result, nil
+	}
+}
+
+// createResourceHandler wraps a resource read function to match the MCP ResourceHandler
+// signature. Textual MIME types are returned as text contents, anything else as a blob.
+func createResourceHandler(mimeType string, fn ResourceReadFunc) mcp.ResourceHandler {
+	return func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		uri := req.Params.URI
+		data, err := fn(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+
+		contents := &mcp.ResourceContents{URI: uri, MIMEType: mimeType}
+		if isTextMIMEType(mimeType, data) {
+			contents.Text = string(data)
+		} else {
+			contents.Blob = data
+		}
+
+		return &mcp.ReadResourceResult{
+			Contents: []*mcp.ResourceContents{contents},
 		}, nil
 	}
 }
+
+// createPromptHandler wraps a prompt function to match the MCP PromptHandler signature,
+// rejecting requests that omit a required argument before the function is called
+func createPromptHandler(prompt *mcp.Prompt, fn PromptFunc) mcp.PromptHandler {
+	return func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		args := req.Params.Arguments
+		if args == nil {
+			args = make(map[string]string)
+		}
+		for _, arg := range prompt.Arguments {
+			if _, ok := args[arg.Name]; arg.Required && !ok {
+				return nil, fmt.Errorf("%w: %q", ErrMissingPromptArgument, arg.Name)
+			}
+		}
+
+		messages, err := fn(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+
+		result := &mcp.GetPromptResult{
+			Description: prompt.Description,
+			Messages:    make([]*mcp.PromptMessage, len(messages)),
+		}
+		for i := range messages {
+			if err := validatePromptContent(messages[i].Content); err != nil {
+				return nil, fmt.Errorf("%w: prompt %q message %d: %w", ErrInvalidPromptContent, prompt.Name, i, err)
+			}
+			result.Messages[i] = &messages[i]
+		}
+		return result, nil
+	}
+}
+
+// validatePromptContent catches content the SDK would fail to serialize, so the
+// prompt author gets a descriptive error instead of a transport failure
+func validatePromptContent(content mcp.Content) error {
+	switch c := content.(type) {
+	case nil:
+		return errors.New("content is nil")
+	case *mcp.EmbeddedResource:
+		if c == nil || c.Resource == nil {
+			return errors.New("embedded resource has no contents")
+		}
+		if c.Resource.URI == "" {
+			return errors.New("embedded resource has no URI")
+		}
+	}
+	return nil
+}
+
+// isTextMIMEType reports whether resource data of the given MIME type should be
+// returned as text. Without a MIME type, any valid UTF-8 is treated as text.
+func isTextMIMEType(mimeType string, data []byte) bool {
+	if mimeType == "" {
+		return utf8.Valid(data)
+	}
+	mediaType, _, _ := strings.Cut(mimeType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/yaml", "application/x-yaml", "application/javascript":
+		return true
+	}
+	return false
+}
+
+// toolErrorResult converts a tool error into a CallToolResult with IsError set,
+// so the client (and the LLM) can see the failure and self-correct.
+func toolErrorResult(toolErr *ToolError) *mcp.CallToolResult {
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: toolErr.Message},
+		},
+		IsError: true,
+	}
+	// Clients that branch on the error code can read it from the metadata
+	if toolErr.Code != "" {
+		result.Meta = mcp.Meta{"errorCode": toolErr.Code}
+	}
+	// Errors aggregated by MultiToolError are listed individually, so clients
+	// needn't parse the combined message
+	if len(toolErr.Errors) > 0 {
+		details := make([]map[string]any, len(toolErr.Errors))
+		for i, err := range toolErr.Errors {
+			details[i] = map[string]any{"message": err.Message}
+			if err.Code != "" {
+				details[i]["code"] = err.Code
+			}
+		}
+		if result.Meta == nil {
+			result.Meta = mcp.Meta{}
+		}
+		result.Meta["errors"] = details
+	}
+	return result
+}
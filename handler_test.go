@@ -2,15 +2,25 @@ package mcpio
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // Test types for examples
@@ -61,6 +71,47 @@ func rawFunc(ctx context.Context, input []byte) ([]byte, error) {
 	return []byte(`{"result": "processed"}`), nil
 }
 
+// connectTestClient connects an in-memory MCP client session to the handler's server
+// headerTransport adds fixed headers to every request
+type headerTransport struct {
+	header http.Header
+}
+
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, values := range t.header {
+		req.Header[key] = values
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func connectTestClient(t *testing.T, h *Handler) *mcp.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	_, err := h.server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, session.Close())
+	})
+
+	return session
+}
+
+// resultText returns the text of the first content block of a tool result
+func resultText(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+	require.NotEmpty(t, result.Content)
+	text, ok := result.Content[0].(*mcp.TextContent)
+	require.True(t, ok, "expected text content, got %T", result.Content[0])
+	return text.Text
+}
+
 func TestHandlerConstruction(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -432,3 +483,2141 @@ func TestCreateTypedHandlerProtocolError(t *testing.T) {
 	assert.Equal(t, EchoOutput{}, output)
 	assert.Equal(t, "protocol error", err.Error())
 }
+
+func TestRawToolInputValidation(t *testing.T) {
+	schema := CreateDynamicSchema([]FieldDef{
+		{Name: "kind", Type: "string", Required: true, Const: "circle"},
+		{Name: "radius", Type: "number", Required: true},
+	})
+
+	handler, err := NewHandler(WithRawTool("area", "Compute an area", schema, rawFunc))
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	tests := []struct {
+		name      string
+		args      map[string]any
+		wantError bool
+		wantText  string
+	}{
+		{
+			name:     "matching const",
+			args:     map[string]any{"kind": "circle", "radius": 2},
+			wantText: `{"result": "processed"}`,
+		},
+		{
+			name:      "mismatched const",
+			args:      map[string]any{"kind": "square", "radius": 2},
+			wantError: true,
+			wantText:  "const",
+		},
+		{
+			name:      "missing required field",
+			args:      map[string]any{"kind": "circle"},
+			wantError: true,
+			wantText:  "radius",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+				Name:      "area",
+				Arguments: tt.args,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantError, result.IsError)
+			assert.Contains(t, resultText(t, result), tt.wantText)
+		})
+	}
+}
+
+func TestRawToolInvalidSchema(t *testing.T) {
+	schema := &jsonschema.Schema{Type: "object", Ref: "#/$defs/missing"}
+
+	_, err := NewHandler(WithRawTool("broken", "Broken schema", schema, rawFunc))
+	require.ErrorIs(t, err, ErrInvalidSchema)
+}
+
+func TestWithToolWithSchemas(t *testing.T) {
+	inputSchema := CreateDynamicSchema([]FieldDef{
+		{Name: "text", Type: "string", Description: "Text to echo", Required: true},
+	})
+	outputSchema := CreateDynamicSchema([]FieldDef{
+		{Name: "message", Type: "string", Description: "Custom output description", Required: true},
+	})
+
+	t.Run("advertises overridden schemas", func(t *testing.T) {
+		handler, err := NewHandler(WithToolWithSchemas("echo", "Echo input", inputSchema, outputSchema, echoFunc))
+		require.NoError(t, err)
+		session := connectTestClient(t, handler)
+
+		list, err := session.ListTools(context.Background(), nil)
+		require.NoError(t, err)
+		require.Len(t, list.Tools, 1)
+
+		tool := list.Tools[0]
+		assert.Equal(t, "echo", tool.Name)
+		require.NotNil(t, tool.OutputSchema)
+		require.Contains(t, tool.OutputSchema.Properties, "message")
+		assert.Equal(t, "Custom output description", tool.OutputSchema.Properties["message"].Description)
+		require.Contains(t, tool.InputSchema.Properties, "text")
+
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "echo",
+			Arguments: map[string]any{"text": "hello"},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+		assert.JSONEq(t, `{"message":"hello"}`, resultText(t, result))
+	})
+
+	t.Run("option errors", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			toolName string
+			in       *jsonschema.Schema
+			out      *jsonschema.Schema
+			wantErr  error
+		}{
+			{"empty tool name", "", inputSchema, outputSchema, ErrEmptyToolName},
+			{"nil input schema", "echo", nil, outputSchema, ErrNilSchema},
+			{"nil output schema", "echo", inputSchema, nil, ErrNilSchema},
+			{"non-object schema", "echo", inputSchema, &jsonschema.Schema{Type: "string"}, ErrInvalidSchema},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := NewHandler(WithToolWithSchemas(tt.toolName, "desc", tt.in, tt.out, echoFunc))
+				require.ErrorIs(t, err, tt.wantErr)
+			})
+		}
+	})
+}
+
+func TestWithRawToolOutputSample(t *testing.T) {
+	inputSchema := CreateObjectSchema("Raw input", map[string]string{"data": "Input data"}, nil)
+
+	t.Run("struct sample", func(t *testing.T) {
+		type sampleOutput struct {
+			Result string `json:"result"          jsonschema:"Processing result"`
+			Count  int    `json:"count,omitempty"`
+		}
+
+		handler, err := NewHandler(
+			WithRawToolOutputSample("process", sampleOutput{}),
+			WithRawTool("process", "Process raw data", inputSchema, rawFunc),
+		)
+		require.NoError(t, err)
+		session := connectTestClient(t, handler)
+
+		list, err := session.ListTools(context.Background(), nil)
+		require.NoError(t, err)
+		require.Len(t, list.Tools, 1)
+
+		outputSchema := list.Tools[0].OutputSchema
+		require.NotNil(t, outputSchema)
+		assert.Equal(t, "object", outputSchema.Type)
+		require.Contains(t, outputSchema.Properties, "result")
+		assert.Equal(t, "string", outputSchema.Properties["result"].Type)
+		assert.Equal(t, "Processing result", outputSchema.Properties["result"].Description)
+		assert.Equal(t, "integer", outputSchema.Properties["count"].Type)
+		assert.Equal(t, []string{"result"}, outputSchema.Required)
+	})
+
+	t.Run("map sample", func(t *testing.T) {
+		sample := map[string]any{
+			"result": "processed",
+			"score":  0.5,
+			"ok":     true,
+			"tags":   []string{"a"},
+			"meta":   map[string]any{"source": "x"},
+		}
+
+		handler, err := NewHandler(
+			WithRawTool("process", "Process raw data", inputSchema, rawFunc),
+			WithRawToolOutputSample("process", sample),
+		)
+		require.NoError(t, err)
+		session := connectTestClient(t, handler)
+
+		list, err := session.ListTools(context.Background(), nil)
+		require.NoError(t, err)
+		outputSchema := list.Tools[0].OutputSchema
+		require.NotNil(t, outputSchema)
+
+		assert.Equal(t, "string", outputSchema.Properties["result"].Type)
+		assert.Equal(t, "number", outputSchema.Properties["score"].Type)
+		assert.Equal(t, "boolean", outputSchema.Properties["ok"].Type)
+		assert.Equal(t, "array", outputSchema.Properties["tags"].Type)
+		require.NotNil(t, outputSchema.Properties["tags"].Items)
+		assert.Equal(t, "string", outputSchema.Properties["tags"].Items.Type)
+		assert.Equal(t, "object", outputSchema.Properties["meta"].Type)
+		assert.Contains(t, outputSchema.Properties["meta"].Properties, "source")
+		assert.Equal(t, []string{"meta", "ok", "result", "score", "tags"}, outputSchema.Required)
+	})
+
+	t.Run("option errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			opts    []Option
+			wantErr error
+		}{
+			{
+				name:    "unknown tool",
+				opts:    []Option{WithRawToolOutputSample("missing", map[string]any{"a": 1})},
+				wantErr: ErrToolNotFound,
+			},
+			{
+				name: "typed tool",
+				opts: []Option{
+					WithTool("echo", "Echo input", echoFunc),
+					WithRawToolOutputSample("echo", map[string]any{"a": 1}),
+				},
+				wantErr: ErrToolNotFound,
+			},
+			{
+				name:    "non-object sample",
+				opts:    []Option{WithRawToolOutputSample("process", []string{"a"})},
+				wantErr: ErrInvalidSchema,
+			},
+			{
+				name:    "nil sample",
+				opts:    []Option{WithRawToolOutputSample("process", nil)},
+				wantErr: ErrNilSchema,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := NewHandler(tt.opts...)
+				require.ErrorIs(t, err, tt.wantErr)
+			})
+		}
+	})
+}
+
+func TestRawToolMultiToolError(t *testing.T) {
+	batchFunc := func(ctx context.Context, input []byte) ([]byte, error) {
+		return nil, MultiToolError(
+			ValidationError("item 3 failed"),
+			WrapToolError(errors.New("connection refused"), "item 7 failed"),
+		)
+	}
+	schema := CreateObjectSchema("Batch input", map[string]string{"items": "Items"}, nil)
+
+	handler, err := NewHandler(WithRawTool("batch", "Validate a batch", schema, batchFunc))
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "batch",
+		Arguments: map[string]any{},
+	})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+
+	assert.Equal(t, "MULTIPLE_ERRORS", result.Meta["errorCode"])
+	assert.Equal(t, []any{
+		map[string]any{"code": "VALIDATION_ERROR", "message": "item 3 failed"},
+		map[string]any{"message": "item 7 failed"},
+	}, result.Meta["errors"])
+	assert.NotContains(t, resultText(t, result), "connection refused")
+}
+
+func TestWithDescriptionDecorator(t *testing.T) {
+	t.Run("decorates all tools", func(t *testing.T) {
+		schema := CreateObjectSchema("Raw input", map[string]string{"data": "Input data"}, nil)
+		var decorated []string
+
+		handler, err := NewHandler(
+			WithDescriptionDecorator(func(name, description string) string {
+				decorated = append(decorated, name)
+				return "[beta] " + description
+			}),
+			WithTool("echo", "Echo input", echoFunc),
+			WithTool("calculate", "Perform arithmetic", calculateFunc),
+			WithRawTool("process", "Process raw data", schema, rawFunc),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"echo", "calculate", "process"}, decorated)
+
+		session := connectTestClient(t, handler)
+		list, err := session.ListTools(context.Background(), nil)
+		require.NoError(t, err)
+
+		descriptions := make(map[string]string)
+		for _, tool := range list.Tools {
+			descriptions[tool.Name] = tool.Description
+		}
+		assert.Equal(t, map[string]string{
+			"echo":      "[beta] Echo input",
+			"calculate": "[beta] Perform arithmetic",
+			"process":   "[beta] Process raw data",
+		}, descriptions)
+	})
+
+	t.Run("nil decorator", func(t *testing.T) {
+		_, err := NewHandler(WithDescriptionDecorator(nil))
+		require.ErrorIs(t, err, ErrNilFunction)
+	})
+}
+
+func TestWithDescriptionTranslator(t *testing.T) {
+	translations := map[string]map[string]string{
+		"fr": {"echo": "Renvoie le texte"},
+		"de": {"echo": "Gibt den Text zurück"},
+	}
+	translate := func(locale, name, description string) string {
+		return translations[locale][name]
+	}
+
+	handler, err := NewHandler(
+		WithTool("echo", "Echo input", echoFunc),
+		WithTool("calculate", "Perform arithmetic", calculateFunc),
+		WithDescriptionTranslator(translate),
+	)
+	require.NoError(t, err)
+
+	descriptions := func(t *testing.T, session *mcp.ClientSession, params *mcp.ListToolsParams) map[string]string {
+		t.Helper()
+		list, err := session.ListTools(context.Background(), params)
+		require.NoError(t, err)
+		got := make(map[string]string, len(list.Tools))
+		for _, tool := range list.Tools {
+			got[tool.Name] = tool.Description
+		}
+		return got
+	}
+
+	t.Run("locale from request meta", func(t *testing.T) {
+		session := connectTestClient(t, handler)
+		got := descriptions(t, session, &mcp.ListToolsParams{Meta: mcp.Meta{"locale": "fr"}})
+		assert.Equal(t, "Renvoie le texte", got["echo"])
+		assert.Equal(t, "Perform arithmetic", got["calculate"], "untranslated tools keep their description")
+	})
+
+	t.Run("no locale", func(t *testing.T) {
+		session := connectTestClient(t, handler)
+		got := descriptions(t, session, nil)
+		assert.Equal(t, "Echo input", got["echo"])
+	})
+
+	t.Run("unknown locale", func(t *testing.T) {
+		session := connectTestClient(t, handler)
+		got := descriptions(t, session, &mcp.ListToolsParams{Meta: mcp.Meta{"locale": "ja"}})
+		assert.Equal(t, "Echo input", got["echo"])
+	})
+
+	t.Run("locale from Accept-Language", func(t *testing.T) {
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+
+		header := http.Header{"Accept-Language": {"en;q=0.5, de, fr;q=0.8"}}
+		client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+		session, err := client.Connect(context.Background(), &mcp.StreamableClientTransport{
+			Endpoint:   server.URL,
+			HTTPClient: &http.Client{Transport: headerTransport{header: header}},
+		}, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			assert.NoError(t, session.Close())
+		})
+
+		got := descriptions(t, session, nil)
+		assert.Equal(t, "Gibt den Text zurück", got["echo"])
+
+		// The request's own locale wins over the header
+		got = descriptions(t, session, &mcp.ListToolsParams{Meta: mcp.Meta{"locale": "fr"}})
+		assert.Equal(t, "Renvoie le texte", got["echo"])
+	})
+
+	t.Run("registered tools are unchanged", func(t *testing.T) {
+		for _, tool := range handler.Tools() {
+			if tool.Name == "echo" {
+				assert.Equal(t, "Echo input", tool.Description)
+			}
+		}
+	})
+
+	t.Run("nil translator", func(t *testing.T) {
+		_, err := NewHandler(WithDescriptionTranslator(nil))
+		require.ErrorIs(t, err, ErrNilFunction)
+	})
+}
+
+func TestWithDynamicEnum(t *testing.T) {
+	var mu sync.Mutex
+	models := []string{"small", "large"}
+	availableModels := func(ctx context.Context) []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(models)
+	}
+	setModels := func(updated ...string) {
+		mu.Lock()
+		defer mu.Unlock()
+		models = updated
+	}
+
+	schema := CreateDynamicSchema([]FieldDef{
+		{Name: "model", Type: "string", Required: true},
+		{Name: "prompt", Type: "string"},
+	})
+	handler, err := NewHandler(
+		WithRawTool("generate", "Generate text", schema, rawFunc),
+		WithDynamicEnum("generate", "model", availableModels),
+		WithTool("echo", "Echo input", echoFunc),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	listEnum := func(t *testing.T) []any {
+		t.Helper()
+		list, err := session.ListTools(context.Background(), nil)
+		require.NoError(t, err)
+		for _, tool := range list.Tools {
+			if tool.Name == "generate" {
+				assert.Nil(t, tool.InputSchema.Properties["prompt"].Enum)
+				return tool.InputSchema.Properties["model"].Enum
+			}
+		}
+		t.Fatal("generate tool not listed")
+		return nil
+	}
+
+	assert.Equal(t, []any{"small", "large"}, listEnum(t))
+	setModels("small", "large", "xlarge")
+	assert.Equal(t, []any{"small", "large", "xlarge"}, listEnum(t))
+	setModels()
+	assert.Nil(t, listEnum(t))
+	assert.Nil(t, schema.Properties["model"].Enum, "the registered schema is not modified")
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr error
+	}{
+		{"empty tool name", []Option{WithDynamicEnum("", "model", availableModels)}, ErrEmptyToolName},
+		{"empty field name", []Option{WithDynamicEnum("generate", "", availableModels)}, ErrEmptyFieldName},
+		{"nil function", []Option{WithDynamicEnum("generate", "model", nil)}, ErrNilFunction},
+		{"unknown tool", []Option{WithDynamicEnum("missing", "model", availableModels)}, ErrToolNotFound},
+		{"unknown field", []Option{WithTool("echo", "Echo input", echoFunc), WithDynamicEnum("echo", "model", availableModels)}, ErrFieldNotFound},
+		{"non-string field", []Option{WithTool("calculate", "Calc", calculateFunc), WithDynamicEnum("calculate", "a", availableModels)}, ErrInvalidSchema},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHandler(tt.opts...)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"fr", "fr"},
+		{"fr-CA, fr;q=0.9, en;q=0.8", "fr-CA"},
+		{"en;q=0.5, de", "de"},
+		{"*, es;q=0.1", "es"},
+		{"en;q=bad, it;q=0.3", "it"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			assert.Equal(t, tt.want, preferredLanguage(tt.header))
+		})
+	}
+}
+
+func TestHandlerTools(t *testing.T) {
+	schema := CreateObjectSchema("Raw input", map[string]string{"data": "Input data"}, []string{"data"})
+
+	handler, err := NewHandler(
+		WithTool("echo", "Echo input", echoFunc),
+		WithRawTool("process", "Process raw data", schema, rawFunc),
+	)
+	require.NoError(t, err)
+
+	tools := handler.Tools()
+	require.Len(t, tools, 2)
+
+	assert.Equal(t, "echo", tools[0].Name)
+	assert.Equal(t, "Echo input", tools[0].Description)
+	require.NotNil(t, tools[0].InputSchema)
+	assert.Contains(t, tools[0].InputSchema.Properties, "text")
+
+	assert.Equal(t, "process", tools[1].Name)
+	assert.Equal(t, "Process raw data", tools[1].Description)
+	assert.Same(t, schema, tools[1].InputSchema)
+
+	t.Run("returns a copy", func(t *testing.T) {
+		tools[0].Name = "changed"
+		assert.Equal(t, "echo", handler.Tools()[0].Name)
+	})
+
+	t.Run("empty handler", func(t *testing.T) {
+		handler, err := NewHandler()
+		require.NoError(t, err)
+		assert.Empty(t, handler.Tools())
+	})
+}
+
+func TestHandlerInputSchema(t *testing.T) {
+	rawSchema := CreateObjectSchema("Raw input", map[string]string{"data": "Input data"}, []string{"data"})
+
+	handler, err := NewHandler(
+		WithTool("calculate", "Perform arithmetic", calculateFunc),
+		WithRawTool("process", "Process raw data", rawSchema, rawFunc),
+	)
+	require.NoError(t, err)
+	require.NoError(t, handler.RegisterTool("echo", "Echo input", echoFunc))
+
+	t.Run("typed tool", func(t *testing.T) {
+		schema, err := handler.InputSchema("calculate")
+		require.NoError(t, err)
+		assert.Equal(t, "object", schema.Type)
+		assert.Len(t, schema.Properties, 3)
+		assert.Equal(t, "string", schema.Properties["operation"].Type)
+		assert.Equal(t, "Operation to perform", schema.Properties["operation"].Description)
+		assert.Equal(t, "number", schema.Properties["a"].Type)
+		assert.Equal(t, "number", schema.Properties["b"].Type)
+		assert.ElementsMatch(t, []string{"operation", "a", "b"}, schema.Required)
+	})
+
+	t.Run("raw tool", func(t *testing.T) {
+		schema, err := handler.InputSchema("process")
+		require.NoError(t, err)
+		assert.Same(t, rawSchema, schema)
+	})
+
+	t.Run("runtime registered tool", func(t *testing.T) {
+		schema, err := handler.InputSchema("echo")
+		require.NoError(t, err)
+		assert.Contains(t, schema.Properties, "text")
+	})
+
+	t.Run("matches the advertised schema", func(t *testing.T) {
+		session := connectTestClient(t, handler)
+		list, err := session.ListTools(context.Background(), nil)
+		require.NoError(t, err)
+		for _, tool := range list.Tools {
+			schema, err := handler.InputSchema(tool.Name)
+			require.NoError(t, err)
+			want, err := json.Marshal(schema)
+			require.NoError(t, err)
+			got, err := json.Marshal(tool.InputSchema)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(want), string(got), tool.Name)
+		}
+	})
+
+	t.Run("unknown tool", func(t *testing.T) {
+		schema, err := handler.InputSchema("missing")
+		require.ErrorIs(t, err, ErrToolNotFound)
+		assert.Nil(t, schema)
+	})
+}
+
+func TestWithToolInvalidInputType(t *testing.T) {
+	stringFunc := func(ctx context.Context, input string) (EchoOutput, error) {
+		return EchoOutput{Message: input}, nil
+	}
+
+	handler, err := NewHandler(WithTool("bad", "Non-object input", stringFunc))
+	require.ErrorIs(t, err, ErrInvalidSchema)
+	assert.Nil(t, handler)
+}
+
+func TestWithInstructions(t *testing.T) {
+	t.Run("instructions reach initialize result", func(t *testing.T) {
+		handler, err := NewHandler(
+			WithInstructions("Use calculate for arithmetic; never guess results."),
+			WithTool("calculate", "Perform arithmetic", calculateFunc),
+		)
+		require.NoError(t, err)
+
+		session := connectTestClient(t, handler)
+		initResult := session.InitializeResult()
+		require.NotNil(t, initResult)
+		assert.Equal(t, "Use calculate for arithmetic; never guess results.", initResult.Instructions)
+	})
+
+	t.Run("empty instructions", func(t *testing.T) {
+		_, err := NewHandler(WithInstructions(""))
+		require.ErrorIs(t, err, ErrEmptyInstructions)
+	})
+
+	t.Run("injected server", func(t *testing.T) {
+		server := mcp.NewServer(&mcp.Implementation{Name: "test-server", Version: "1.0.0"}, nil)
+		_, err := NewHandler(WithServer(server), WithInstructions("guidance"))
+		require.ErrorIs(t, err, ErrInjectedServer)
+	})
+}
+
+func TestWithServerOptions(t *testing.T) {
+	t.Run("options reach the server", func(t *testing.T) {
+		initialized := make(chan struct{}, 1)
+		handler, err := NewHandler(
+			WithServerOptions(&mcp.ServerOptions{
+				PageSize:     1,
+				Instructions: "from server options",
+				InitializedHandler: func(context.Context, *mcp.InitializedRequest) {
+					initialized <- struct{}{}
+				},
+			}),
+			WithTool("echo", "Echo input", echoFunc),
+			WithTool("calculate", "Perform arithmetic", calculateFunc),
+		)
+		require.NoError(t, err)
+
+		session := connectTestClient(t, handler)
+		assert.Equal(t, "from server options", session.InitializeResult().Instructions)
+
+		list, err := session.ListTools(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Len(t, list.Tools, 1)
+		assert.NotEmpty(t, list.NextCursor)
+
+		select {
+		case <-initialized:
+		case <-time.After(time.Second):
+			t.Fatal("initialized handler was not called")
+		}
+	})
+
+	t.Run("instructions option takes precedence", func(t *testing.T) {
+		handler, err := NewHandler(
+			WithServerOptions(&mcp.ServerOptions{Instructions: "from server options"}),
+			WithInstructions("from WithInstructions"),
+		)
+		require.NoError(t, err)
+
+		session := connectTestClient(t, handler)
+		assert.Equal(t, "from WithInstructions", session.InitializeResult().Instructions)
+	})
+
+	t.Run("nil options", func(t *testing.T) {
+		_, err := NewHandler(WithServerOptions(nil))
+		require.ErrorIs(t, err, ErrNilServerOptions)
+	})
+
+	t.Run("injected server", func(t *testing.T) {
+		server := mcp.NewServer(&mcp.Implementation{Name: "test-server", Version: "1.0.0"}, nil)
+		_, err := NewHandler(WithServer(server), WithServerOptions(&mcp.ServerOptions{PageSize: 1}))
+		require.ErrorIs(t, err, ErrInjectedServer)
+	})
+}
+
+func TestWithResource(t *testing.T) {
+	readConfig := func(ctx context.Context, uri string) ([]byte, error) {
+		return []byte(`{"debug": true}`), nil
+	}
+	readImage := func(ctx context.Context, uri string) ([]byte, error) {
+		return []byte{0x89, 0x50, 0x4e, 0x47}, nil
+	}
+
+	t.Run("lists and reads resources", func(t *testing.T) {
+		handler, err := NewHandler(
+			WithResource("file:///config.json", "config", "Server configuration", "application/json", readConfig),
+			WithResource("file:///logo.png", "logo", "Logo image", "image/png", readImage),
+		)
+		require.NoError(t, err)
+		session := connectTestClient(t, handler)
+
+		list, err := session.ListResources(context.Background(), nil)
+		require.NoError(t, err)
+		require.Len(t, list.Resources, 2)
+
+		byURI := make(map[string]*mcp.Resource)
+		for _, r := range list.Resources {
+			byURI[r.URI] = r
+		}
+		require.Contains(t, byURI, "file:///config.json")
+		assert.Equal(t, "config", byURI["file:///config.json"].Name)
+		assert.Equal(t, "Server configuration", byURI["file:///config.json"].Description)
+		assert.Equal(t, "application/json", byURI["file:///config.json"].MIMEType)
+
+		config, err := session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: "file:///config.json"})
+		require.NoError(t, err)
+		require.Len(t, config.Contents, 1)
+		assert.JSONEq(t, `{"debug": true}`, config.Contents[0].Text)
+
+		logo, err := session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: "file:///logo.png"})
+		require.NoError(t, err)
+		require.Len(t, logo.Contents, 1)
+		assert.Equal(t, []byte{0x89, 0x50, 0x4e, 0x47}, logo.Contents[0].Blob)
+		assert.Empty(t, logo.Contents[0].Text)
+	})
+
+	t.Run("option errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			uri     string
+			resName string
+			read    ResourceReadFunc
+			wantErr error
+		}{
+			{"empty uri", "", "config", readConfig, ErrEmptyResourceURI},
+			{"relative uri", "config.json", "config", readConfig, ErrInvalidResourceURI},
+			{"unparsable uri", "file://%zz", "config", readConfig, ErrInvalidResourceURI},
+			{"empty name", "file:///config.json", "", readConfig, ErrEmptyResourceName},
+			{"nil read function", "file:///config.json", "config", nil, ErrNilFunction},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := NewHandler(WithResource(tt.uri, tt.resName, "desc", "text/plain", tt.read))
+				require.ErrorIs(t, err, tt.wantErr)
+			})
+		}
+	})
+
+	t.Run("duplicate uri", func(t *testing.T) {
+		_, err := NewHandler(
+			WithResource("file:///config.json", "config", "App config", "application/json", readConfig),
+			WithResource("file:///config.json", "config-copy", "Same file", "application/json", readConfig),
+		)
+		require.ErrorIs(t, err, ErrDuplicateResource)
+		assert.Contains(t, err.Error(), "file:///config.json")
+
+		_, err = NewHandler(
+			WithResource("file:///config.json", "config", "App config", "application/json", readConfig),
+			WithResource("file:///other.json", "other", "Other config", "application/json", readConfig),
+		)
+		require.NoError(t, err)
+	})
+}
+
+func TestWithToolAnnotations(t *testing.T) {
+	t.Run("annotations reach registered tool", func(t *testing.T) {
+		openWorld := false
+		handler, err := NewHandler(
+			WithTool("echo", "Echo input", echoFunc),
+			WithTool("calculate", "Perform arithmetic", calculateFunc),
+			WithToolAnnotations("echo", ToolAnnotations{
+				Title:         "Echo",
+				ReadOnlyHint:  true,
+				OpenWorldHint: &openWorld,
+			}),
+		)
+		require.NoError(t, err)
+		session := connectTestClient(t, handler)
+
+		list, err := session.ListTools(context.Background(), nil)
+		require.NoError(t, err)
+
+		tools := make(map[string]*mcp.Tool)
+		for _, tool := range list.Tools {
+			tools[tool.Name] = tool
+		}
+		require.NotNil(t, tools["echo"].Annotations)
+		assert.True(t, tools["echo"].Annotations.ReadOnlyHint)
+		assert.Equal(t, "Echo", tools["echo"].Annotations.Title)
+		require.NotNil(t, tools["echo"].Annotations.OpenWorldHint)
+		assert.False(t, *tools["echo"].Annotations.OpenWorldHint)
+		assert.Nil(t, tools["calculate"].Annotations)
+	})
+
+	t.Run("option errors", func(t *testing.T) {
+		_, err := NewHandler(WithToolAnnotations("", ToolAnnotations{}))
+		require.ErrorIs(t, err, ErrEmptyToolName)
+
+		_, err = NewHandler(WithToolAnnotations("missing", ToolAnnotations{ReadOnlyHint: true}))
+		require.ErrorIs(t, err, ErrToolNotFound)
+	})
+}
+
+func TestRawToolStructuredContent(t *testing.T) {
+	schema := CreateObjectSchema("Raw input", map[string]string{"data": "Input data"}, nil)
+	listFunc := func(ctx context.Context, input []byte) ([]byte, error) {
+		return []byte(`[1, 2, 3]`), nil
+	}
+	objectFunc := func(ctx context.Context, input []byte) ([]byte, error) {
+		return []byte(`{"result": "processed", "count": 2, "tags": ["a", "b"]}`), nil
+	}
+
+	handler, err := NewHandler(
+		WithRawTool("object", "Return an object", schema, objectFunc),
+		WithRawTool("list", "Return an array", schema, listFunc),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	t.Run("object output", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "object", Arguments: map[string]any{}})
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+
+		assert.Equal(t, map[string]any{
+			"result": "processed",
+			"count":  float64(2),
+			"tags":   []any{"a", "b"},
+		}, result.StructuredContent)
+		assert.JSONEq(t, `{"result": "processed", "count": 2, "tags": ["a", "b"]}`, resultText(t, result))
+	})
+
+	t.Run("non-object output is text only", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "list", Arguments: map[string]any{}})
+		require.NoError(t, err)
+		assert.Nil(t, result.StructuredContent)
+		assert.JSONEq(t, `[1, 2, 3]`, resultText(t, result))
+	})
+}
+
+func TestHandlerCapabilities(t *testing.T) {
+	readConfig := func(ctx context.Context, uri string) ([]byte, error) {
+		return []byte("{}"), nil
+	}
+	prompt := func(ctx context.Context, args map[string]string) ([]mcp.PromptMessage, error) {
+		return nil, nil
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want ServerCapabilities
+	}{
+		{
+			name: "nothing registered",
+			want: ServerCapabilities{Logging: true},
+		},
+		{
+			name: "tools only",
+			opts: []Option{WithTool("echo", "Echo input", echoFunc)},
+			want: ServerCapabilities{Tools: true, Logging: true},
+		},
+		{
+			name: "tools, resources and prompts",
+			opts: []Option{
+				WithTool("echo", "Echo input", echoFunc),
+				WithResource("file:///config.json", "config", "Config", "application/json", readConfig),
+				WithPrompt("greet", "Greeting", nil, prompt),
+			},
+			want: ServerCapabilities{Tools: true, Resources: true, Prompts: true, Logging: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := NewHandler(tt.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, handler.Capabilities())
+
+			// The view must match what the SDK server advertises on initialize
+			advertised := connectTestClient(t, handler).InitializeResult().Capabilities
+			assert.Equal(t, tt.want.Tools, advertised.Tools != nil)
+			assert.Equal(t, tt.want.Resources, advertised.Resources != nil)
+			assert.Equal(t, tt.want.Prompts, advertised.Prompts != nil)
+			assert.Equal(t, tt.want.Logging, advertised.Logging != nil)
+		})
+	}
+}
+
+func TestWithToolTimeout(t *testing.T) {
+	toolCanceled := make(chan struct{})
+	slowFunc := func(ctx context.Context, input EchoInput) (EchoOutput, error) {
+		<-ctx.Done()
+		close(toolCanceled)
+		return EchoOutput{}, ctx.Err()
+	}
+	slowRawFunc := func(ctx context.Context, input []byte) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	schema := CreateObjectSchema("Raw input", map[string]string{"data": "Input data"}, nil)
+
+	handler, err := NewHandler(
+		WithTool("slow", "Never finishes", slowFunc),
+		WithTool("echo", "Echo input", echoFunc),
+		WithRawTool("slow_raw", "Never finishes", schema, slowRawFunc),
+		WithToolTimeout("slow", 20*time.Millisecond),
+		WithToolTimeout("echo", time.Second),
+		WithToolTimeout("slow_raw", 20*time.Millisecond),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	t.Run("exceeds timeout", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "slow",
+			Arguments: map[string]any{"text": "hi"},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "TIMEOUT", result.Meta["errorCode"])
+
+		select {
+		case <-toolCanceled:
+		case <-time.After(time.Second):
+			t.Fatal("tool context was not cancelled")
+		}
+	})
+
+	t.Run("raw tool exceeds timeout", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "slow_raw",
+			Arguments: map[string]any{"data": "x"},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "exceeded")
+	})
+
+	t.Run("completes under timeout", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "echo",
+			Arguments: map[string]any{"text": "hi"},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+	})
+
+	t.Run("option errors", func(t *testing.T) {
+		_, err := NewHandler(WithToolTimeout("", time.Second))
+		require.ErrorIs(t, err, ErrEmptyToolName)
+
+		_, err = NewHandler(WithTool("echo", "Echo input", echoFunc), WithToolTimeout("echo", 0))
+		require.ErrorIs(t, err, ErrInvalidTimeout)
+
+		_, err = NewHandler(WithToolTimeout("missing", time.Second))
+		require.ErrorIs(t, err, ErrToolNotFound)
+	})
+}
+
+func TestWithDefaultToolTimeout(t *testing.T) {
+	sleepFunc := func(ctx context.Context, input EchoInput) (EchoOutput, error) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+			return EchoOutput{Message: input.Text}, nil
+		case <-ctx.Done():
+			return EchoOutput{}, ctx.Err()
+		}
+	}
+
+	handler, err := NewHandler(
+		WithTool("slow", "Sleeps before echoing", sleepFunc),
+		WithTool("slow_allowed", "Sleeps before echoing", sleepFunc),
+		WithDefaultToolTimeout(20*time.Millisecond),
+		WithToolTimeout("slow_allowed", 5*time.Second),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	t.Run("default applies", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "slow",
+			Arguments: map[string]any{"text": "hi"},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "TIMEOUT", result.Meta["errorCode"])
+	})
+
+	t.Run("per-tool timeout takes precedence", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "slow_allowed",
+			Arguments: map[string]any{"text": "hi"},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+	})
+
+	t.Run("zero disables the default", func(t *testing.T) {
+		handler, err := NewHandler(
+			WithTool("slow", "Sleeps before echoing", sleepFunc),
+			WithDefaultToolTimeout(0),
+		)
+		require.NoError(t, err)
+		session := connectTestClient(t, handler)
+
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "slow",
+			Arguments: map[string]any{"text": "hi"},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+	})
+
+	t.Run("negative duration", func(t *testing.T) {
+		_, err := NewHandler(WithDefaultToolTimeout(-time.Second))
+		require.ErrorIs(t, err, ErrInvalidTimeout)
+	})
+}
+
+func TestWithMaxConcurrentCalls(t *testing.T) {
+	const limit = 2
+
+	// newHandler returns a handler whose tool blocks until release is closed,
+	// signalling started as each call begins and tracking peak concurrency
+	newHandler := func(t *testing.T, opts ...Option) (h *Handler, started chan struct{}, release chan struct{}, peak *atomic.Int32) {
+		t.Helper()
+		started = make(chan struct{}, limit+2)
+		release = make(chan struct{})
+		peak = new(atomic.Int32)
+		var running atomic.Int32
+		slowFunc := func(ctx context.Context, input EchoInput) (EchoOutput, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			started <- struct{}{}
+			<-release
+			return EchoOutput{Message: input.Text}, nil
+		}
+
+		opts = append([]Option{WithTool("slow", "Blocks until released", slowFunc), WithMaxConcurrentCalls(limit)}, opts...)
+		h, err := NewHandler(opts...)
+		require.NoError(t, err)
+		return h, started, release, peak
+	}
+
+	// callAll fires n concurrent calls and returns their results once all finish
+	callAll := func(t *testing.T, session *mcp.ClientSession, n int) <-chan *mcp.CallToolResult {
+		t.Helper()
+		results := make(chan *mcp.CallToolResult, n)
+		for range n {
+			go func() {
+				result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+					Name:      "slow",
+					Arguments: map[string]any{"text": "hi"},
+				})
+				if assert.NoError(t, err) {
+					results <- result
+				}
+			}()
+		}
+		return results
+	}
+
+	waitStarted := func(t *testing.T, started <-chan struct{}, n int) {
+		t.Helper()
+		for range n {
+			select {
+			case <-started:
+			case <-time.After(2 * time.Second):
+				t.Fatal("tool call did not start")
+			}
+		}
+	}
+
+	t.Run("queues excess calls", func(t *testing.T) {
+		handler, started, release, peak := newHandler(t)
+		session := connectTestClient(t, handler)
+
+		results := callAll(t, session, limit+2)
+		waitStarted(t, started, limit)
+
+		select {
+		case <-started:
+			t.Fatal("call started beyond the concurrency limit")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(release)
+		waitStarted(t, started, 2)
+		for range limit + 2 {
+			result := <-results
+			assert.False(t, result.IsError, resultText(t, result))
+		}
+		assert.Equal(t, int32(limit), peak.Load())
+	})
+
+	t.Run("rejects excess calls", func(t *testing.T) {
+		handler, started, release, peak := newHandler(t, WithOverloadRejection(true))
+		session := connectTestClient(t, handler)
+
+		results := callAll(t, session, limit)
+		waitStarted(t, started, limit)
+
+		rejected := callAll(t, session, 2)
+		for range 2 {
+			result := <-rejected
+			assert.True(t, result.IsError)
+			assert.Equal(t, "OVERLOADED", result.Meta["errorCode"])
+		}
+
+		close(release)
+		for range limit {
+			assert.False(t, (<-results).IsError)
+		}
+		assert.Equal(t, int32(limit), peak.Load())
+
+		// Slots are released once calls finish
+		result := <-callAll(t, session, 1)
+		assert.False(t, result.IsError)
+	})
+
+	t.Run("timed out calls keep their slot", func(t *testing.T) {
+		release := make(chan struct{})
+		// The tool ignores cancellation, so it keeps running after its timeout
+		stuckFunc := func(ctx context.Context, input EchoInput) (EchoOutput, error) {
+			<-release
+			return EchoOutput{Message: input.Text}, nil
+		}
+		handler, err := NewHandler(
+			WithTool("slow", "Ignores cancellation", stuckFunc),
+			WithToolTimeout("slow", 20*time.Millisecond),
+			WithMaxConcurrentCalls(1),
+			WithOverloadRejection(true),
+		)
+		require.NoError(t, err)
+		session := connectTestClient(t, handler)
+
+		result := <-callAll(t, session, 1)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "TIMEOUT", result.Meta["errorCode"])
+
+		result = <-callAll(t, session, 1)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "OVERLOADED", result.Meta["errorCode"])
+
+		// The slot frees up once the timed out tool actually returns
+		close(release)
+		assert.Eventually(t, func() bool {
+			return !(<-callAll(t, session, 1)).IsError
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		for _, n := range []int{0, -1} {
+			_, err := NewHandler(WithMaxConcurrentCalls(n))
+			require.ErrorIs(t, err, ErrInvalidConcurrencyLimit)
+		}
+	})
+}
+
+func TestWithInputValidation(t *testing.T) {
+	schema := CreateDynamicSchema([]FieldDef{
+		{Name: "name", Type: "string", Required: true},
+		{Name: "count", Type: "number"},
+	})
+	var received []byte
+	captureFunc := func(ctx context.Context, input []byte) ([]byte, error) {
+		received = input
+		return []byte(`{"ok": true}`), nil
+	}
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		args      map[string]any
+		wantError bool
+		wantText  string
+	}{
+		{
+			name: "conforming input",
+			args: map[string]any{"name": "widget", "count": 3},
+		},
+		{
+			name:      "wrong type names the field",
+			args:      map[string]any{"name": "widget", "count": "three"},
+			wantError: true,
+			wantText:  "count",
+		},
+		{
+			name:      "missing required field is named",
+			args:      map[string]any{"count": 3},
+			wantError: true,
+			wantText:  "name",
+		},
+		{
+			name:      "explicitly enabled",
+			opts:      []Option{WithInputValidation(true)},
+			args:      map[string]any{"count": "three"},
+			wantError: true,
+			wantText:  "invalid input",
+		},
+		{
+			name: "disabled passes input through",
+			opts: []Option{WithInputValidation(false)},
+			args: map[string]any{"count": "three"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = nil
+			opts := append([]Option{WithRawTool("capture", "Capture input", schema, captureFunc)}, tt.opts...)
+			handler, err := NewHandler(opts...)
+			require.NoError(t, err)
+			session := connectTestClient(t, handler)
+
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+				Name:      "capture",
+				Arguments: tt.args,
+			})
+			require.NoError(t, err)
+
+			if tt.wantError {
+				assert.True(t, result.IsError)
+				assert.Contains(t, resultText(t, result), tt.wantText)
+				assert.Nil(t, received, "tool should not be called with invalid input")
+				return
+			}
+			assert.False(t, result.IsError)
+			assert.NotNil(t, received)
+		})
+	}
+}
+
+// recordingLogHandler is a slog.Handler that captures every record it receives
+type recordingLogHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordingLogHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordingLogHandler) Handle(_ context.Context, record slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, record)
+	return nil
+}
+
+func (h *recordingLogHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *recordingLogHandler) WithGroup(string) slog.Handler { return h }
+
+// attrs returns the attributes of each captured record, keyed by attribute name
+func (h *recordingLogHandler) attrs() []map[string]slog.Value {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	all := make([]map[string]slog.Value, 0, len(h.records))
+	for _, record := range h.records {
+		attrs := map[string]slog.Value{"msg": slog.StringValue(record.Message)}
+		record.Attrs(func(attr slog.Attr) bool {
+			attrs[attr.Key] = attr.Value
+			return true
+		})
+		all = append(all, attrs)
+	}
+	return all
+}
+
+func TestWithLogger(t *testing.T) {
+	failFunc := func(ctx context.Context, input EchoInput) (EchoOutput, error) {
+		return EchoOutput{}, errors.New("backend unavailable")
+	}
+
+	tests := []struct {
+		name          string
+		tool          string
+		args          map[string]any
+		wantErrorType string
+	}{
+		{name: "successful call", tool: "echo", args: map[string]any{"text": "hi"}},
+		{
+			name:          "tool error",
+			tool:          "calculate",
+			args:          map[string]any{"operation": "divide", "a": 1, "b": 0},
+			wantErrorType: "tool",
+		},
+		{name: "protocol error", tool: "fail", args: map[string]any{"text": "hi"}, wantErrorType: "protocol"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := &recordingLogHandler{}
+			handler, err := NewHandler(
+				WithTool("echo", "Echo input", echoFunc),
+				WithTool("calculate", "Perform arithmetic", calculateFunc),
+				WithTool("fail", "Always fails", failFunc),
+				WithLogger(slog.New(logs)),
+			)
+			require.NoError(t, err)
+			session := connectTestClient(t, handler)
+
+			_, _ = session.CallTool(context.Background(), &mcp.CallToolParams{
+				Name:      tt.tool,
+				Arguments: tt.args,
+			})
+
+			records := logs.attrs()
+			require.Len(t, records, 2)
+
+			start, end := records[0], records[1]
+			assert.Equal(t, "tool call started", start["msg"].String())
+			assert.Equal(t, tt.tool, start["tool"].String())
+			assert.NotEmpty(t, start["call_id"].String())
+			assert.Equal(t, start["call_id"].String(), end["call_id"].String())
+			assert.Equal(t, "tool call finished", end["msg"].String())
+			assert.Equal(t, tt.tool, end["tool"].String())
+			assert.Contains(t, end, "duration")
+
+			if tt.wantErrorType == "" {
+				assert.NotContains(t, end, "error_type")
+			} else {
+				assert.Equal(t, tt.wantErrorType, end["error_type"].String())
+			}
+		})
+	}
+
+	t.Run("nil logger", func(t *testing.T) {
+		_, err := NewHandler(WithLogger(nil))
+		require.ErrorIs(t, err, ErrNilLogger)
+	})
+}
+
+func TestWithFieldDefaultFromEnv(t *testing.T) {
+	type DeployInput struct {
+		Service  string `json:"service"`
+		Region   string `json:"region,omitempty"`
+		Replicas int    `json:"replicas,omitempty"`
+	}
+	deployFunc := func(ctx context.Context, input DeployInput) (DeployInput, error) {
+		return input, nil
+	}
+	var rawReceived map[string]any
+	rawDeployFunc := func(ctx context.Context, input []byte) ([]byte, error) {
+		rawReceived = nil
+		if err := json.Unmarshal(input, &rawReceived); err != nil {
+			return nil, err
+		}
+		return input, nil
+	}
+	rawSchema := CreateDynamicSchema([]FieldDef{
+		{Name: "service", Type: "string", Required: true},
+		{Name: "region", Type: "string", Required: true},
+	})
+
+	t.Setenv("MCPIO_TEST_REGION", "eu-west-1")
+	t.Setenv("MCPIO_TEST_REPLICAS", "3")
+
+	handler, err := NewHandler(
+		WithTool("deploy", "Deploy a service", deployFunc),
+		WithRawTool("raw_deploy", "Deploy a service", rawSchema, rawDeployFunc),
+		WithFieldDefaultFromEnv("deploy", "region", "MCPIO_TEST_REGION"),
+		WithFieldDefaultFromEnv("deploy", "replicas", "MCPIO_TEST_REPLICAS"),
+		WithFieldDefaultFromEnv("raw_deploy", "region", "MCPIO_TEST_REGION"),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	callDeploy := func(t *testing.T, args map[string]any) DeployInput {
+		t.Helper()
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "deploy", Arguments: args})
+		require.NoError(t, err)
+		require.False(t, result.IsError, resultText(t, result))
+
+		var output DeployInput
+		require.NoError(t, json.Unmarshal([]byte(resultText(t, result)), &output))
+		return output
+	}
+
+	t.Run("absent fields use env values", func(t *testing.T) {
+		output := callDeploy(t, map[string]any{"service": "api"})
+		assert.Equal(t, DeployInput{Service: "api", Region: "eu-west-1", Replicas: 3}, output)
+	})
+
+	t.Run("explicit value overrides env", func(t *testing.T) {
+		output := callDeploy(t, map[string]any{"service": "api", "region": "us-east-1", "replicas": 1})
+		assert.Equal(t, DeployInput{Service: "api", Region: "us-east-1", Replicas: 1}, output)
+	})
+
+	t.Run("raw tool receives defaulted value", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "raw_deploy",
+			Arguments: map[string]any{"service": "api"},
+		})
+		require.NoError(t, err)
+		require.False(t, result.IsError, resultText(t, result))
+		assert.Equal(t, map[string]any{"service": "api", "region": "eu-west-1"}, rawReceived)
+	})
+
+	t.Run("option errors", func(t *testing.T) {
+		_, err := NewHandler(WithFieldDefaultFromEnv("", "region", "REGION"))
+		require.ErrorIs(t, err, ErrEmptyToolName)
+
+		_, err = NewHandler(WithFieldDefaultFromEnv("deploy", "", "REGION"))
+		require.ErrorIs(t, err, ErrEmptyFieldName)
+
+		_, err = NewHandler(WithFieldDefaultFromEnv("deploy", "region", ""))
+		require.ErrorIs(t, err, ErrEmptyEnvVar)
+
+		_, err = NewHandler(WithFieldDefaultFromEnv("missing", "region", "REGION"))
+		require.ErrorIs(t, err, ErrToolNotFound)
+
+		_, err = NewHandler(
+			WithTool("deploy", "Deploy a service", deployFunc),
+			WithFieldDefaultFromEnv("deploy", "zone", "REGION"),
+		)
+		require.ErrorIs(t, err, ErrFieldNotFound)
+	})
+}
+
+func TestHandlerFuncUnderSubpath(t *testing.T) {
+	handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		mount func(mux *http.ServeMux)
+		path  string
+	}{
+		{
+			name: "HandlerFunc",
+			mount: func(mux *http.ServeMux) {
+				mux.HandleFunc("/mcp", handler.HandlerFunc())
+			},
+			path: "/mcp",
+		},
+		{
+			name: "StripPrefix",
+			mount: func(mux *http.ServeMux) {
+				mux.Handle("/api/v1/", http.StripPrefix("/api/v1", handler.HandlerFunc()))
+			},
+			path: "/api/v1/mcp",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			tt.mount(mux)
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			ctx := context.Background()
+			client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+			session, err := client.Connect(ctx, &mcp.StreamableClientTransport{Endpoint: server.URL + tt.path}, nil)
+			require.NoError(t, err)
+			defer func() {
+				assert.NoError(t, session.Close())
+			}()
+
+			result, err := session.CallTool(ctx, &mcp.CallToolParams{
+				Name:      "echo",
+				Arguments: map[string]any{"text": "mounted"},
+			})
+			require.NoError(t, err)
+			assert.False(t, result.IsError)
+			assert.Contains(t, resultText(t, result), "mounted")
+		})
+	}
+}
+
+func TestWithTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() {
+		assert.NoError(t, provider.Shutdown(context.Background()))
+	})
+
+	handler, err := NewHandler(
+		WithTool("echo", "Echo input", echoFunc),
+		WithTool("calculate", "Perform arithmetic", calculateFunc),
+		WithTracer(provider.Tracer("mcp-io-test")),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	_, err = session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "echo",
+		Arguments: map[string]any{"text": "hi"},
+	})
+	require.NoError(t, err)
+	_, err = session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "calculate",
+		Arguments: map[string]any{"operation": "divide", "a": 1, "b": 0},
+	})
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	echoSpan, calcSpan := spans[0], spans[1]
+	assert.Equal(t, "echo", echoSpan.Name())
+	assert.Equal(t, codes.Unset, echoSpan.Status().Code)
+	assert.Contains(t, echoSpan.Attributes(), attribute.Int("mcp.tool.input_size", len(`{"text":"hi"}`)))
+
+	assert.Equal(t, "calculate", calcSpan.Name())
+	assert.Equal(t, codes.Error, calcSpan.Status().Code)
+	assert.Contains(t, calcSpan.Attributes(), attribute.String("mcp.tool.error_kind", "tool"))
+	require.Len(t, calcSpan.Events(), 1)
+	assert.Equal(t, "exception", calcSpan.Events()[0].Name)
+
+	t.Run("nil tracer", func(t *testing.T) {
+		_, err := NewHandler(WithTracer(nil))
+		require.ErrorIs(t, err, ErrNilTracer)
+	})
+}
+
+func TestWithResultTiming(t *testing.T) {
+	slowFunc := func(ctx context.Context, input EchoInput) (EchoOutput, error) {
+		time.Sleep(50 * time.Millisecond)
+		return EchoOutput{Message: input.Text}, nil
+	}
+	slowRawFunc := func(ctx context.Context, input []byte) ([]byte, error) {
+		time.Sleep(50 * time.Millisecond)
+		return nil, ValidationError("bad input")
+	}
+	schema := CreateObjectSchema("Raw input", map[string]string{"data": "Input data"}, nil)
+
+	handler, err := NewHandler(
+		WithTool("slow", "Sleeps before echoing", slowFunc),
+		WithRawTool("slow_error", "Sleeps before failing", schema, slowRawFunc),
+		WithResultTiming(),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	tests := []struct {
+		name      string
+		tool      string
+		args      map[string]any
+		wantError bool
+	}{
+		{name: "successful result", tool: "slow", args: map[string]any{"text": "hi"}},
+		{name: "error result", tool: "slow_error", args: map[string]any{"data": "x"}, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: tt.tool, Arguments: tt.args})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantError, result.IsError)
+
+			require.Contains(t, result.Meta, "durationMs")
+			durationMs, ok := result.Meta["durationMs"].(float64)
+			require.True(t, ok, "durationMs should be a number, got %T", result.Meta["durationMs"])
+			assert.GreaterOrEqual(t, durationMs, 50.0)
+			assert.Less(t, durationMs, 1000.0)
+		})
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc))
+		require.NoError(t, err)
+		session := connectTestClient(t, handler)
+
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "echo",
+			Arguments: map[string]any{"text": "hi"},
+		})
+		require.NoError(t, err)
+		assert.NotContains(t, result.Meta, "durationMs")
+	})
+}
+
+func TestToolErrorCodeInResult(t *testing.T) {
+	rawErrorFunc := func(ctx context.Context, input []byte) ([]byte, error) {
+		return nil, NewToolErrorWithCode("quota exhausted", "QUOTA_EXCEEDED")
+	}
+	rawNoCodeFunc := func(ctx context.Context, input []byte) ([]byte, error) {
+		return nil, NewToolError("something went wrong")
+	}
+	schema := CreateObjectSchema("Raw input", map[string]string{"data": "Input data"}, []string{"data"})
+
+	handler, err := NewHandler(
+		WithTool("calculate", "Perform arithmetic", calculateFunc),
+		WithRawTool("raw_error", "Always fails", schema, rawErrorFunc),
+		WithRawTool("raw_no_code", "Always fails", schema, rawNoCodeFunc),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	tests := []struct {
+		name     string
+		tool     string
+		args     map[string]any
+		wantCode string
+		wantText string
+	}{
+		{
+			name:     "typed tool error",
+			tool:     "calculate",
+			args:     map[string]any{"operation": "modulo", "a": 1, "b": 2},
+			wantCode: "VALIDATION_ERROR",
+			wantText: "unsupported operation: modulo",
+		},
+		{
+			name:     "raw tool error",
+			tool:     "raw_error",
+			args:     map[string]any{"data": "x"},
+			wantCode: "QUOTA_EXCEEDED",
+			wantText: "quota exhausted",
+		},
+		{
+			name:     "raw input validation error",
+			tool:     "raw_error",
+			args:     map[string]any{},
+			wantCode: "VALIDATION_ERROR",
+			wantText: "invalid input",
+		},
+		{
+			name:     "tool error without a code",
+			tool:     "raw_no_code",
+			args:     map[string]any{"data": "x"},
+			wantText: "something went wrong",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: tt.tool, Arguments: tt.args})
+			require.NoError(t, err)
+			assert.True(t, result.IsError)
+			assert.Contains(t, resultText(t, result), tt.wantText)
+
+			if tt.wantCode == "" {
+				assert.NotContains(t, result.Meta, "errorCode")
+				return
+			}
+			assert.Equal(t, tt.wantCode, result.Meta["errorCode"])
+		})
+	}
+}
+
+func TestTypedToolErrorHidesCause(t *testing.T) {
+	lookupFunc := func(ctx context.Context, input EchoInput) (EchoOutput, error) {
+		cause := errors.New("pq: password authentication failed for user admin")
+		return EchoOutput{}, WrapToolError(cause, "lookup failed")
+	}
+
+	handler, err := NewHandler(WithTool("lookup", "Look up a record", lookupFunc))
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "lookup",
+		Arguments: map[string]any{"text": "x"},
+	})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Equal(t, "lookup failed", resultText(t, result))
+	assert.NotContains(t, resultText(t, result), "pq:")
+	assert.NotContains(t, result.Meta, "errorCode")
+}
+
+// rpcErrorCode returns the JSON-RPC error code of a client call error. The SDK's
+// wire error type is internal, so the code is read from its JSON form.
+func rpcErrorCode(t *testing.T, err error) int {
+	t.Helper()
+	for ; err != nil; err = errors.Unwrap(err) {
+		data, marshalErr := json.Marshal(err)
+		if marshalErr != nil {
+			continue
+		}
+		var wire struct {
+			Code *int `json:"code"`
+		}
+		if json.Unmarshal(data, &wire) == nil && wire.Code != nil {
+			return *wire.Code
+		}
+	}
+	t.Fatalf("no JSON-RPC error code found")
+	return 0
+}
+
+func TestProtocolErrorRPCCode(t *testing.T) {
+	errUpstream := errors.New("upstream unavailable")
+	typedFunc := func(ctx context.Context, input EchoInput) (EchoOutput, error) {
+		return EchoOutput{}, &ProtocolError{Message: "echo backend failed", RPCCode: -32001, Err: errUpstream}
+	}
+	rawCodeFunc := func(ctx context.Context, input []byte) ([]byte, error) {
+		return nil, NewProtocolError("rate limited", -32029)
+	}
+	rawDefaultFunc := func(ctx context.Context, input []byte) ([]byte, error) {
+		return nil, &ProtocolError{Message: "storage offline"}
+	}
+	schema := CreateObjectSchema("Raw input", map[string]string{"data": "Input data"}, nil)
+
+	handler, err := NewHandler(
+		WithTool("typed", "Fails with a protocol error", typedFunc),
+		WithRawTool("raw_code", "Fails with a protocol error", schema, rawCodeFunc),
+		WithRawTool("raw_default", "Fails with a protocol error", schema, rawDefaultFunc),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	tests := []struct {
+		name     string
+		tool     string
+		args     map[string]any
+		wantCode int
+		wantMsg  string
+	}{
+		{"typed tool custom code", "typed", map[string]any{"text": "hi"}, -32001, "echo backend failed: upstream unavailable"},
+		{"raw tool custom code", "raw_code", map[string]any{}, -32029, "rate limited"},
+		{"default code", "raw_default", map[string]any{}, RPCCodeInternalError, "storage offline"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: tt.tool, Arguments: tt.args})
+			require.Error(t, err)
+			assert.Equal(t, tt.wantCode, rpcErrorCode(t, err))
+			assert.Contains(t, err.Error(), tt.wantMsg)
+		})
+	}
+}
+
+func TestHandlerClose(t *testing.T) {
+	t.Run("cleanups run exactly once in reverse order", func(t *testing.T) {
+		var order []string
+		errFlush := errors.New("flush failed")
+		handler, err := NewHandler(
+			WithTool("echo", "Echo input", echoFunc),
+			WithCleanup(func() error {
+				order = append(order, "first")
+				return nil
+			}),
+			WithCleanup(func() error {
+				order = append(order, "second")
+				return errFlush
+			}),
+		)
+		require.NoError(t, err)
+
+		require.ErrorIs(t, handler.Close(), errFlush)
+		require.ErrorIs(t, handler.Close(), errFlush)
+		assert.Equal(t, []string{"second", "first"}, order)
+	})
+
+	t.Run("closes connected sessions", func(t *testing.T) {
+		handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc))
+		require.NoError(t, err)
+
+		ctx := context.Background()
+		clientTransport, serverTransport := mcp.NewInMemoryTransports()
+		_, err = handler.server.Connect(ctx, serverTransport, nil)
+		require.NoError(t, err)
+		client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+		session, err := client.Connect(ctx, clientTransport, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, handler.Close())
+
+		done := make(chan struct{})
+		go func() {
+			_ = session.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("client session was not closed")
+		}
+	})
+
+	t.Run("rejects HTTP requests after close", func(t *testing.T) {
+		handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc))
+		require.NoError(t, err)
+		require.NoError(t, handler.Close())
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/mcp", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	})
+
+	t.Run("nil cleanup", func(t *testing.T) {
+		_, err := NewHandler(WithCleanup(nil))
+		require.ErrorIs(t, err, ErrNilFunction)
+	})
+}
+
+func TestWithTools(t *testing.T) {
+	specs := []ToolSpec{
+		{Name: "echo", Description: "Echo input", Handler: echoFunc},
+		{Name: "calculate", Description: "Perform arithmetic", Handler: calculateFunc},
+		{
+			Name:        "process",
+			Description: "Process raw data",
+			Handler:     rawFunc,
+			InputSchema: CreateObjectSchema("Raw input", map[string]string{"data": "Input data"}, nil),
+		},
+	}
+
+	handler, err := NewHandler(WithTools(specs...))
+	require.NoError(t, err)
+
+	names := make([]string, 0)
+	for _, tool := range handler.Tools() {
+		names = append(names, tool.Name)
+	}
+	assert.Equal(t, []string{"echo", "calculate", "process"}, names)
+	assert.Contains(t, handler.Tools()[2].InputSchema.Properties, "data")
+
+	session := connectTestClient(t, handler)
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "calculate",
+		Arguments: map[string]any{"operation": "add", "a": 1, "b": 2},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"result": 3}`, resultText(t, result))
+
+	t.Run("errors identify the spec", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			specs   []ToolSpec
+			wantErr error
+			wantMsg string
+		}{
+			{
+				name:    "invalid handler",
+				specs:   []ToolSpec{specs[0], {Name: "bad", Handler: "not a function"}},
+				wantErr: ErrInvalidToolFunc,
+				wantMsg: `tool spec 1 ("bad")`,
+			},
+			{
+				name:    "raw handler without schema",
+				specs:   []ToolSpec{{Name: "raw", Handler: RawToolFunc(rawFunc)}},
+				wantErr: ErrNilSchema,
+				wantMsg: `tool spec 0 ("raw")`,
+			},
+			{
+				name:    "empty name",
+				specs:   []ToolSpec{specs[0], specs[1], {Handler: echoFunc}},
+				wantErr: ErrEmptyToolName,
+				wantMsg: `tool spec 2 ("")`,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := NewHandler(WithTools(tt.specs...))
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.Contains(t, err.Error(), tt.wantMsg)
+			})
+		}
+	})
+}
+
+func TestClientCapabilitiesFromContext(t *testing.T) {
+	type SamplingOutput struct {
+		Known    bool `json:"known"`
+		Sampling bool `json:"sampling"`
+	}
+	checkSampling := func(ctx context.Context, input struct{}) (SamplingOutput, error) {
+		caps := ClientCapabilitiesFromContext(ctx)
+		return SamplingOutput{Known: caps != nil, Sampling: caps != nil && caps.Sampling != nil}, nil
+	}
+
+	handler, err := NewHandler(WithTool("check_sampling", "Report sampling support", checkSampling))
+	require.NoError(t, err)
+
+	connect := func(t *testing.T, opts *mcp.ClientOptions) *mcp.ClientSession {
+		t.Helper()
+		clientTransport, serverTransport := mcp.NewInMemoryTransports()
+		_, err := handler.server.Connect(context.Background(), serverTransport, nil)
+		require.NoError(t, err)
+
+		client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, opts)
+		session, err := client.Connect(context.Background(), clientTransport, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			assert.NoError(t, session.Close())
+		})
+		return session
+	}
+
+	tests := []struct {
+		name string
+		opts *mcp.ClientOptions
+		want string
+	}{
+		{
+			name: "client supports sampling",
+			opts: &mcp.ClientOptions{
+				CreateMessageHandler: func(context.Context, *mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+					return &mcp.CreateMessageResult{}, nil
+				},
+			},
+			want: `{"known": true, "sampling": true}`,
+		},
+		{
+			name: "client without sampling",
+			opts: nil,
+			want: `{"known": true, "sampling": false}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := connect(t, tt.opts)
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "check_sampling"})
+			require.NoError(t, err)
+			assert.False(t, result.IsError)
+			assert.JSONEq(t, tt.want, resultText(t, result))
+		})
+	}
+
+	t.Run("outside a tool call", func(t *testing.T) {
+		assert.Nil(t, ClientCapabilitiesFromContext(context.Background()))
+	})
+}
+
+func TestRequestFromContext(t *testing.T) {
+	type NameOutput struct {
+		Tool          string `json:"tool"`
+		ProgressToken any    `json:"progress_token"`
+	}
+	whoAmI := func(ctx context.Context, input struct{}) (NameOutput, error) {
+		req, ok := RequestFromContext(ctx)
+		if !ok {
+			return NameOutput{}, NewToolError("no request in context")
+		}
+		return NameOutput{Tool: req.Params.Name, ProgressToken: req.Params.GetProgressToken()}, nil
+	}
+	rawWhoAmI := func(ctx context.Context, input []byte) ([]byte, error) {
+		req, ok := RequestFromContext(ctx)
+		if !ok {
+			return nil, NewToolError("no request in context")
+		}
+		return json.Marshal(map[string]string{"tool": req.Params.Name})
+	}
+
+	handler, err := NewHandler(
+		WithTool("who_am_i", "Report the called tool", whoAmI),
+		WithRawTool("raw_who_am_i", "Report the called tool", CreateObjectSchema("Input", nil, nil), rawWhoAmI),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	t.Run("typed tool", func(t *testing.T) {
+		params := &mcp.CallToolParams{Name: "who_am_i", Meta: mcp.Meta{"progressToken": "progress-1"}}
+		result, err := session.CallTool(context.Background(), params)
+		require.NoError(t, err)
+		assert.False(t, result.IsError, resultText(t, result))
+		assert.JSONEq(t, `{"tool": "who_am_i", "progress_token": "progress-1"}`, resultText(t, result))
+	})
+
+	t.Run("raw tool", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "raw_who_am_i"})
+		require.NoError(t, err)
+		assert.False(t, result.IsError, resultText(t, result))
+		assert.JSONEq(t, `{"tool": "raw_who_am_i"}`, resultText(t, result))
+	})
+
+	t.Run("outside a tool call", func(t *testing.T) {
+		req, ok := RequestFromContext(context.Background())
+		assert.False(t, ok)
+		assert.Nil(t, req)
+	})
+}
+
+func TestWithPropertyOrder(t *testing.T) {
+	shared := CreateObjectSchema("Raw input", map[string]string{"first": "First", "second": "Second"}, nil)
+	handler, err := NewHandler(
+		WithTool("calculate", "Perform arithmetic", calculateFunc),
+		WithPropertyOrder("calculate", []string{"a", "operation", "b"}),
+		WithTool("echo", "Echo input", echoFunc),
+		WithRawTool("ordered", "Raw tool", shared, rawFunc),
+		WithRawTool("unordered", "Raw tool", shared, rawFunc),
+		WithPropertyOrder("ordered", []string{"second", "first"}),
+	)
+	require.NoError(t, err)
+
+	session := connectTestClient(t, handler)
+	list, err := session.ListTools(context.Background(), nil)
+	require.NoError(t, err)
+
+	byName := make(map[string]*mcp.Tool)
+	for _, tool := range list.Tools {
+		byName[tool.Name] = tool
+	}
+	// inputMeta returns the _meta of a listed tool's input schema
+	inputMeta := func(t *testing.T, name string) map[string]any {
+		t.Helper()
+		require.Contains(t, byName, name)
+		data, err := json.Marshal(byName[name].InputSchema)
+		require.NoError(t, err)
+		var schema struct {
+			Meta map[string]any `json:"_meta"`
+		}
+		require.NoError(t, json.Unmarshal(data, &schema))
+		return schema.Meta
+	}
+	assert.Equal(t, []any{"a", "operation", "b"}, inputMeta(t, "calculate")["propertyOrdering"])
+	assert.NotContains(t, byName["calculate"].Meta, "propertyOrdering")
+	assert.NotContains(t, inputMeta(t, "echo"), "propertyOrdering")
+	assert.Equal(t, []any{"second", "first"}, inputMeta(t, "ordered")["propertyOrdering"])
+
+	// The schema shared with another tool isn't changed
+	assert.NotContains(t, inputMeta(t, "unordered"), "propertyOrdering")
+	assert.Nil(t, shared.Extra)
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr error
+	}{
+		{"empty tool name", []Option{WithPropertyOrder("", []string{"a"})}, ErrEmptyToolName},
+		{"empty order", []Option{WithTool("calculate", "Calc", calculateFunc), WithPropertyOrder("calculate", nil)}, ErrInvalidPropertyOrder},
+		{"duplicate field", []Option{WithTool("calculate", "Calc", calculateFunc), WithPropertyOrder("calculate", []string{"a", "a"})}, ErrInvalidPropertyOrder},
+		{"unknown field", []Option{WithTool("calculate", "Calc", calculateFunc), WithPropertyOrder("calculate", []string{"c"})}, ErrFieldNotFound},
+		{"unknown tool", []Option{WithPropertyOrder("missing", []string{"a"})}, ErrToolNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHandler(tt.opts...)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestWithFieldTitle(t *testing.T) {
+	rawSchema := CreateDynamicSchema([]FieldDef{
+		{Name: "url", Type: "string", Description: "Page to fetch", Required: true},
+	})
+	handler, err := NewHandler(
+		WithTool("calculate", "Perform arithmetic", calculateFunc),
+		WithRawTool("fetch", "Fetch a page", rawSchema, rawFunc),
+		WithFieldTitle("calculate", "a", "First operand"),
+		WithFieldTitle("calculate", "b", "Second operand"),
+		WithFieldTitle("fetch", "url", "Page URL"),
+	)
+	require.NoError(t, err)
+
+	session := connectTestClient(t, handler)
+	list, err := session.ListTools(context.Background(), nil)
+	require.NoError(t, err)
+
+	titles := make(map[string]string)
+	for _, tool := range list.Tools {
+		for name, property := range tool.InputSchema.Properties {
+			titles[tool.Name+"."+name] = property.Title
+		}
+	}
+	assert.Equal(t, "First operand", titles["calculate.a"])
+	assert.Equal(t, "Second operand", titles["calculate.b"])
+	assert.Empty(t, titles["calculate.operation"])
+	assert.Equal(t, "Page URL", titles["fetch.url"])
+	assert.Empty(t, rawSchema.Properties["url"].Title, "the caller's schema is not modified")
+
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "fetch",
+		Arguments: map[string]any{"url": "https://example.com"},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.IsError, resultText(t, result))
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr error
+	}{
+		{"empty tool name", []Option{WithFieldTitle("", "a", "A")}, ErrEmptyToolName},
+		{"empty field name", []Option{WithFieldTitle("calculate", "", "A")}, ErrEmptyFieldName},
+		{"empty title", []Option{WithFieldTitle("calculate", "a", "")}, ErrEmptyTitle},
+		{"unknown field", []Option{WithTool("calculate", "Calc", calculateFunc), WithFieldTitle("calculate", "c", "C")}, ErrFieldNotFound},
+		{"unknown tool", []Option{WithFieldTitle("missing", "a", "A")}, ErrToolNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHandler(tt.opts...)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestWithToolTags(t *testing.T) {
+	handler, err := NewHandler(
+		WithTool("calculate", "Perform arithmetic", calculateFunc),
+		WithTool("echo", "Echo input", echoFunc),
+		WithRawTool("process", "Process raw data", CreateObjectSchema("Raw input", nil, nil), rawFunc),
+		WithToolTags("calculate", "math"),
+		WithToolTags("process", "math", "data"),
+		WithToolTags("process", "data"),
+		WithToolTags("echo", "text"),
+	)
+	require.NoError(t, err)
+
+	toolNames := func(tools []ToolInfo) []string {
+		names := make([]string, 0, len(tools))
+		for _, tool := range tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"calculate", "process"}, toolNames(handler.ToolsByTag("math")))
+	assert.Equal(t, []string{"echo"}, toolNames(handler.ToolsByTag("text")))
+	assert.Empty(t, handler.ToolsByTag("unknown"))
+	assert.Equal(t, []string{"math", "data"}, handler.Tools()[2].Tags)
+
+	t.Run("tags stay local", func(t *testing.T) {
+		session := connectTestClient(t, handler)
+		list, err := session.ListTools(context.Background(), nil)
+		require.NoError(t, err)
+		for _, tool := range list.Tools {
+			assert.Empty(t, tool.Meta, tool.Name)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			opts    []Option
+			wantErr error
+		}{
+			{"empty tool name", []Option{WithToolTags("", "math")}, ErrEmptyToolName},
+			{"empty tag", []Option{WithTool("echo", "Echo", echoFunc), WithToolTags("echo", "")}, ErrEmptyTag},
+			{"unknown tool", []Option{WithToolTags("missing", "math")}, ErrToolNotFound},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := NewHandler(tt.opts...)
+				require.ErrorIs(t, err, tt.wantErr)
+			})
+		}
+	})
+}
+
+func TestHandlerHealth(t *testing.T) {
+	newHandler := func(t *testing.T) *Handler {
+		t.Helper()
+		handler, err := NewHandler(
+			WithTool("echo", "Echo input", echoFunc),
+			WithTool("calculate", "Perform arithmetic", calculateFunc),
+		)
+		require.NoError(t, err)
+		return handler
+	}
+
+	t.Run("healthy", func(t *testing.T) {
+		handler := newHandler(t)
+		require.NoError(t, handler.Health())
+
+		// Tools added at runtime don't affect health
+		require.NoError(t, handler.RegisterTool("extra", "Extra tool", echoFunc))
+		require.NoError(t, handler.UnregisterTool("extra"))
+		assert.NoError(t, handler.Health())
+	})
+
+	t.Run("no tools", func(t *testing.T) {
+		handler, err := NewHandler()
+		require.NoError(t, err)
+		assert.NoError(t, handler.Health())
+	})
+
+	t.Run("configured tool unregistered", func(t *testing.T) {
+		handler := newHandler(t)
+		require.NoError(t, handler.UnregisterTool("calculate"))
+		err := handler.Health()
+		require.ErrorIs(t, err, ErrToolNotFound)
+		assert.Contains(t, err.Error(), `"calculate"`)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		handler := newHandler(t)
+		require.NoError(t, handler.Close())
+		assert.ErrorIs(t, handler.Health(), ErrHandlerClosed)
+	})
+
+	t.Run("not initialized", func(t *testing.T) {
+		assert.ErrorIs(t, (&Handler{}).Health(), ErrNotInitialized)
+		var handler *Handler
+		assert.ErrorIs(t, handler.Health(), ErrNotInitialized)
+	})
+}
@@ -0,0 +1,78 @@
+package mcpio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// InvokeResult is the outcome of a tool call made with Handler.Invoke
+type InvokeResult struct {
+	Content           []mcp.Content   // Content blocks, as a client would receive them
+	StructuredContent json.RawMessage // Structured output, if the tool returned any
+	IsError           bool            // The tool reported an error
+	ErrorCode         string          // The ToolError code, if the tool returned one
+}
+
+// Invoke calls a tool in-process, without a network transport, and returns the
+// result as a client would see it. The call goes through the same validation,
+// middleware, and per-call behavior as calls from connected clients. Tool errors
+// are reported in the result; unknown tools return ErrToolNotFound, and protocol
+// errors are returned as errors.
+func (h *Handler) Invoke(ctx context.Context, name string, rawInput json.RawMessage) (*InvokeResult, error) {
+	if h.closed.Load() {
+		return nil, ErrHandlerClosed
+	}
+	if _, err := h.InputSchema(name); err != nil {
+		return nil, err
+	}
+
+	session, err := h.inProcessSession()
+	if err != nil {
+		return nil, err
+	}
+
+	params := &mcp.CallToolParams{Name: name}
+	if len(rawInput) > 0 {
+		params.Arguments = rawInput
+	}
+	res, err := session.CallTool(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &InvokeResult{Content: res.Content, IsError: res.IsError}
+	if res.StructuredContent != nil {
+		if result.StructuredContent, err = json.Marshal(res.StructuredContent); err != nil {
+			return nil, fmt.Errorf("marshaling structured content: %w", err)
+		}
+	}
+	result.ErrorCode, _ = res.Meta["errorCode"].(string)
+	return result, nil
+}
+
+// inProcessSession returns the client session Invoke uses, connecting it over an
+// in-memory transport on first use
+func (h *Handler) inProcessSession() (*mcp.ClientSession, error) {
+	h.invokeMu.Lock()
+	defer h.invokeMu.Unlock()
+	if h.invokeSession != nil {
+		return h.invokeSession, nil
+	}
+
+	// The session outlives any single call, so it isn't tied to a caller's context
+	ctx := context.Background()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	if _, err := h.server.Connect(ctx, serverTransport, nil); err != nil {
+		return nil, fmt.Errorf("connecting in-process session: %w", err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "mcpio-invoke", Version: "1.0.0"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("connecting in-process session: %w", err)
+	}
+	h.invokeSession = session
+	return session, nil
+}
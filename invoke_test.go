@@ -0,0 +1,123 @@
+package mcpio
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvoke(t *testing.T) {
+	handler, err := NewHandler(
+		WithTool("calculate", "Perform arithmetic", calculateFunc),
+		WithRawTool("process", "Process raw data", CreateObjectSchema("Raw input", nil, nil), rawFunc),
+	)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	tests := []struct {
+		name           string
+		tool           string
+		input          string
+		wantError      bool
+		wantCode       string
+		wantStructured string
+		wantText       string
+	}{
+		{
+			name:           "typed tool success",
+			tool:           "calculate",
+			input:          `{"operation": "multiply", "a": 6, "b": 7}`,
+			wantStructured: `{"result": 42}`,
+		},
+		{
+			name:           "raw tool success",
+			tool:           "process",
+			input:          `{}`,
+			wantStructured: `{"result": "processed"}`,
+		},
+		{
+			name:           "raw tool without input",
+			tool:           "process",
+			wantStructured: `{"result": "processed"}`,
+		},
+		{
+			name:      "tool error",
+			tool:      "calculate",
+			input:     `{"operation": "modulo", "a": 1, "b": 2}`,
+			wantError: true,
+			wantCode:  "VALIDATION_ERROR",
+			wantText:  "unsupported operation: modulo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := handler.Invoke(ctx, tt.tool, json.RawMessage(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantError, result.IsError)
+			assert.Equal(t, tt.wantCode, result.ErrorCode)
+			if tt.wantStructured != "" {
+				assert.JSONEq(t, tt.wantStructured, string(result.StructuredContent))
+			} else {
+				assert.Nil(t, result.StructuredContent)
+			}
+			require.NotEmpty(t, result.Content)
+			text, ok := result.Content[0].(*mcp.TextContent)
+			require.True(t, ok)
+			if tt.wantText != "" {
+				assert.Contains(t, text.Text, tt.wantText)
+			} else {
+				assert.JSONEq(t, tt.wantStructured, text.Text)
+			}
+		})
+	}
+
+	t.Run("input the SDK rejects", func(t *testing.T) {
+		result, err := handler.Invoke(ctx, "calculate", json.RawMessage(`{"operation": "add", "a": 1, "b": 2, "c": 3}`))
+		require.Error(t, err)
+		assert.Nil(t, result)
+	})
+
+	t.Run("unknown tool", func(t *testing.T) {
+		result, err := handler.Invoke(ctx, "missing", nil)
+		require.ErrorIs(t, err, ErrToolNotFound)
+		assert.Nil(t, result)
+	})
+
+	t.Run("concurrent calls share a session", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() {
+				result, err := handler.Invoke(ctx, "calculate", json.RawMessage(`{"operation": "add", "a": 1, "b": 1}`))
+				if assert.NoError(t, err) {
+					assert.JSONEq(t, `{"result": 2}`, string(result.StructuredContent))
+				}
+			})
+		}
+		wg.Wait()
+	})
+
+	t.Run("closed handler", func(t *testing.T) {
+		require.NoError(t, handler.Close())
+		result, err := handler.Invoke(ctx, "calculate", nil)
+		require.ErrorIs(t, err, ErrHandlerClosed)
+		assert.Nil(t, result)
+	})
+}
+
+func TestInvokeNilInterfaceOutput(t *testing.T) {
+	nothing := func(ctx context.Context, input EchoInput) (any, error) {
+		return nil, nil
+	}
+	handler, err := NewHandler(WithTool("nothing", "Return nothing", nothing))
+	require.NoError(t, err)
+
+	result, err := handler.Invoke(context.Background(), "nothing", json.RawMessage(`{"text": "hi"}`))
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+}
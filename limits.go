@@ -0,0 +1,38 @@
+package mcpio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// defaultMaxRequestBytes is the HTTP request body limit unless WithMaxRequestBytes
+// sets another
+const defaultMaxRequestBytes = 4 << 20
+
+// limitBody reads the request body up to the configured limit, so oversized bodies
+// are rejected with 413 before the MCP server parses them. It returns the request
+// with the body buffered, or false once an error response has been written.
+func (h *Handler) limitBody(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	if h.maxRequestBytes <= 0 || r.Body == nil || r.Body == http.NoBody {
+		return r, true
+	}
+	if r.ContentLength > h.maxRequestBytes {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxRequestBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+		}
+		return nil, false
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return r, true
+}
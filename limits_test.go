@@ -0,0 +1,74 @@
+package mcpio
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMaxRequestBytes(t *testing.T) {
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`
+	// padded is a valid initialize request of at least n bytes
+	padded := func(n int) string {
+		return initialize + strings.Repeat(" ", max(0, n-len(initialize)))
+	}
+
+	post := func(t *testing.T, handler *Handler, body io.Reader, contentLength int64) int {
+		t.Helper()
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+
+		req, err := http.NewRequest(http.MethodPost, server.URL, body)
+		require.NoError(t, err)
+		req.ContentLength = contentLength
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp.StatusCode
+	}
+
+	limited, err := NewHandler(WithTool("echo", "Echo input", echoFunc), WithMaxRequestBytes(1024))
+	require.NoError(t, err)
+
+	t.Run("under the limit", func(t *testing.T) {
+		body := padded(1024)
+		assert.Equal(t, http.StatusOK, post(t, limited, strings.NewReader(body), int64(len(body))))
+	})
+
+	t.Run("declared length over the limit", func(t *testing.T) {
+		body := padded(1025)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, post(t, limited, strings.NewReader(body), int64(len(body))))
+	})
+
+	t.Run("chunked body over the limit", func(t *testing.T) {
+		// A reader of unknown length is sent chunked, so only reading finds the size
+		body := io.MultiReader(strings.NewReader(padded(2048)))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, post(t, limited, body, -1))
+	})
+
+	t.Run("default limit", func(t *testing.T) {
+		handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc))
+		require.NoError(t, err)
+		body := padded(defaultMaxRequestBytes + 1)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, post(t, handler, strings.NewReader(body), int64(len(body))))
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc), WithMaxRequestBytes(0))
+		require.NoError(t, err)
+		body := padded(defaultMaxRequestBytes + 1)
+		assert.Equal(t, http.StatusOK, post(t, handler, strings.NewReader(body), int64(len(body))))
+	})
+
+	t.Run("negative limit", func(t *testing.T) {
+		_, err := NewHandler(WithMaxRequestBytes(-1))
+		require.ErrorIs(t, err, ErrInvalidByteLimit)
+	})
+}
@@ -0,0 +1,15 @@
+package mcpio
+
+import "time"
+
+// Metrics receives measurements for tool calls. Implementations back it with a
+// metrics system such as Prometheus and must be safe for concurrent use.
+type Metrics interface {
+	// IncCall counts a call to the named tool
+	IncCall(tool string)
+	// IncError counts a failed call. kind is "tool" for a ToolError returned to the
+	// model, or "protocol" for any other error returned to the client.
+	IncError(tool, kind string)
+	// ObserveLatency records how long a call to the named tool took
+	ObserveLatency(tool string, d time.Duration)
+}
@@ -0,0 +1,77 @@
+package mcpio
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMetrics records every measurement it receives
+type fakeMetrics struct {
+	mu        sync.Mutex
+	calls     map[string]int
+	errors    map[string]int // Keyed by "tool/kind"
+	latencies map[string][]time.Duration
+}
+
+func newFakeMetrics() *fakeMetrics {
+	return &fakeMetrics{
+		calls:     make(map[string]int),
+		errors:    make(map[string]int),
+		latencies: make(map[string][]time.Duration),
+	}
+}
+
+func (m *fakeMetrics) IncCall(tool string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls[tool]++
+}
+
+func (m *fakeMetrics) IncError(tool, kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[tool+"/"+kind]++
+}
+
+func (m *fakeMetrics) ObserveLatency(tool string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latencies[tool] = append(m.latencies[tool], d)
+}
+
+func TestWithMetrics(t *testing.T) {
+	metrics := newFakeMetrics()
+	handler, err := NewHandler(
+		WithTool("calculate", "Perform arithmetic", calculateFunc),
+		WithMetrics(metrics),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	calls := []map[string]any{
+		{"operation": "add", "a": 1, "b": 2},
+		{"operation": "multiply", "a": 3, "b": 4},
+		{"operation": "divide", "a": 1, "b": 0}, // Tool error
+	}
+	for _, args := range calls {
+		_, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "calculate", Arguments: args})
+		require.NoError(t, err)
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	assert.Equal(t, map[string]int{"calculate": 3}, metrics.calls)
+	assert.Equal(t, map[string]int{"calculate/tool": 1}, metrics.errors)
+	assert.Len(t, metrics.latencies["calculate"], 3)
+
+	t.Run("nil metrics", func(t *testing.T) {
+		_, err := NewHandler(WithMetrics(nil))
+		require.ErrorIs(t, err, ErrNilMetrics)
+	})
+}
@@ -0,0 +1,301 @@
+package mcpio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// fieldEnvDefault fills a tool input field from an environment variable when the
+// client omits it
+type fieldEnvDefault struct {
+	field  string
+	envVar string
+	// decode is set for non-string fields, whose env values are parsed as JSON
+	decode bool
+}
+
+// envDefaultsMiddleware injects environment-sourced defaults into tools/call
+// arguments before the SDK unmarshals and validates them. The environment is read
+// on every call so changes take effect without rebuilding the handler.
+func envDefaultsMiddleware(defaults map[string][]fieldEnvDefault) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if call, ok := req.(*mcp.CallToolRequest); ok && call.Params != nil {
+				if fields, ok := defaults[call.Params.Name]; ok {
+					call.Params.Arguments = applyEnvDefaults(call.Params.Arguments, fields)
+				}
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
+
+// applyEnvDefaults returns arguments with each absent field set from its environment
+// variable. Arguments that are not a JSON object, and unset variables, are left alone.
+func applyEnvDefaults(arguments json.RawMessage, fields []fieldEnvDefault) json.RawMessage {
+	args := make(map[string]json.RawMessage)
+	if len(arguments) > 0 && string(arguments) != "null" {
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return arguments
+		}
+	}
+
+	changed := false
+	for _, def := range fields {
+		if _, present := args[def.field]; present {
+			continue
+		}
+		value, ok := os.LookupEnv(def.envVar)
+		if !ok {
+			continue
+		}
+		if def.decode && json.Valid([]byte(value)) {
+			args[def.field] = json.RawMessage(value)
+		} else {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				continue
+			}
+			args[def.field] = encoded
+		}
+		changed = true
+	}
+	if !changed {
+		return arguments
+	}
+
+	updated, err := json.Marshal(args)
+	if err != nil {
+		return arguments
+	}
+	return updated
+}
+
+// fieldMaxBytesMiddleware rejects tools/call requests whose string arguments exceed
+// their byte limit, before the SDK unmarshals them or the tool runs
+func fieldMaxBytesMiddleware(limits map[string]map[string]int) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if call, ok := req.(*mcp.CallToolRequest); ok && call.Params != nil {
+				if fields, ok := limits[call.Params.Name]; ok {
+					if toolErr := checkFieldBytes(call.Params.Arguments, fields); toolErr != nil {
+						return toolErrorResult(toolErr), nil
+					}
+				}
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
+
+// checkFieldBytes returns a validation error for the first string field, by name,
+// longer than its limit. Arguments that are not a JSON object, and non-string values, are left
+// to schema validation.
+func checkFieldBytes(arguments json.RawMessage, limits map[string]int) *ToolError {
+	var args map[string]json.RawMessage
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return nil
+	}
+
+	for _, field := range slices.Sorted(maps.Keys(limits)) {
+		limit := limits[field]
+		var value string
+		if err := json.Unmarshal(args[field], &value); err != nil {
+			continue
+		}
+		if len(value) > limit {
+			return ValidationError(fmt.Sprintf("field %q is %d bytes, exceeding the limit of %d bytes", field, len(value), limit))
+		}
+	}
+	return nil
+}
+
+// localeMetaKey is the request _meta key clients can set to choose a locale
+const localeMetaKey = "locale"
+
+// descriptionTranslatorMiddleware rewrites tool descriptions in tools/list results
+// for the request's locale. The server's tools are copied, not modified, since
+// clients may list tools in different locales concurrently.
+func descriptionTranslatorMiddleware(translate func(locale, name, description string) string) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			result, err := next(ctx, method, req)
+			list, ok := result.(*mcp.ListToolsResult)
+			if !ok || list == nil || err != nil {
+				return result, err
+			}
+			locale := requestLocale(req)
+			if locale == "" {
+				return result, err
+			}
+
+			translated := *list
+			translated.Tools = make([]*mcp.Tool, len(list.Tools))
+			for i, tool := range list.Tools {
+				translated.Tools[i] = tool
+				if description := translate(locale, tool.Name, tool.Description); description != "" {
+					localized := *tool
+					localized.Description = description
+					translated.Tools[i] = &localized
+				}
+			}
+			return &translated, nil
+		}
+	}
+}
+
+// requestLocale returns the locale a request asks for: the locale in its _meta,
+// or else the preferred language in the HTTP Accept-Language header
+func requestLocale(req mcp.Request) string {
+	if params, ok := req.GetParams().(*mcp.ListToolsParams); ok && params != nil {
+		if locale, ok := params.Meta[localeMetaKey].(string); ok && locale != "" {
+			return locale
+		}
+	}
+	if extra := req.GetExtra(); extra != nil && extra.Header != nil {
+		return preferredLanguage(extra.Header.Get("Accept-Language"))
+	}
+	return ""
+}
+
+// preferredLanguage returns the language with the highest quality value in an
+// Accept-Language header, ignoring the "*" wildcard. Ties go to the first listed.
+func preferredLanguage(header string) string {
+	best, bestQuality := "", 0.0
+	for entry := range strings.SplitSeq(header, ",") {
+		tag, params, _ := strings.Cut(entry, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > bestQuality {
+			best, bestQuality = tag, quality
+		}
+	}
+	return best
+}
+
+// dynamicEnumMiddleware sets the enums of input fields in tools/list results from
+// their functions. The affected tools and schemas are copied, since the registered
+// schemas are shared by every client and used to validate calls.
+func dynamicEnumMiddleware(enums map[string]map[string]func(context.Context) []string) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			result, err := next(ctx, method, req)
+			list, ok := result.(*mcp.ListToolsResult)
+			if !ok || list == nil || err != nil {
+				return result, err
+			}
+
+			updated := *list
+			updated.Tools = make([]*mcp.Tool, len(list.Tools))
+			for i, tool := range list.Tools {
+				updated.Tools[i] = tool
+				fields, ok := enums[tool.Name]
+				if !ok || tool.InputSchema == nil {
+					continue
+				}
+
+				schema := *tool.InputSchema
+				schema.Properties = maps.Clone(schema.Properties)
+				for field, fn := range fields {
+					property, ok := schema.Properties[field]
+					if !ok {
+						continue
+					}
+					withEnum := *property
+					withEnum.Enum = nil
+					for _, value := range fn(ctx) {
+						withEnum.Enum = append(withEnum.Enum, value)
+					}
+					schema.Properties[field] = &withEnum
+				}
+				withEnums := *tool
+				withEnums.InputSchema = &schema
+				updated.Tools[i] = &withEnums
+			}
+			return &updated, nil
+		}
+	}
+}
+
+// resultTimingMiddleware records how long each tool call took, in milliseconds,
+// under durationMs in the result's _meta. Both successful and error results are timed.
+func resultTimingMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		start := time.Now()
+		result, err := next(ctx, method, req)
+
+		if res, ok := result.(*mcp.CallToolResult); ok && res != nil {
+			if res.Meta == nil {
+				res.Meta = mcp.Meta{}
+			}
+			res.Meta["durationMs"] = float64(time.Since(start).Microseconds()) / 1000
+		}
+		return result, err
+	}
+}
+
+// toolErrorContextKey is the context key for the slot a typed tool's ToolError is
+// recorded in
+type toolErrorContextKey struct{}
+
+// toolErrorSlot carries a ToolError from a typed tool handler back out to
+// toolErrorCodeMiddleware, since the SDK builds typed error results itself
+type toolErrorSlot struct {
+	err *ToolError
+}
+
+// recordToolError stores a typed tool's ToolError so its result can be rebuilt.
+// It is a no-op when ctx has no slot.
+func recordToolError(ctx context.Context, toolErr *ToolError) {
+	if slot, ok := ctx.Value(toolErrorContextKey{}).(*toolErrorSlot); ok {
+		slot.err = toolErr
+	}
+}
+
+// toolErrorCodeMiddleware replaces the error result the SDK built for a typed
+// tool's ToolError with one from toolErrorResult, so typed and raw tool errors reach
+// clients alike: the message and code, without the wrapped cause
+func toolErrorCodeMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if _, ok := req.(*mcp.CallToolRequest); !ok {
+			return next(ctx, method, req)
+		}
+
+		slot := &toolErrorSlot{}
+		result, err := next(context.WithValue(ctx, toolErrorContextKey{}, slot), method, req)
+
+		res, ok := result.(*mcp.CallToolResult)
+		if !ok || res == nil || !res.IsError || slot.err == nil {
+			return result, err
+		}
+		rebuilt := toolErrorResult(slot.err)
+		for key, value := range res.Meta {
+			if rebuilt.Meta == nil {
+				rebuilt.Meta = mcp.Meta{}
+			}
+			if _, set := rebuilt.Meta[key]; !set {
+				rebuilt.Meta[key] = value
+			}
+		}
+		return rebuilt, err
+	}
+}
@@ -2,9 +2,18 @@ package mcpio
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"reflect"
+	"slices"
+	"time"
 
 	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 )
 
 // ToolFunc is the function signature for typed tools with automatic schema generation.
@@ -14,9 +23,31 @@ type ToolFunc[TIn, TOut any] func(context.Context, TIn) (TOut, error)
 
 // RawToolFunc is the function signature for raw JSON tools.
 // The function receives a context and raw JSON bytes as input, and returns JSON bytes as output.
-// Schema must be provided explicitly when using WithRawTool.
+// Schema must be provided explicitly when using WithRawTool. With WithPayloadCodec,
+// the bytes are in the codec's format instead of JSON.
 type RawToolFunc func(context.Context, []byte) ([]byte, error)
 
+// ResourceReadFunc is the function signature for reading a resource.
+// The function receives a context and the requested URI, and returns the resource contents.
+type ResourceReadFunc func(ctx context.Context, uri string) ([]byte, error)
+
+// PromptFunc is the function signature for rendering a prompt.
+// The function receives a context and the client-supplied arguments, and returns the prompt messages.
+// Message content may be any MCP content block, including embedded resources built with
+// EmbeddedResourceContent.
+type PromptFunc func(ctx context.Context, args map[string]string) ([]mcp.PromptMessage, error)
+
+// PromptArg describes an argument accepted by a prompt
+type PromptArg struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// ToolAnnotations are hints describing a tool's behavior (read-only, destructive,
+// idempotent, open-world) that help clients decide whether to auto-approve a call
+type ToolAnnotations = mcp.ToolAnnotations
+
 // Option is a functional option for configuring handlers
 type Option func(*handlerConfig) error
 
@@ -42,6 +73,18 @@ func WithVersion(version string) Option {
 	}
 }
 
+// WithInstructions sets instructions returned to clients during initialization,
+// giving the model high-level guidance on how to use the server's tools
+func WithInstructions(instructions string) Option {
+	return func(cfg *handlerConfig) error {
+		if instructions == "" {
+			return ErrEmptyInstructions
+		}
+		cfg.instructions = instructions
+		return nil
+	}
+}
+
 // WithTool adds a type-safe tool with automatic schema generation
 func WithTool[TIn, TOut any](name, description string, fn ToolFunc[TIn, TOut]) Option {
 	return func(cfg *handlerConfig) error {
@@ -49,18 +92,127 @@ func WithTool[TIn, TOut any](name, description string, fn ToolFunc[TIn, TOut]) O
 			return ErrEmptyToolName
 		}
 
+		// Generate the input schema up front so it can be inspected and so
+		// unsupported input types return an error instead of panicking
+		inputSchema, err := generateInputSchema[TIn]()
+		if err != nil {
+			return fmt.Errorf("%w: tool %q: %w", ErrInvalidSchema, name, err)
+		}
+
+		tool := &mcp.Tool{
+			Name:        name,
+			Description: description,
+			InputSchema: inputSchema,
+		}
+		// Generate the output schema here so tag markers are honored. Pointer
+		// outputs are left to the generic AddTool, which substitutes the zero
+		// value for a nil result only when it generates the schema itself.
+		outType := reflect.TypeFor[TOut]()
+		if outType.Kind() != reflect.Pointer {
+			tool.OutputSchema = generateOutputSchemaFor(outType)
+		}
+
 		// Create registration function that uses the generic AddTool
-		registerFunc := func(server *mcp.Server) {
-			tool := &mcp.Tool{
-				Name:        name,
-				Description: description,
-				// Let the generic AddTool handle schema generation
+		registerFunc := func(server *mcp.Server, tool *mcp.Tool, chain *callChain) {
+			handler := createTypedHandler(wrapToolFunc(chain, name, fn))
+			mcp.AddTool(server, tool, handler)
+		}
+
+		// The SDK validates the decoded input struct, where output-only fields are
+		// always present, so validate the arguments before decoding instead
+		if inType := reflect.TypeFor[TIn](); hasReadOnlyTags(inType) {
+			resolved, err := inputSchema.Resolve(nil)
+			if err != nil {
+				return fmt.Errorf("%w: tool %q: %w", ErrInvalidSchema, name, err)
+			}
+			fnValue := reflect.ValueOf(fn)
+			registerFunc = func(server *mcp.Server, tool *mcp.Tool, chain *callChain) {
+				server.AddTool(tool, createReflectHandler(chain, name, fnValue, inType, resolved, tool.OutputSchema != nil))
+			}
+		}
+
+		entry := &toolEntry{tool: tool, register: registerFunc}
+		if tool.OutputSchema == nil {
+			entry.outputType = outType
+		}
+		cfg.tools = append(cfg.tools, entry)
+
+		return nil
+	}
+}
+
+// ToolSpec describes a tool for WithTools. Handler is either a typed tool function
+// with the shape of a ToolFunc, func(context.Context, TIn) (TOut, error), whose
+// types are read with reflection, or a RawToolFunc, which also needs InputSchema.
+type ToolSpec struct {
+	Name        string
+	Description string
+	Handler     any
+	InputSchema *jsonschema.Schema // Required for raw handlers, ignored otherwise
+}
+
+// WithTools adds several tools at once, e.g. from a slice built from configuration.
+// Errors identify the failing spec by index and name.
+func WithTools(specs ...ToolSpec) Option {
+	return func(cfg *handlerConfig) error {
+		for i, spec := range specs {
+			var opt Option
+			switch fn := spec.Handler.(type) {
+			case RawToolFunc:
+				opt = WithRawTool(spec.Name, spec.Description, spec.InputSchema, fn)
+			case func(context.Context, []byte) ([]byte, error):
+				opt = WithRawTool(spec.Name, spec.Description, spec.InputSchema, fn)
+			default:
+				opt = withReflectTool(spec.Name, spec.Description, fn)
+			}
+			if err := opt(cfg); err != nil {
+				return fmt.Errorf("tool spec %d (%q): %w", i, spec.Name, err)
 			}
-			handler := createTypedHandler(fn)
+		}
+		return nil
+	}
+}
+
+// WithToolWithSchemas adds a type-safe tool that advertises the given input and output
+// schemas instead of the ones generated from TIn and TOut. Input is still unmarshaled
+// into TIn and output is serialized from TOut, so this is useful when a type's JSON
+// form differs from its Go structure (e.g. a custom MarshalJSON).
+func WithToolWithSchemas[TIn, TOut any](
+	name, description string,
+	inputSchema, outputSchema *jsonschema.Schema,
+	fn ToolFunc[TIn, TOut],
+) Option {
+	return func(cfg *handlerConfig) error {
+		if name == "" {
+			return ErrEmptyToolName
+		}
+		if inputSchema == nil || outputSchema == nil {
+			return ErrNilSchema
+		}
+
+		// The SDK panics on non-object or unresolvable schemas, so check them here
+		for _, schema := range []*jsonschema.Schema{inputSchema, outputSchema} {
+			if schema.Type != "object" {
+				return fmt.Errorf("%w: tool %q: schema must have type \"object\"", ErrInvalidSchema, name)
+			}
+			if _, err := schema.Resolve(nil); err != nil {
+				return fmt.Errorf("%w: tool %q: %w", ErrInvalidSchema, name, err)
+			}
+		}
+
+		tool := &mcp.Tool{
+			Name:         name,
+			Description:  description,
+			InputSchema:  inputSchema,
+			OutputSchema: outputSchema,
+		}
+
+		registerFunc := func(server *mcp.Server, tool *mcp.Tool, chain *callChain) {
+			handler := createTypedHandler(wrapToolFunc(chain, name, fn))
 			mcp.AddTool(server, tool, handler)
 		}
 
-		cfg.tools = append(cfg.tools, registerFunc)
+		cfg.tools = append(cfg.tools, &toolEntry{tool: tool, register: registerFunc})
 
 		return nil
 	}
@@ -76,19 +228,833 @@ func WithRawTool(name, description string, inputSchema *jsonschema.Schema, fn Ra
 			return ErrNilSchema
 		}
 
+		// The SDK panics on non-object input schemas, so check it here
+		if inputSchema.Type != "object" {
+			return fmt.Errorf("%w: tool %q: schema must have type \"object\"", ErrInvalidSchema, name)
+		}
+
+		// Resolve the schema up front so invalid schemas fail at construction time
+		resolved, err := inputSchema.Resolve(nil)
+		if err != nil {
+			return fmt.Errorf("%w: tool %q: %w", ErrInvalidSchema, name, err)
+		}
+
+		tool := &mcp.Tool{
+			Name:        name,
+			Description: description,
+			InputSchema: inputSchema,
+		}
+
 		// Create registration function that uses the low-level AddTool
-		registerFunc := func(server *mcp.Server) {
-			tool := &mcp.Tool{
+		registerFunc := func(server *mcp.Server, tool *mcp.Tool, chain *callChain) {
+			wrapped := wrapToolFunc(chain, name, ToolFunc[[]byte, []byte](fn))
+			validateWith := resolved
+			if !chain.inputValidation {
+				validateWith = nil
+			}
+			handler := createRawHandler(chain, name, RawToolFunc(wrapped), validateWith)
+			if chain.codec != nil {
+				if tool.Meta == nil {
+					tool.Meta = mcp.Meta{}
+				}
+				tool.Meta[payloadContentTypeMetaKey] = chain.codec.ContentType()
+			}
+			server.AddTool(tool, handler)
+		}
+
+		cfg.tools = append(cfg.tools, &toolEntry{tool: tool, rawFunc: fn, register: registerFunc})
+
+		return nil
+	}
+}
+
+// WithRawToolOutputSample attaches an output schema to a raw tool, inferred from a
+// sample of the JSON the tool returns. Struct samples honor json and jsonschema tags;
+// other samples are inferred from their JSON form. The sample must describe an object.
+func WithRawToolOutputSample(name string, sample any) Option {
+	return func(cfg *handlerConfig) error {
+		if name == "" {
+			return ErrEmptyToolName
+		}
+		if sample == nil {
+			return ErrNilSchema
+		}
+
+		outputSchema, err := inferSchemaFromSample(sample)
+		if err != nil {
+			return fmt.Errorf("%w: tool %q: %w", ErrInvalidSchema, name, err)
+		}
+		if outputSchema.Type != "object" {
+			return fmt.Errorf("%w: tool %q: output sample must be an object", ErrInvalidSchema, name)
+		}
+
+		cfg.toolModifiers = append(cfg.toolModifiers, func(cfg *handlerConfig) error {
+			entry := cfg.findTool(name)
+			if entry == nil || entry.rawFunc == nil {
+				return fmt.Errorf("%w: raw tool %q", ErrToolNotFound, name)
+			}
+			entry.tool.OutputSchema = outputSchema
+			return nil
+		})
+
+		return nil
+	}
+}
+
+// WithToolAnnotations attaches behavior hints to a previously or subsequently
+// registered tool. It returns ErrToolNotFound if no tool has the given name.
+func WithToolAnnotations(name string, annotations ToolAnnotations) Option {
+	return func(cfg *handlerConfig) error {
+		if name == "" {
+			return ErrEmptyToolName
+		}
+
+		cfg.toolModifiers = append(cfg.toolModifiers, func(cfg *handlerConfig) error {
+			entry := cfg.findTool(name)
+			if entry == nil {
+				return fmt.Errorf("%w: %q", ErrToolNotFound, name)
+			}
+			entry.tool.Annotations = &annotations
+			return nil
+		})
+
+		return nil
+	}
+}
+
+// WithToolTags attaches tags to a previously or subsequently registered tool, for
+// grouping tools locally with Handler.ToolsByTag (e.g. "math" or "text"). Tags are
+// not sent to clients, since MCP has no notion of them. Tags accumulate across
+// calls, ignoring duplicates. It returns ErrToolNotFound if no tool has the given
+// name.
+func WithToolTags(name string, tags ...string) Option {
+	return func(cfg *handlerConfig) error {
+		if name == "" {
+			return ErrEmptyToolName
+		}
+		if slices.Contains(tags, "") {
+			return ErrEmptyTag
+		}
+		tags = slices.Clone(tags)
+
+		cfg.toolModifiers = append(cfg.toolModifiers, func(cfg *handlerConfig) error {
+			entry := cfg.findTool(name)
+			if entry == nil {
+				return fmt.Errorf("%w: %q", ErrToolNotFound, name)
+			}
+			for _, tag := range tags {
+				if !slices.Contains(entry.tags, tag) {
+					entry.tags = append(entry.tags, tag)
+				}
+			}
+			return nil
+		})
+
+		return nil
+	}
+}
+
+// propertyOrderingMetaKey is the input schema _meta key for the order of input fields
+const propertyOrderingMetaKey = "propertyOrdering"
+
+// WithPropertyOrder advertises the order in which clients should present the named
+// tool's input fields, e.g. when rendering a form, as the "propertyOrdering" entry of
+// the input schema's _meta. Every name must be an input field, listed once; fields
+// left out have no defined order. The schema is copied, so a schema shared with other
+// tools is left as it was. It returns ErrToolNotFound if no tool has the given name.
+func WithPropertyOrder(toolName string, order []string) Option {
+	return func(cfg *handlerConfig) error {
+		if toolName == "" {
+			return ErrEmptyToolName
+		}
+		if len(order) == 0 {
+			return fmt.Errorf("%w: no fields given for tool %q", ErrInvalidPropertyOrder, toolName)
+		}
+		order = slices.Clone(order)
+
+		cfg.toolModifiers = append(cfg.toolModifiers, func(cfg *handlerConfig) error {
+			entry := cfg.findTool(toolName)
+			if entry == nil {
+				return fmt.Errorf("%w: %q", ErrToolNotFound, toolName)
+			}
+			seen := make(map[string]bool, len(order))
+			for _, field := range order {
+				if entry.tool.InputSchema == nil || entry.tool.InputSchema.Properties[field] == nil {
+					return fmt.Errorf("%w: tool %q has no input field %q", ErrFieldNotFound, toolName, field)
+				}
+				if seen[field] {
+					return fmt.Errorf("%w: field %q listed twice for tool %q", ErrInvalidPropertyOrder, field, toolName)
+				}
+				seen[field] = true
+			}
+
+			schema := *entry.tool.InputSchema
+			schema.Extra = maps.Clone(schema.Extra)
+			if schema.Extra == nil {
+				schema.Extra = make(map[string]any)
+			}
+			meta, _ := schema.Extra["_meta"].(map[string]any)
+			meta = maps.Clone(meta)
+			if meta == nil {
+				meta = make(map[string]any)
+			}
+			meta[propertyOrderingMetaKey] = order
+			schema.Extra["_meta"] = meta
+			entry.tool.InputSchema = &schema
+			return nil
+		})
+
+		return nil
+	}
+}
+
+// WithToolTimeout bounds how long the named tool may run. When the timeout fires, the
+// tool's context is cancelled and the call fails with a tool error coded "TIMEOUT".
+// It returns ErrToolNotFound if no tool has the given name.
+func WithToolTimeout(name string, d time.Duration) Option {
+	return func(cfg *handlerConfig) error {
+		if name == "" {
+			return ErrEmptyToolName
+		}
+		if d <= 0 {
+			return fmt.Errorf("%w: %s", ErrInvalidTimeout, d)
+		}
+
+		cfg.toolModifiers = append(cfg.toolModifiers, func(cfg *handlerConfig) error {
+			if cfg.findTool(name) == nil {
+				return fmt.Errorf("%w: %q", ErrToolNotFound, name)
+			}
+			if cfg.toolTimeouts == nil {
+				cfg.toolTimeouts = make(map[string]time.Duration)
+			}
+			cfg.toolTimeouts[name] = d
+			return nil
+		})
+
+		return nil
+	}
+}
+
+// WithDefaultToolTimeout bounds how long every tool may run unless a per-tool timeout
+// set with WithToolTimeout overrides it. A zero duration disables the default timeout.
+func WithDefaultToolTimeout(d time.Duration) Option {
+	return func(cfg *handlerConfig) error {
+		if d < 0 {
+			return fmt.Errorf("%w: %s", ErrInvalidTimeout, d)
+		}
+		cfg.defaultToolTimeout = d
+		return nil
+	}
+}
+
+// WithInputValidation controls whether raw tool arguments are validated against the
+// tool's input schema before the tool function is called. Validation is on by default;
+// typed tools are always validated by the SDK.
+func WithInputValidation(enabled bool) Option {
+	return func(cfg *handlerConfig) error {
+		cfg.disableInputValidation = !enabled
+		return nil
+	}
+}
+
+// WithLogger sets a structured logger that records the start and end of every tool
+// call, including its duration and whether a failure was a tool or protocol error
+func WithLogger(logger *slog.Logger) Option {
+	return func(cfg *handlerConfig) error {
+		if logger == nil {
+			return ErrNilLogger
+		}
+		cfg.logger = logger
+		return nil
+	}
+}
+
+// WithFieldDefaultFromEnv fills the named tool's input field from an environment
+// variable when a call omits it, e.g. to default a region. The variable is read on
+// every call; an explicit value in the call always wins, and an unset variable leaves
+// the field absent. Non-string fields are parsed from the variable as JSON.
+func WithFieldDefaultFromEnv(toolName, field, envVar string) Option {
+	return func(cfg *handlerConfig) error {
+		if toolName == "" {
+			return ErrEmptyToolName
+		}
+		if field == "" {
+			return ErrEmptyFieldName
+		}
+		if envVar == "" {
+			return ErrEmptyEnvVar
+		}
+
+		cfg.toolModifiers = append(cfg.toolModifiers, func(cfg *handlerConfig) error {
+			entry := cfg.findTool(toolName)
+			if entry == nil {
+				return fmt.Errorf("%w: %q", ErrToolNotFound, toolName)
+			}
+			schema := entry.tool.InputSchema
+			if schema == nil || schema.Properties[field] == nil {
+				return fmt.Errorf("%w: tool %q has no input field %q", ErrFieldNotFound, toolName, field)
+			}
+
+			if cfg.envDefaults == nil {
+				cfg.envDefaults = make(map[string][]fieldEnvDefault)
+			}
+			cfg.envDefaults[toolName] = append(cfg.envDefaults[toolName], fieldEnvDefault{
+				field:  field,
+				envVar: envVar,
+				decode: schema.Properties[field].Type != "string",
+			})
+			return nil
+		})
+
+		return nil
+	}
+}
+
+// WithFieldMaxBytes limits the named tool's string input field to n bytes of UTF-8,
+// for storage that is sized in bytes rather than characters. Calls with a longer
+// value fail with a tool error coded "VALIDATION_ERROR" before the tool runs.
+func WithFieldMaxBytes(toolName, field string, n int) Option {
+	return func(cfg *handlerConfig) error {
+		if toolName == "" {
+			return ErrEmptyToolName
+		}
+		if field == "" {
+			return ErrEmptyFieldName
+		}
+		if n <= 0 {
+			return fmt.Errorf("%w: %d", ErrInvalidByteLimit, n)
+		}
+
+		cfg.toolModifiers = append(cfg.toolModifiers, func(cfg *handlerConfig) error {
+			entry := cfg.findTool(toolName)
+			if entry == nil {
+				return fmt.Errorf("%w: %q", ErrToolNotFound, toolName)
+			}
+			schema := entry.tool.InputSchema
+			if schema == nil || schema.Properties[field] == nil {
+				return fmt.Errorf("%w: tool %q has no input field %q", ErrFieldNotFound, toolName, field)
+			}
+			if schema.Properties[field].Type != "string" {
+				return fmt.Errorf("%w: tool %q input field %q is not a string", ErrInvalidSchema, toolName, field)
+			}
+
+			if cfg.fieldMaxBytes == nil {
+				cfg.fieldMaxBytes = make(map[string]map[string]int)
+			}
+			if cfg.fieldMaxBytes[toolName] == nil {
+				cfg.fieldMaxBytes[toolName] = make(map[string]int)
+			}
+			cfg.fieldMaxBytes[toolName][field] = n
+			return nil
+		})
+
+		return nil
+	}
+}
+
+// WithFieldTitle sets the title of the named tool's input field, which clients can
+// show as the field's label. The tool's input schema is copied, so a schema passed to
+// WithRawTool is left unchanged.
+func WithFieldTitle(toolName, field, title string) Option {
+	return func(cfg *handlerConfig) error {
+		if toolName == "" {
+			return ErrEmptyToolName
+		}
+		if field == "" {
+			return ErrEmptyFieldName
+		}
+		if title == "" {
+			return ErrEmptyTitle
+		}
+
+		cfg.toolModifiers = append(cfg.toolModifiers, func(cfg *handlerConfig) error {
+			entry := cfg.findTool(toolName)
+			if entry == nil {
+				return fmt.Errorf("%w: %q", ErrToolNotFound, toolName)
+			}
+			if entry.tool.InputSchema == nil || entry.tool.InputSchema.Properties[field] == nil {
+				return fmt.Errorf("%w: tool %q has no input field %q", ErrFieldNotFound, toolName, field)
+			}
+
+			schema := *entry.tool.InputSchema
+			schema.Properties = maps.Clone(schema.Properties)
+			property := *schema.Properties[field]
+			property.Title = title
+			schema.Properties[field] = &property
+			entry.tool.InputSchema = &schema
+			return nil
+		})
+
+		return nil
+	}
+}
+
+// WithMetrics records call counts, error counts, and latencies for every tool call
+// in the given Metrics implementation
+func WithMetrics(metrics Metrics) Option {
+	return func(cfg *handlerConfig) error {
+		if metrics == nil {
+			return ErrNilMetrics
+		}
+		cfg.metrics = metrics
+		return nil
+	}
+}
+
+// WithEventChannel publishes telemetry events to ch: the start and end of every tool
+// call, with the error of calls that fail, and the connection and disconnection of
+// client sessions. Events are sent without blocking and dropped when ch is full, so
+// a slow consumer never delays calls. With WithServer, only call events are
+// published.
+func WithEventChannel(ch chan<- Event) Option {
+	return func(cfg *handlerConfig) error {
+		if ch == nil {
+			return ErrNilChannel
+		}
+		cfg.events = ch
+		return nil
+	}
+}
+
+// WithTracer starts an OpenTelemetry span named after the tool around every tool
+// call, as a child of any span in the incoming request context. Errors are recorded
+// on the span along with whether they were tool or protocol errors.
+func WithTracer(tracer trace.Tracer) Option {
+	return func(cfg *handlerConfig) error {
+		if tracer == nil {
+			return ErrNilTracer
+		}
+		cfg.tracer = tracer
+		return nil
+	}
+}
+
+// WithResultTiming adds how long each tool call took, in milliseconds, to the
+// durationMs field of the result's _meta, for both successful and error results
+func WithResultTiming() Option {
+	return func(cfg *handlerConfig) error {
+		cfg.resultTiming = true
+		return nil
+	}
+}
+
+// WithLargeResultAsResource returns the named tool's result as a resource_link when
+// its text output exceeds threshold bytes, instead of inline. The output is stored as
+// a temporary resource that only the session that made the call can read; only the
+// most recent results are kept. Structured content is still sent inline, since the
+// spec requires it for tools with an output schema.
+func WithLargeResultAsResource(toolName string, threshold int) Option {
+	return func(cfg *handlerConfig) error {
+		if toolName == "" {
+			return ErrEmptyToolName
+		}
+		if threshold <= 0 {
+			return fmt.Errorf("%w: %d", ErrInvalidThreshold, threshold)
+		}
+
+		cfg.toolModifiers = append(cfg.toolModifiers, func(cfg *handlerConfig) error {
+			if cfg.findTool(toolName) == nil {
+				return fmt.Errorf("%w: %q", ErrToolNotFound, toolName)
+			}
+			if cfg.largeResultThresholds == nil {
+				cfg.largeResultThresholds = make(map[string]int)
+			}
+			cfg.largeResultThresholds[toolName] = threshold
+			return nil
+		})
+
+		return nil
+	}
+}
+
+// WithContinuationSupport lets the named raw tool return a large result in parts.
+// The tool returns {"partial": true, "continuation": "<token>", "data": ...} for
+// each part but the last, which has "partial": false. Clients receive only the
+// data, with partial and the continuation token in the result's _meta, and pass
+// the token back under continuation in the next call's _meta, where the tool reads
+// it with ContinuationFromContext. Output without a partial field is returned as is.
+func WithContinuationSupport(toolName string) Option {
+	return func(cfg *handlerConfig) error {
+		if toolName == "" {
+			return ErrEmptyToolName
+		}
+
+		cfg.toolModifiers = append(cfg.toolModifiers, func(cfg *handlerConfig) error {
+			entry := cfg.findTool(toolName)
+			if entry == nil {
+				return fmt.Errorf("%w: %q", ErrToolNotFound, toolName)
+			}
+			if entry.rawFunc == nil {
+				return fmt.Errorf("%w: %q", ErrNotRawTool, toolName)
+			}
+			if cfg.continuationTools == nil {
+				cfg.continuationTools = make(map[string]bool)
+			}
+			cfg.continuationTools[toolName] = true
+			return nil
+		})
+
+		return nil
+	}
+}
+
+// WithResource adds a readable resource (e.g. a config file or dataset) to the server.
+// The URI must be absolute, and mimeType is advertised to clients and used to decide
+// whether contents are returned as text or as a binary blob.
+func WithResource(uri, name, description, mimeType string, read ResourceReadFunc) Option {
+	return func(cfg *handlerConfig) error {
+		if uri == "" {
+			return ErrEmptyResourceURI
+		}
+		// The SDK panics on invalid URIs, so check them here
+		parsed, err := url.Parse(uri)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidResourceURI, err)
+		}
+		if !parsed.IsAbs() {
+			return fmt.Errorf("%w: %q is not absolute", ErrInvalidResourceURI, uri)
+		}
+		if name == "" {
+			return ErrEmptyResourceName
+		}
+		if read == nil {
+			return ErrNilFunction
+		}
+		// The SDK silently replaces a resource registered twice, so reject it here
+		for _, entry := range cfg.resources {
+			if entry.resource.URI == uri {
+				return fmt.Errorf("%w: %q", ErrDuplicateResource, uri)
+			}
+		}
+
+		cfg.resources = append(cfg.resources, &resourceEntry{
+			resource: &mcp.Resource{
+				URI:         uri,
 				Name:        name,
 				Description: description,
-				InputSchema: inputSchema,
+				MIMEType:    mimeType,
+			},
+			handler: createResourceHandler(mimeType, read),
+		})
+
+		return nil
+	}
+}
+
+// WithPrompt adds a reusable prompt template that clients can discover and render.
+// Requests missing a required argument are rejected before fn is called.
+func WithPrompt(name, description string, args []PromptArg, fn PromptFunc) Option {
+	return func(cfg *handlerConfig) error {
+		if name == "" {
+			return ErrEmptyPromptName
+		}
+		if fn == nil {
+			return ErrNilFunction
+		}
+
+		prompt := &mcp.Prompt{
+			Name:        name,
+			Description: description,
+			Arguments:   make([]*mcp.PromptArgument, 0, len(args)),
+		}
+		for _, arg := range args {
+			if arg.Name == "" {
+				return fmt.Errorf("%w: prompt %q", ErrEmptyArgumentName, name)
 			}
-			handler := createRawHandler(fn)
-			server.AddTool(tool, handler)
+			prompt.Arguments = append(prompt.Arguments, &mcp.PromptArgument{
+				Name:        arg.Name,
+				Description: arg.Description,
+				Required:    arg.Required,
+			})
+		}
+
+		cfg.prompts = append(cfg.prompts, &promptEntry{
+			prompt:  prompt,
+			handler: createPromptHandler(prompt, fn),
+		})
+
+		return nil
+	}
+}
+
+// WithDescriptionDecorator rewrites every tool description at registration time,
+// e.g. to add a consistent "[beta]" prefix without editing each tool
+func WithDescriptionDecorator(fn func(name, description string) string) Option {
+	return func(cfg *handlerConfig) error {
+		if fn == nil {
+			return ErrNilFunction
+		}
+		cfg.descriptionDecorator = fn
+		return nil
+	}
+}
+
+// WithDescriptionTranslator localizes tool descriptions when clients list tools.
+// The locale comes from the "locale" field of the tools/list request's _meta, or
+// else the HTTP Accept-Language header. fn receives the tool name and its
+// registered description; returning "" keeps the original. Without a locale, fn
+// is not called.
+func WithDescriptionTranslator(fn func(locale, name, description string) string) Option {
+	return func(cfg *handlerConfig) error {
+		if fn == nil {
+			return ErrNilFunction
+		}
+		cfg.descriptionTranslator = fn
+		return nil
+	}
+}
+
+// WithDynamicEnum makes the named tool's string input field list the values fn
+// returns as its enum each time clients list tools, for choices that depend on
+// runtime state such as the available models. An empty result lists the field
+// without an enum. The enum is advertised only; calls are not checked against it,
+// so the tool must still reject values that are no longer valid.
+func WithDynamicEnum(toolName, field string, fn func(ctx context.Context) []string) Option {
+	return func(cfg *handlerConfig) error {
+		if toolName == "" {
+			return ErrEmptyToolName
+		}
+		if field == "" {
+			return ErrEmptyFieldName
+		}
+		if fn == nil {
+			return ErrNilFunction
+		}
+
+		cfg.toolModifiers = append(cfg.toolModifiers, func(cfg *handlerConfig) error {
+			entry := cfg.findTool(toolName)
+			if entry == nil {
+				return fmt.Errorf("%w: %q", ErrToolNotFound, toolName)
+			}
+			schema := entry.tool.InputSchema
+			if schema == nil || schema.Properties[field] == nil {
+				return fmt.Errorf("%w: tool %q has no input field %q", ErrFieldNotFound, toolName, field)
+			}
+			if schema.Properties[field].Type != "string" {
+				return fmt.Errorf("%w: tool %q input field %q is not a string", ErrInvalidSchema, toolName, field)
+			}
+
+			if cfg.dynamicEnums == nil {
+				cfg.dynamicEnums = make(map[string]map[string]func(context.Context) []string)
+			}
+			if cfg.dynamicEnums[toolName] == nil {
+				cfg.dynamicEnums[toolName] = make(map[string]func(context.Context) []string)
+			}
+			cfg.dynamicEnums[toolName][field] = fn
+			return nil
+		})
+
+		return nil
+	}
+}
+
+// WithValidateExamples checks every example attached to a tool's input and output
+// schemas, including nested property schemas, against the schema it belongs to.
+// Construction fails with ErrInvalidExample if any example doesn't conform.
+func WithValidateExamples() Option {
+	return func(cfg *handlerConfig) error {
+		cfg.validateExamples = true
+		return nil
+	}
+}
+
+// WithStrictValidation controls how the server name and version are checked. Names
+// containing control characters and versions that aren't semantic versions (e.g.
+// "1.2.0" or "v1.2.0-beta.1") are logged as warnings by default, and fail
+// construction with ErrInvalidName or ErrInvalidVersion when strict is true.
+func WithStrictValidation(strict bool) Option {
+	return func(cfg *handlerConfig) error {
+		cfg.strictValidation = strict
+		return nil
+	}
+}
+
+// WithQuota checks every tool call against a quota store before the tool runs.
+// Calls the store denies fail with a tool error coded "RATE_LIMITED". Clients are
+// identified by WithClientIdentifier.
+func WithQuota(store QuotaStore) Option {
+	return func(cfg *handlerConfig) error {
+		if store == nil {
+			return ErrNilQuotaStore
+		}
+		cfg.quota = store
+		return nil
+	}
+}
+
+// WithClientIdentifier sets how the client making a tool call is identified for
+// quotas and rate limits. The default, SessionClientID, changes whenever a client opens a new
+// session; an identifier based on IdentityFromContext keeps a user's quota across
+// sessions and server instances.
+func WithClientIdentifier(fn func(ctx context.Context) string) Option {
+	return func(cfg *handlerConfig) error {
+		if fn == nil {
+			return ErrNilFunction
+		}
+		cfg.clientIdentifier = fn
+		return nil
+	}
+}
+
+// WithRateLimit throttles calls to the named tool to r per second with bursts of up
+// to burst calls. Each client has its own allowance, identified as for WithQuota.
+// Calls over the limit fail with a tool error coded "RATE_LIMITED".
+func WithRateLimit(name string, r rate.Limit, burst int) Option {
+	return func(cfg *handlerConfig) error {
+		if name == "" {
+			return ErrEmptyToolName
+		}
+		if r <= 0 || burst <= 0 {
+			return fmt.Errorf("%w: rate %v, burst %d", ErrInvalidRateLimit, r, burst)
+		}
+
+		cfg.toolModifiers = append(cfg.toolModifiers, func(cfg *handlerConfig) error {
+			if cfg.findTool(name) == nil {
+				return fmt.Errorf("%w: %q", ErrToolNotFound, name)
+			}
+			if cfg.rateLimits == nil {
+				cfg.rateLimits = make(map[string]rateLimit)
+			}
+			cfg.rateLimits[name] = rateLimit{limit: r, burst: burst}
+			return nil
+		})
+
+		return nil
+	}
+}
+
+// WithDefaultRateLimit throttles calls to every tool without a limit set by
+// WithRateLimit, each tool and client having its own allowance
+func WithDefaultRateLimit(r rate.Limit, burst int) Option {
+	return func(cfg *handlerConfig) error {
+		if r <= 0 || burst <= 0 {
+			return fmt.Errorf("%w: rate %v, burst %d", ErrInvalidRateLimit, r, burst)
+		}
+		cfg.defaultRateLimit = &rateLimit{limit: r, burst: burst}
+		return nil
+	}
+}
+
+// WithMaxConcurrentCalls bounds how many tool calls may run at once across all
+// tools, e.g. to protect a shared downstream connection pool. Calls beyond the limit
+// wait for a free slot until their context is done, unless WithOverloadRejection
+// is enabled. A call that exceeds its timeout holds its slot until the tool
+// function actually returns.
+func WithMaxConcurrentCalls(n int) Option {
+	return func(cfg *handlerConfig) error {
+		if n <= 0 {
+			return fmt.Errorf("%w: %d", ErrInvalidConcurrencyLimit, n)
 		}
+		cfg.maxConcurrentCalls = n
+		return nil
+	}
+}
 
-		cfg.tools = append(cfg.tools, registerFunc)
+// WithOverloadRejection controls whether calls beyond the WithMaxConcurrentCalls
+// limit fail immediately with a tool error coded "OVERLOADED" instead of waiting
+// for a free slot. It has no effect without a concurrency limit.
+func WithOverloadRejection(enabled bool) Option {
+	return func(cfg *handlerConfig) error {
+		cfg.rejectOverload = enabled
+		return nil
+	}
+}
 
+// WithHTTPAuth requires every HTTP and WebSocket request to carry an
+// "Authorization: Bearer <token>" header accepted by validate, which can check JWTs
+// or opaque tokens. Requests without a token, or whose token validate rejects with an
+// error, get a 401 response before reaching the MCP server. The identity validate
+// returns, such as a user ID or claims, is available to tools through
+// IdentityFromContext. Stdio and in-process sessions are not affected.
+func WithHTTPAuth(validate func(token string) (identity any, err error)) Option {
+	return func(cfg *handlerConfig) error {
+		if validate == nil {
+			return ErrNilFunction
+		}
+		cfg.httpAuth = validate
+		return nil
+	}
+}
+
+// WithCORS lets browser-based clients on other origins use the HTTP transport.
+// Preflight OPTIONS requests from allowed origins are answered with 204, and other
+// responses get the Access-Control-* headers browsers check. WebSocket handshakes
+// from other origins are rejected with 403. It returns
+// ErrInvalidCORSOptions if no origins are allowed.
+func WithCORS(opts CORSOptions) Option {
+	return func(cfg *handlerConfig) error {
+		policy, err := newCORSPolicy(opts)
+		if err != nil {
+			return err
+		}
+		cfg.cors = policy
+		return nil
+	}
+}
+
+// WithMaxRequestBytes limits HTTP request bodies to n bytes, so a client can't
+// exhaust memory with an oversized JSON-RPC message. Larger bodies are rejected with
+// 413 before they are parsed, and larger WebSocket messages end the session. The default is 4 MiB; 0 removes the limit.
+func WithMaxRequestBytes(n int64) Option {
+	return func(cfg *handlerConfig) error {
+		if n < 0 {
+			return fmt.Errorf("%w: %d", ErrInvalidByteLimit, n)
+		}
+		cfg.maxRequestBytes = n
+		return nil
+	}
+}
+
+// WithSlowClientPolicy queues the messages sent to each WebSocket session, so a
+// client that reads slowly can't block the server, and applies policy when the
+// queue is full. Streamable HTTP and SSE writes are managed by the MCP SDK and are
+// not affected.
+func WithSlowClientPolicy(policy SlowClientPolicy) Option {
+	return func(cfg *handlerConfig) error {
+		if err := policy.validate(); err != nil {
+			return err
+		}
+		cfg.slowClientPolicy = &policy
+		return nil
+	}
+}
+
+// WithPayloadCodec makes raw tool functions receive and return payloads encoded
+// with codec instead of JSON. Arguments are still validated as JSON against the
+// tool's input schema, and results reach clients as JSON. Each raw tool advertises
+// the codec's content type under payloadContentType in its _meta.
+func WithPayloadCodec(codec Codec) Option {
+	return func(cfg *handlerConfig) error {
+		if codec == nil {
+			return ErrNilCodec
+		}
+		cfg.codec = codec
+		return nil
+	}
+}
+
+// WithCleanup registers a function that runs when the handler is closed, e.g. to
+// flush metrics or stop a script engine. Cleanups run once, in reverse order of
+// registration.
+func WithCleanup(fn func() error) Option {
+	return func(cfg *handlerConfig) error {
+		if fn == nil {
+			return ErrNilFunction
+		}
+		cfg.cleanups = append(cfg.cleanups, fn)
+		return nil
+	}
+}
+
+// WithServerOptions passes SDK-level settings such as KeepAlive or PageSize to the
+// server the handler creates. WithInstructions, when also given, takes precedence
+// over opts.Instructions. It can't be combined with WithServer.
+func WithServerOptions(opts *mcp.ServerOptions) Option {
+	return func(cfg *handlerConfig) error {
+		if opts == nil {
+			return ErrNilServerOptions
+		}
+		cfg.serverOptions = opts
 		return nil
 	}
 }
@@ -0,0 +1,28 @@
+package mcpio
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Progress reports the progress of a long-running tool call to the client, e.g.
+// Progress(ctx, 50, 100, "halfway"). Progress should increase with every call;
+// pass a total of zero when it isn't known. It does nothing if the client didn't
+// ask for progress by sending a progress token, or when called outside a tool call.
+func Progress(ctx context.Context, progress, total float64, message string) error {
+	req, ok := RequestFromContext(ctx)
+	if !ok || req.Params == nil || req.Session == nil {
+		return nil
+	}
+	token := req.Params.GetProgressToken()
+	if token == nil {
+		return nil
+	}
+	return req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+		ProgressToken: token,
+		Progress:      progress,
+		Total:         total,
+		Message:       message,
+	})
+}
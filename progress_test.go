@@ -0,0 +1,82 @@
+package mcpio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgress(t *testing.T) {
+	longTask := func(ctx context.Context, input struct{}) (EchoOutput, error) {
+		if err := Progress(ctx, 50, 100, "halfway"); err != nil {
+			return EchoOutput{}, err
+		}
+		if err := Progress(ctx, 100, 100, "done"); err != nil {
+			return EchoOutput{}, err
+		}
+		return EchoOutput{Message: "finished"}, nil
+	}
+
+	handler, err := NewHandler(WithTool("long_task", "Run a long task", longTask))
+	require.NoError(t, err)
+
+	notifications := make(chan *mcp.ProgressNotificationParams, 10)
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	_, err = handler.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+			notifications <- req.Params
+		},
+	})
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, session.Close())
+	})
+
+	t.Run("notifications sent for a progress token", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name: "long_task",
+			Meta: mcp.Meta{"progressToken": "task-1"},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError, resultText(t, result))
+
+		want := []struct {
+			progress float64
+			message  string
+		}{{50, "halfway"}, {100, "done"}}
+		for _, w := range want {
+			select {
+			case params := <-notifications:
+				assert.Equal(t, "task-1", params.ProgressToken)
+				assert.InDelta(t, w.progress, params.Progress, 0)
+				assert.InDelta(t, 100, params.Total, 0)
+				assert.Equal(t, w.message, params.Message)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("progress notification %v was not received", w.progress)
+			}
+		}
+	})
+
+	t.Run("no-op without a progress token", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "long_task"})
+		require.NoError(t, err)
+		assert.False(t, result.IsError, resultText(t, result))
+
+		select {
+		case params := <-notifications:
+			t.Fatalf("unexpected progress notification: %+v", params)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("outside a tool call", func(t *testing.T) {
+		assert.NoError(t, Progress(context.Background(), 1, 2, "ignored"))
+	})
+}
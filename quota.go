@@ -0,0 +1,46 @@
+package mcpio
+
+import (
+	"context"
+	"fmt"
+)
+
+// QuotaStore decides whether a client may call a tool. Implementations can be backed
+// by Redis or a database so quotas are shared across server instances, and must be
+// safe for concurrent use.
+type QuotaStore interface {
+	// Allow reports whether clientID may make another call to toolName, consuming
+	// quota if so. An error fails the call with a protocol error.
+	Allow(ctx context.Context, clientID, toolName string) (bool, error)
+}
+
+// checkQuota consults the quota store for a call to the named tool by clientID,
+// returning a RATE_LIMITED tool error when the client is over quota
+func checkQuota(ctx context.Context, store QuotaStore, clientID, name string) error {
+	allowed, err := store.Allow(ctx, clientID, name)
+	if err != nil {
+		return fmt.Errorf("checking quota for tool %q: %w", name, err)
+	}
+	if !allowed {
+		return NewToolErrorWithCode(fmt.Sprintf("quota exceeded for tool %q", name), "RATE_LIMITED")
+	}
+	return nil
+}
+
+// SessionClientID identifies the client making a tool call by its session: the
+// session ID when the transport has one (e.g. streamable HTTP), otherwise the client
+// name sent on initialize. It is the default for WithClientIdentifier. A client
+// that opens a new session gets a new ID.
+func SessionClientID(ctx context.Context) string {
+	req, _ := RequestFromContext(ctx)
+	if req == nil || req.Session == nil {
+		return ""
+	}
+	if id := req.Session.ID(); id != "" {
+		return id
+	}
+	if params := req.Session.InitializeParams(); params != nil && params.ClientInfo != nil {
+		return params.ClientInfo.Name
+	}
+	return ""
+}
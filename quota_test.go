@@ -0,0 +1,160 @@
+package mcpio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryQuotaStore allows a fixed number of calls per client and tool
+type memoryQuotaStore struct {
+	mu    sync.Mutex
+	limit int
+	used  map[string]int
+	err   error
+}
+
+func newMemoryQuotaStore(limit int) *memoryQuotaStore {
+	return &memoryQuotaStore{limit: limit, used: make(map[string]int)}
+}
+
+func (s *memoryQuotaStore) Allow(ctx context.Context, clientID, toolName string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return false, s.err
+	}
+	key := clientID + "/" + toolName
+	if s.used[key] >= s.limit {
+		return false, nil
+	}
+	s.used[key]++
+	return true, nil
+}
+
+func TestWithQuota(t *testing.T) {
+	callEcho := func(t *testing.T, session *mcp.ClientSession) *mcp.CallToolResult {
+		t.Helper()
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "echo",
+			Arguments: map[string]any{"text": "hi"},
+		})
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("denies after limit", func(t *testing.T) {
+		store := newMemoryQuotaStore(2)
+		handler, err := NewHandler(
+			WithTool("echo", "Echo input", echoFunc),
+			WithTool("calculate", "Perform arithmetic", calculateFunc),
+			WithQuota(store),
+		)
+		require.NoError(t, err)
+		session := connectTestClient(t, handler)
+
+		assert.False(t, callEcho(t, session).IsError)
+		assert.False(t, callEcho(t, session).IsError)
+
+		denied := callEcho(t, session)
+		assert.True(t, denied.IsError)
+		assert.Equal(t, `quota exceeded for tool "echo"`, resultText(t, denied))
+		assert.Equal(t, "RATE_LIMITED", denied.Meta["errorCode"])
+
+		// Quotas are tracked per tool
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "calculate",
+			Arguments: map[string]any{"operation": "add", "a": 1, "b": 2},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+		assert.Equal(t, map[string]int{"test-client/echo": 2, "test-client/calculate": 1}, store.used)
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		store := newMemoryQuotaStore(2)
+		store.err = errors.New("redis unavailable")
+		handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc), WithQuota(store))
+		require.NoError(t, err)
+		session := connectTestClient(t, handler)
+
+		result := callEcho(t, session)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "redis unavailable")
+	})
+
+	t.Run("nil store", func(t *testing.T) {
+		_, err := NewHandler(WithQuota(nil))
+		require.ErrorIs(t, err, ErrNilQuotaStore)
+	})
+
+	t.Run("reconnecting client stays limited", func(t *testing.T) {
+		store := newMemoryQuotaStore(1)
+		handler, err := NewHandler(
+			WithTool("echo", "Echo input", echoFunc),
+			WithQuota(store),
+			WithHTTPAuth(func(token string) (any, error) { return token, nil }),
+			WithClientIdentifier(userClientID),
+		)
+		require.NoError(t, err)
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+
+		assert.False(t, callEcho(t, connectAuthenticatedClient(t, server.URL, "alice")).IsError)
+		// A new session for the same user shares the exhausted quota
+		assert.True(t, callEcho(t, connectAuthenticatedClient(t, server.URL, "alice")).IsError)
+		assert.False(t, callEcho(t, connectAuthenticatedClient(t, server.URL, "bob")).IsError)
+		assert.Equal(t, map[string]int{"alice/echo": 1, "bob/echo": 1}, store.used)
+	})
+
+	t.Run("default identifies sessions", func(t *testing.T) {
+		store := newMemoryQuotaStore(1)
+		handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc), WithQuota(store))
+		require.NoError(t, err)
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+
+		assert.False(t, callEcho(t, connectAuthenticatedClient(t, server.URL, "")).IsError)
+		assert.False(t, callEcho(t, connectAuthenticatedClient(t, server.URL, "")).IsError)
+		assert.Len(t, store.used, 2)
+	})
+
+	t.Run("nil identifier", func(t *testing.T) {
+		_, err := NewHandler(WithClientIdentifier(nil))
+		require.ErrorIs(t, err, ErrNilFunction)
+	})
+}
+
+// userClientID identifies clients by their WithHTTPAuth identity, falling back to
+// the session
+func userClientID(ctx context.Context) string {
+	if identity, ok := IdentityFromContext(ctx); ok {
+		return fmt.Sprint(identity)
+	}
+	return SessionClientID(ctx)
+}
+
+// connectAuthenticatedClient opens a streamable HTTP session to serverURL, sending
+// token as a bearer token unless it is empty
+func connectAuthenticatedClient(t *testing.T, serverURL, token string) *mcp.ClientSession {
+	t.Helper()
+	transport := &mcp.StreamableClientTransport{Endpoint: serverURL}
+	if token != "" {
+		transport.HTTPClient = &http.Client{Transport: headerTransport{header: http.Header{"Authorization": {"Bearer " + token}}}}
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+	session, err := client.Connect(context.Background(), transport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, session.Close())
+	})
+	return session
+}
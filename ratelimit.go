@@ -0,0 +1,84 @@
+package mcpio
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// pruneBucketsAt is the number of buckets at which fully refilled ones, which
+// behave exactly like new buckets, are dropped so idle clients don't accumulate
+const pruneBucketsAt = 1024
+
+// rateLimit is a token-bucket limit on calls to a tool
+type rateLimit struct {
+	limit rate.Limit
+	burst int
+}
+
+// bucketKey identifies the bucket for one client's calls to one tool
+type bucketKey struct {
+	clientID string
+	tool     string
+}
+
+// rateLimiter throttles tool calls per client and tool. Tools without a limit of
+// their own use the default limit, if any.
+type rateLimiter struct {
+	limits       map[string]rateLimit
+	defaultLimit *rateLimit
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*rate.Limiter
+}
+
+// newRateLimiter returns a limiter for the configured limits, or nil when no limits
+// are configured
+func newRateLimiter(limits map[string]rateLimit, defaultLimit *rateLimit) *rateLimiter {
+	if len(limits) == 0 && defaultLimit == nil {
+		return nil
+	}
+	return &rateLimiter{
+		limits:       limits,
+		defaultLimit: defaultLimit,
+		buckets:      make(map[bucketKey]*rate.Limiter),
+	}
+}
+
+// check consumes a token from clientID's bucket for the named tool, returning a
+// RATE_LIMITED tool error when the bucket is empty
+func (l *rateLimiter) check(clientID, name string) error {
+	limit, ok := l.limits[name]
+	if !ok {
+		if l.defaultLimit == nil {
+			return nil
+		}
+		limit = *l.defaultLimit
+	}
+
+	if !l.bucket(bucketKey{clientID: clientID, tool: name}, limit).Allow() {
+		return NewToolErrorWithCode(fmt.Sprintf("rate limit exceeded for tool %q", name), "RATE_LIMITED")
+	}
+	return nil
+}
+
+// bucket returns the limiter for key, creating it if needed
+func (l *rateLimiter) bucket(key bucketKey, limit rateLimit) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if bucket, ok := l.buckets[key]; ok {
+		return bucket
+	}
+	if len(l.buckets) >= pruneBucketsAt {
+		for k, bucket := range l.buckets {
+			if bucket.Tokens() >= float64(bucket.Burst()) {
+				delete(l.buckets, k)
+			}
+		}
+	}
+	bucket := rate.NewLimiter(limit.limit, limit.burst)
+	l.buckets[key] = bucket
+	return bucket
+}
@@ -0,0 +1,130 @@
+package mcpio
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	handler, err := NewHandler(
+		WithTool("echo", "Echo input", echoFunc),
+		WithTool("calculate", "Perform arithmetic", calculateFunc),
+		WithRateLimit("echo", rate.Every(50*time.Millisecond), 2),
+		WithDefaultRateLimit(rate.Every(time.Hour), 1),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	call := func(t *testing.T, name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: name, Arguments: args})
+		require.NoError(t, err)
+		return result
+	}
+	echo := map[string]any{"text": "hi"}
+
+	t.Run("per-tool limit", func(t *testing.T) {
+		for range 2 {
+			assert.False(t, call(t, "echo", echo).IsError)
+		}
+
+		result := call(t, "echo", echo)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "RATE_LIMITED", result.Meta["errorCode"])
+		assert.Contains(t, resultText(t, result), "rate limit exceeded")
+
+		// One token is restored every 50ms
+		time.Sleep(60 * time.Millisecond)
+		assert.False(t, call(t, "echo", echo).IsError)
+	})
+
+	t.Run("default limit", func(t *testing.T) {
+		args := map[string]any{"operation": "add", "a": 1, "b": 2}
+		assert.False(t, call(t, "calculate", args).IsError)
+
+		result := call(t, "calculate", args)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "RATE_LIMITED", result.Meta["errorCode"])
+	})
+
+	t.Run("option errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			opt     Option
+			wantErr error
+		}{
+			{"empty tool name", WithRateLimit("", 1, 1), ErrEmptyToolName},
+			{"zero rate", WithRateLimit("echo", 0, 1), ErrInvalidRateLimit},
+			{"zero burst", WithRateLimit("echo", 1, 0), ErrInvalidRateLimit},
+			{"unknown tool", WithRateLimit("missing", 1, 1), ErrToolNotFound},
+			{"zero default rate", WithDefaultRateLimit(0, 1), ErrInvalidRateLimit},
+			{"zero default burst", WithDefaultRateLimit(1, 0), ErrInvalidRateLimit},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := NewHandler(WithTool("echo", "Echo input", echoFunc), tt.opt)
+				require.ErrorIs(t, err, tt.wantErr)
+			})
+		}
+	})
+}
+
+func TestRateLimitClientIdentifier(t *testing.T) {
+	handler, err := NewHandler(
+		WithTool("echo", "Echo input", echoFunc),
+		WithRateLimit("echo", rate.Every(time.Hour), 1),
+		WithHTTPAuth(func(token string) (any, error) { return token, nil }),
+		WithClientIdentifier(userClientID),
+	)
+	require.NoError(t, err)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	call := func(t *testing.T, token string) *mcp.CallToolResult {
+		t.Helper()
+		session := connectAuthenticatedClient(t, server.URL, token)
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "echo",
+			Arguments: map[string]any{"text": "hi"},
+		})
+		require.NoError(t, err)
+		return result
+	}
+
+	assert.False(t, call(t, "alice").IsError)
+	// Reconnecting doesn't refill the user's bucket
+	result := call(t, "alice")
+	assert.True(t, result.IsError)
+	assert.Equal(t, "RATE_LIMITED", result.Meta["errorCode"])
+	assert.False(t, call(t, "bob").IsError)
+}
+
+func TestRateLimiterConcurrent(t *testing.T) {
+	const burst = 5
+	limiter := newRateLimiter(map[string]rateLimit{"echo": {limit: rate.Every(time.Hour), burst: burst}}, nil)
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Go(func() {
+			if limiter.check("client", "echo") == nil {
+				allowed.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(burst), allowed.Load())
+	assert.NoError(t, limiter.check("client", "unlimited"))
+	assert.Nil(t, newRateLimiter(nil, nil))
+}
@@ -0,0 +1,197 @@
+package mcpio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+var (
+	contextType = reflect.TypeFor[context.Context]()
+	errorType   = reflect.TypeFor[error]()
+)
+
+// RegisterTool adds a typed tool to a running handler, e.g. one discovered from a
+// plugin after startup. fn must have the shape of a ToolFunc:
+// func(context.Context, TIn) (TOut, error). Because methods can't be generic, the
+// input and output types are read from fn with reflection. It returns
+// ErrDuplicateTool if a tool with the same name is already registered, and is safe
+// to call while requests are being served.
+func (h *Handler) RegisterTool(name, description string, fn any) error {
+	entry, err := newReflectToolEntry(name, description, fn)
+	if err != nil {
+		return err
+	}
+	return h.addTool(entry)
+}
+
+// RegisterRawTool adds a raw JSON tool to a running handler, with the same checks as
+// WithRawTool. It returns ErrDuplicateTool if a tool with the same name is already
+// registered, and is safe to call while requests are being served.
+func (h *Handler) RegisterRawTool(name, description string, inputSchema *jsonschema.Schema, fn RawToolFunc) error {
+	if fn == nil {
+		return ErrNilFunction
+	}
+
+	// Build the entry exactly as the option would
+	cfg := &handlerConfig{}
+	if err := WithRawTool(name, description, inputSchema, fn)(cfg); err != nil {
+		return err
+	}
+	return h.addTool(cfg.tools[0])
+}
+
+// UnregisterTool removes a tool from a running handler, e.g. when its plugin is
+// unloaded. It no longer appears in tools/list and calls to it fail as calls to an
+// unknown tool. It returns ErrToolNotFound if no tool has the given name, and is safe
+// to call while requests are being served.
+func (h *Handler) UnregisterTool(name string) error {
+	h.registerMu.Lock()
+	defer h.registerMu.Unlock()
+
+	h.mu.Lock()
+	found := h.forgetTool(name)
+	h.mu.Unlock()
+	if !found {
+		return fmt.Errorf("%w: %q", ErrToolNotFound, name)
+	}
+
+	// Removing notifies every session, so it happens without holding mu
+	h.server.RemoveTools(name)
+	return nil
+}
+
+// newReflectToolEntry builds a tool entry for a typed tool function whose input and
+// output types are read with reflection
+func newReflectToolEntry(name, description string, fn any) (*toolEntry, error) {
+	if name == "" {
+		return nil, ErrEmptyToolName
+	}
+	if fn == nil {
+		return nil, ErrNilFunction
+	}
+
+	fnValue := reflect.ValueOf(fn)
+	inType, outType, err := toolFuncTypes(fnValue.Type())
+	if err != nil {
+		return nil, fmt.Errorf("%w: tool %q: %w", ErrInvalidToolFunc, name, err)
+	}
+
+	inputSchema, err := generateInputSchemaFor(inType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: tool %q: %w", ErrInvalidSchema, name, err)
+	}
+	resolved, err := inputSchema.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: tool %q: %w", ErrInvalidSchema, name, err)
+	}
+
+	tool := &mcp.Tool{
+		Name:        name,
+		Description: description,
+		InputSchema: inputSchema,
+	}
+	// Structured output needs an object schema, as with the SDK's typed tools
+	tool.OutputSchema = generateOutputSchemaFor(outType)
+
+	registerFunc := func(server *mcp.Server, tool *mcp.Tool, chain *callChain) {
+		server.AddTool(tool, createReflectHandler(chain, name, fnValue, inType, resolved, tool.OutputSchema != nil))
+	}
+
+	return &toolEntry{tool: tool, register: registerFunc}, nil
+}
+
+// withReflectTool is the option form of newReflectToolEntry
+func withReflectTool(name, description string, fn any) Option {
+	return func(cfg *handlerConfig) error {
+		entry, err := newReflectToolEntry(name, description, fn)
+		if err != nil {
+			return err
+		}
+		cfg.tools = append(cfg.tools, entry)
+		return nil
+	}
+}
+
+// toolFuncTypes checks that fnType is func(context.Context, TIn) (TOut, error) and
+// returns TIn and TOut
+func toolFuncTypes(fnType reflect.Type) (reflect.Type, reflect.Type, error) {
+	if fnType.Kind() != reflect.Func {
+		return nil, nil, fmt.Errorf("got %s, want a function", fnType)
+	}
+	if fnType.NumIn() != 2 || fnType.In(0) != contextType ||
+		fnType.NumOut() != 2 || fnType.Out(1) != errorType {
+		return nil, nil, fmt.Errorf("got %s, want func(context.Context, TIn) (TOut, error)", fnType)
+	}
+	return fnType.In(1), fnType.Out(0), nil
+}
+
+// createReflectHandler adapts a reflected typed tool function to the MCP ToolHandler
+// signature. It validates and decodes the input the way the SDK does for typed
+// tools, and classifies errors the same way as createTypedHandler. When structured
+// is set, the output is also returned as structured content.
+func createReflectHandler(
+	chain *callChain,
+	name string,
+	fnValue reflect.Value,
+	inType reflect.Type,
+	inputSchema *jsonschema.Resolved,
+	structured bool,
+) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		inputJSON := []byte(req.Params.Arguments)
+		if len(inputJSON) == 0 {
+			inputJSON = []byte("null")
+		}
+		if toolErr := chain.validateInput(ctx, name, inputSchema, inputJSON); toolErr != nil {
+			return toolErrorResult(toolErr), nil
+		}
+
+		input := reflect.New(inType)
+		if string(inputJSON) != "null" {
+			if err := json.Unmarshal(inputJSON, input.Interface()); err != nil {
+				return toolErrorResult(ValidationError(fmt.Sprintf("invalid input: %v", err))), nil
+			}
+		}
+
+		output, err := chain.call(withRequest(ctx, req), name, func(ctx context.Context) (any, error) {
+			results := fnValue.Call([]reflect.Value{reflect.ValueOf(ctx), input.Elem()})
+			if err, _ := results[1].Interface().(error); err != nil {
+				return nil, err
+			}
+			return results[0].Interface(), nil
+		})
+		if err != nil {
+			var toolErr *ToolError
+			if errors.As(err, &toolErr) {
+				return toolErrorResult(toolErr), nil
+			}
+			var protoErr *ProtocolError
+			if errors.As(err, &protoErr) {
+				return nil, protoErr.wireError()
+			}
+			// Match the SDK's typed tools, which return other errors as error results
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{&mcp.TextContent{Text: err.Error()}},
+				IsError: true,
+			}, nil
+		}
+
+		outputJSON, err := json.Marshal(output)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling output: %w", err)
+		}
+		result := &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: string(outputJSON)}},
+		}
+		if structured && string(outputJSON) != "null" {
+			result.StructuredContent = json.RawMessage(outputJSON)
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,253 @@
+package mcpio
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connectHTTPClient serves the handler over HTTP and connects a client session to it
+func connectHTTPClient(t *testing.T, h *Handler) *mcp.ClientSession {
+	t.Helper()
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+	session, err := client.Connect(context.Background(), &mcp.StreamableClientTransport{Endpoint: server.URL}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, session.Close())
+	})
+	return session
+}
+
+func TestRegisterTool(t *testing.T) {
+	handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc))
+	require.NoError(t, err)
+	session := connectHTTPClient(t, handler)
+
+	require.NoError(t, handler.RegisterTool("calculate", "Perform arithmetic", calculateFunc))
+	require.NoError(t, handler.RegisterRawTool(
+		"process",
+		"Process raw data",
+		CreateObjectSchema("Raw input", map[string]string{"data": "Input data"}, []string{"data"}),
+		rawFunc,
+	))
+
+	names := make([]string, 0)
+	for _, tool := range handler.Tools() {
+		names = append(names, tool.Name)
+	}
+	assert.Equal(t, []string{"echo", "calculate", "process"}, names)
+
+	list, err := session.ListTools(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Len(t, list.Tools, 3)
+
+	t.Run("typed tool", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "calculate",
+			Arguments: map[string]any{"operation": "add", "a": 2, "b": 3},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+		assert.JSONEq(t, `{"result": 5}`, resultText(t, result))
+		assert.NotNil(t, result.StructuredContent)
+	})
+
+	t.Run("typed tool error", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "calculate",
+			Arguments: map[string]any{"operation": "divide", "a": 1, "b": 0},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "division by zero")
+	})
+
+	t.Run("typed tool invalid input", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "calculate",
+			Arguments: map[string]any{"operation": "add", "a": "two", "b": 3},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "invalid input")
+	})
+
+	t.Run("raw tool", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "process",
+			Arguments: map[string]any{"data": "x"},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+		assert.JSONEq(t, `{"result": "processed"}`, resultText(t, result))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			fn      any
+			tool    string
+			wantErr error
+		}{
+			{"duplicate of constructed tool", echoFunc, "echo", ErrDuplicateTool},
+			{"duplicate of registered tool", calculateFunc, "calculate", ErrDuplicateTool},
+			{"empty name", echoFunc, "", ErrEmptyToolName},
+			{"nil function", nil, "nil_fn", ErrNilFunction},
+			{"not a function", "echo", "not_fn", ErrInvalidToolFunc},
+			{"wrong signature", func(input EchoInput) EchoOutput { return EchoOutput{} }, "bad_sig", ErrInvalidToolFunc},
+			{"non-object input", func(ctx context.Context, input string) (string, error) { return input, nil }, "bad_input", ErrInvalidSchema},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := handler.RegisterTool(tt.tool, "desc", tt.fn)
+				require.ErrorIs(t, err, tt.wantErr)
+			})
+		}
+
+		err := handler.RegisterRawTool("process", "desc", CreateObjectSchema("", nil, nil), rawFunc)
+		require.ErrorIs(t, err, ErrDuplicateTool)
+	})
+}
+
+func TestRegisterToolConcurrent(t *testing.T) {
+	handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc))
+	require.NoError(t, err)
+	session := connectHTTPClient(t, handler)
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, handler.RegisterTool(fmt.Sprintf("echo_%d", i), "Echo input", echoFunc))
+		}()
+		go func() {
+			defer wg.Done()
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+				Name:      "echo",
+				Arguments: map[string]any{"text": "hi"},
+			})
+			if assert.NoError(t, err) {
+				assert.False(t, result.IsError)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, handler.Tools(), 11)
+}
+
+func TestRegisterToolSlowSession(t *testing.T) {
+	ctx := context.Background()
+	handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc))
+	require.NoError(t, err)
+
+	// The client end of this session is never read, so once the transport has
+	// buffered the first notification, notifying it again blocks
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	_, err = handler.server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	conn, err := clientTransport.Connect(ctx)
+	require.NoError(t, err)
+	require.NoError(t, handler.RegisterTool("echo_2", "Echo input", echoFunc))
+
+	registered := make(chan error, 1)
+	go func() {
+		registered <- handler.RegisterTool("calculate", "Perform arithmetic", calculateFunc)
+	}()
+
+	// Readers aren't held up while the session is notified
+	require.Eventually(t, func() bool { return len(handler.Tools()) == 3 }, time.Second, time.Millisecond)
+	assert.True(t, handler.Capabilities().Tools)
+	select {
+	case err := <-registered:
+		t.Fatalf("registration finished before the session was notified: %v", err)
+	default:
+	}
+
+	require.NoError(t, conn.Close())
+	require.NoError(t, <-registered)
+}
+
+func TestRegisterToolRollback(t *testing.T) {
+	handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc))
+	require.NoError(t, err)
+
+	entry, err := newReflectToolEntry("calculate", "Perform arithmetic", calculateFunc)
+	require.NoError(t, err)
+	// The SDK panics on tools it can't register
+	entry.tool.InputSchema = &jsonschema.Schema{Type: "string"}
+
+	err = handler.addTool(entry)
+	require.ErrorIs(t, err, ErrToolRegistration)
+	assert.Len(t, handler.Tools(), 1)
+	require.ErrorIs(t, handler.UnregisterTool("calculate"), ErrToolNotFound)
+}
+
+func TestNewHandlerDuplicateTool(t *testing.T) {
+	_, err := NewHandler(
+		WithTool("echo", "Echo input", echoFunc),
+		WithTool("echo", "Echo again", echoFunc),
+	)
+	require.ErrorIs(t, err, ErrDuplicateTool)
+}
+
+func TestUnregisterTool(t *testing.T) {
+	handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc))
+	require.NoError(t, err)
+	session := connectHTTPClient(t, handler)
+
+	listNames := func(t *testing.T) []string {
+		t.Helper()
+		list, err := session.ListTools(context.Background(), nil)
+		require.NoError(t, err)
+		names := make([]string, 0, len(list.Tools))
+		for _, tool := range list.Tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+
+	require.NoError(t, handler.RegisterTool("calculate", "Perform arithmetic", calculateFunc))
+	assert.ElementsMatch(t, []string{"echo", "calculate"}, listNames(t))
+
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "calculate",
+		Arguments: map[string]any{"operation": "add", "a": 1, "b": 2},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+
+	require.NoError(t, handler.UnregisterTool("calculate"))
+	assert.Equal(t, []string{"echo"}, listNames(t))
+	assert.Len(t, handler.Tools(), 1)
+
+	_, err = session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "calculate",
+		Arguments: map[string]any{"operation": "add", "a": 1, "b": 2},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "calculate")
+
+	require.ErrorIs(t, handler.UnregisterTool("calculate"), ErrToolNotFound)
+
+	// The name can be reused once unregistered
+	require.NoError(t, handler.RegisterTool("calculate", "Perform arithmetic", calculateFunc))
+
+	// Removing the last tool clears the tools capability
+	require.NoError(t, handler.UnregisterTool("echo"))
+	require.NoError(t, handler.UnregisterTool("calculate"))
+	assert.False(t, handler.Capabilities().Tools)
+}
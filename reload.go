@@ -0,0 +1,117 @@
+package mcpio
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// schemaFileExt is the extension of the schema files read by ReloadSchemas
+const schemaFileExt = ".json"
+
+// ReloadSchemas replaces the input schemas of raw tools with the schemas in dir, so
+// schema edits take effect without a restart. Each file is named after the tool it
+// describes, e.g. "search.json" for the "search" tool; files without the .json
+// extension are ignored. Every file is read and checked before any tool changes, so
+// a bad file leaves all tools as they were. Connected clients are sent a
+// tools/list_changed notification.
+//
+// It returns ErrToolNotFound or ErrNotRawTool for files naming a tool that doesn't
+// exist or wasn't registered as a raw tool, and ErrInvalidSchema for files that
+// don't hold a valid object schema.
+func (h *Handler) ReloadSchemas(dir string) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading schema directory: %w", err)
+	}
+
+	h.registerMu.Lock()
+	defer h.registerMu.Unlock()
+
+	updated, err := h.reloadedEntries(dir, files)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range updated {
+		// Adding a tool with an existing name replaces it and notifies clients, so
+		// it happens without holding mu
+		if err := h.registerEntry(entry); err != nil {
+			return err
+		}
+		h.mu.Lock()
+		h.entries[entry.tool.Name] = entry
+		index := slices.IndexFunc(h.tools, func(info ToolInfo) bool { return info.Name == entry.tool.Name })
+		h.tools[index].InputSchema = entry.tool.InputSchema
+		h.mu.Unlock()
+	}
+	return nil
+}
+
+// reloadedEntries builds an updated entry for every schema file in dir, so nothing
+// changes if any file is bad
+func (h *Handler) reloadedEntries(dir string, files []os.DirEntry) ([]*toolEntry, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	updated := make([]*toolEntry, 0, len(files))
+	for _, file := range files {
+		name, ok := strings.CutSuffix(file.Name(), schemaFileExt)
+		if !ok || file.IsDir() {
+			continue
+		}
+		entry, err := h.reloadedEntry(name, filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		updated = append(updated, entry)
+	}
+	return updated, nil
+}
+
+// reloadedEntry builds a copy of the named raw tool's entry that uses the schema in
+// path. The caller must hold h.mu for reading.
+func (h *Handler) reloadedEntry(name, path string) (*toolEntry, error) {
+	current := h.entries[name]
+	if current == nil {
+		return nil, fmt.Errorf("%w: %q (from %s)", ErrToolNotFound, name, path)
+	}
+	if current.rawFunc == nil {
+		return nil, fmt.Errorf("%w: %q (from %s)", ErrNotRawTool, name, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema for tool %q: %w", name, err)
+	}
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("%w: tool %q: %s: %w", ErrInvalidSchema, name, path, err)
+	}
+
+	// Build the entry as WithRawTool would, which checks the schema, then carry
+	// over everything else about the tool, e.g. annotations and output schema
+	cfg := &handlerConfig{}
+	if err := WithRawTool(name, current.tool.Description, &schema, current.rawFunc)(cfg); err != nil {
+		return nil, err
+	}
+	entry := cfg.tools[0]
+	// The live tool may be read by the server at any time, so the copy gets its
+	// own metadata and annotations for registration to change
+	tool := *current.tool
+	tool.Meta = maps.Clone(current.tool.Meta)
+	if current.tool.Annotations != nil {
+		annotations := *current.tool.Annotations
+		tool.Annotations = &annotations
+	}
+	tool.InputSchema = entry.tool.InputSchema
+	entry.tool = &tool
+	entry.tags = current.tags
+	return entry, nil
+}
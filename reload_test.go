@@ -0,0 +1,160 @@
+package mcpio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadSchemas(t *testing.T) {
+	dir := t.TempDir()
+	writeSchema := func(t *testing.T, name, schema string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(schema), 0o600))
+	}
+
+	handler, err := NewHandler(
+		WithRawTool("search", "Search records", CreateObjectSchema("Search input", map[string]string{"query": "Search terms"}, []string{"query"}), rawFunc),
+		WithToolAnnotations("search", ToolAnnotations{ReadOnlyHint: true}),
+		WithToolTags("search", "data"),
+		WithTool("echo", "Echo input", echoFunc),
+	)
+	require.NoError(t, err)
+
+	listChanged := make(chan struct{}, 10)
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	_, err = handler.server.Connect(context.Background(), serverTransport, nil)
+	require.NoError(t, err)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, &mcp.ClientOptions{
+		ToolListChangedHandler: func(context.Context, *mcp.ToolListChangedRequest) {
+			listChanged <- struct{}{}
+		},
+	})
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, session.Close())
+	})
+
+	callSearch := func(t *testing.T, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "search", Arguments: args})
+		require.NoError(t, err)
+		return result
+	}
+	assert.False(t, callSearch(t, map[string]any{"query": "go"}).IsError)
+
+	t.Run("reload picks up a changed schema", func(t *testing.T) {
+		writeSchema(t, "search.json", `{
+			"type": "object",
+			"properties": {"term": {"type": "string"}, "limit": {"type": "integer"}},
+			"required": ["term"]
+		}`)
+		writeSchema(t, "README.txt", "not a schema")
+		require.NoError(t, handler.ReloadSchemas(dir))
+
+		select {
+		case <-listChanged:
+		case <-time.After(5 * time.Second):
+			t.Fatal("tools/list_changed was not sent")
+		}
+
+		schema, err := handler.InputSchema("search")
+		require.NoError(t, err)
+		assert.Contains(t, schema.Properties, "term")
+		assert.Equal(t, []string{"data"}, handler.ToolsByTag("data")[0].Tags)
+
+		list, err := session.ListTools(context.Background(), nil)
+		require.NoError(t, err)
+		var search *mcp.Tool
+		for _, tool := range list.Tools {
+			if tool.Name == "search" {
+				search = tool
+			}
+		}
+		require.NotNil(t, search)
+		assert.Contains(t, search.InputSchema.Properties, "term")
+		require.NotNil(t, search.Annotations)
+		assert.True(t, search.Annotations.ReadOnlyHint)
+
+		assert.False(t, callSearch(t, map[string]any{"term": "go", "limit": 5}).IsError)
+		assert.True(t, callSearch(t, map[string]any{"query": "go"}).IsError)
+	})
+
+	t.Run("bad files leave tools unchanged", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			file    string
+			content string
+			wantErr error
+		}{
+			{"invalid JSON", "search.json", `{"type": `, ErrInvalidSchema},
+			{"non-object schema", "search.json", `{"type": "string"}`, ErrInvalidSchema},
+			{"unknown tool", "missing.json", `{"type": "object"}`, ErrToolNotFound},
+			{"typed tool", "echo.json", `{"type": "object"}`, ErrNotRawTool},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				dir := t.TempDir()
+				require.NoError(t, os.WriteFile(filepath.Join(dir, tt.file), []byte(tt.content), 0o600))
+				require.ErrorIs(t, handler.ReloadSchemas(dir), tt.wantErr)
+
+				schema, err := handler.InputSchema("search")
+				require.NoError(t, err)
+				assert.Contains(t, schema.Properties, "term")
+			})
+		}
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		require.ErrorIs(t, handler.ReloadSchemas(filepath.Join(dir, "missing")), os.ErrNotExist)
+	})
+}
+
+func TestReloadSchemasWhileListing(t *testing.T) {
+	dir := t.TempDir()
+	schema := `{"type": "object", "properties": {"term": {"type": "string"}}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "search.json"), []byte(schema), 0o600))
+
+	// The codec makes registration write the tool's _meta, which tools/list reads
+	handler, err := NewHandler(
+		WithRawTool("search", "Search records", CreateObjectSchema("Search input", map[string]string{"query": "Search terms"}, nil), rawFunc),
+		WithToolAnnotations("search", ToolAnnotations{ReadOnlyHint: true}),
+		WithPayloadCodec(msgpackCodec{}),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	done := make(chan struct{})
+	listed := make(chan struct{})
+	go func() {
+		defer close(listed)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			_, err := session.ListTools(context.Background(), nil)
+			assert.NoError(t, err)
+		}
+	}()
+
+	for range 200 {
+		require.NoError(t, handler.ReloadSchemas(dir))
+	}
+	close(done)
+	<-listed
+
+	list, err := session.ListTools(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, list.Tools, 1)
+	assert.Contains(t, list.Tools[0].InputSchema.Properties, "term")
+	assert.Equal(t, msgpackCodec{}.ContentType(), list.Tools[0].Meta[payloadContentTypeMetaKey])
+}
@@ -0,0 +1,145 @@
+package mcpio
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxStoredResults bounds how many large results are kept; the oldest is removed
+// when a new one is stored past the limit
+const maxStoredResults = 100
+
+// storedResultURIPrefix begins the URI of every stored result
+const storedResultURIPrefix = "mcpio://results/"
+
+// storedResult is a large result kept for the session whose call produced it
+type storedResult struct {
+	session  mcp.Session
+	contents string
+	mimeType string
+}
+
+// resultStore keeps large tool results for their sessions to read as resources.
+// Results aren't registered on the server, so they are neither listed to nor
+// readable by other sessions. It is safe for concurrent use.
+type resultStore struct {
+	mu      sync.Mutex
+	results map[string]storedResult
+	uris    []string // Stored URIs, oldest first
+}
+
+func newResultStore() *resultStore {
+	return &resultStore{results: make(map[string]storedResult)}
+}
+
+// store keeps contents for session and returns a link to them. The URI ends in a
+// random token, so stored results can't be found by guessing.
+func (s *resultStore) store(session mcp.Session, toolName, contents, mimeType string) (*mcp.ResourceLink, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("generating result ID: %w", err)
+	}
+	uri := storedResultURIPrefix + url.PathEscape(toolName) + "/" + hex.EncodeToString(token)
+
+	s.mu.Lock()
+	s.results[uri] = storedResult{session: session, contents: contents, mimeType: mimeType}
+	s.uris = append(s.uris, uri)
+	if len(s.uris) > maxStoredResults {
+		for _, evicted := range s.uris[:len(s.uris)-maxStoredResults] {
+			delete(s.results, evicted)
+		}
+		s.uris = s.uris[len(s.uris)-maxStoredResults:]
+	}
+	s.mu.Unlock()
+
+	size := int64(len(contents))
+	return &mcp.ResourceLink{
+		URI:      uri,
+		Name:     toolName + " result",
+		MIMEType: mimeType,
+		Size:     &size,
+	}, nil
+}
+
+// read returns the stored result at uri if session stored it. Results stored by
+// other sessions are reported as not found, so their existence isn't revealed.
+func (s *resultStore) read(session mcp.Session, uri string) (*mcp.ReadResourceResult, error) {
+	s.mu.Lock()
+	result, ok := s.results[uri]
+	s.mu.Unlock()
+	if !ok || result.session != session {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: result.mimeType, Text: result.contents}},
+	}, nil
+}
+
+// largeResultMiddleware replaces successful tool results whose text exceeds the
+// tool's threshold with a resource_link to a stored copy of the text, and serves
+// resources/read requests for stored copies
+func largeResultMiddleware(thresholds map[string]int, store *resultStore) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if read, ok := req.(*mcp.ReadResourceRequest); ok && read.Params != nil &&
+				strings.HasPrefix(read.Params.URI, storedResultURIPrefix) {
+				return store.read(req.GetSession(), read.Params.URI)
+			}
+
+			result, err := next(ctx, method, req)
+
+			call, ok := req.(*mcp.CallToolRequest)
+			if !ok || call.Params == nil {
+				return result, err
+			}
+			threshold, ok := thresholds[call.Params.Name]
+			if !ok {
+				return result, err
+			}
+			res, ok := result.(*mcp.CallToolResult)
+			if !ok || res == nil || res.IsError {
+				return result, err
+			}
+
+			text, ok := resultTextContent(res)
+			if !ok || len(text) <= threshold {
+				return result, err
+			}
+
+			mimeType := "text/plain"
+			if res.StructuredContent != nil {
+				mimeType = "application/json"
+			}
+			link, storeErr := store.store(req.GetSession(), call.Params.Name, text, mimeType)
+			if storeErr != nil {
+				// The result is still valid, so send it inline
+				return result, err
+			}
+			// Structured content is kept: tools with an output schema must return it,
+			// and clients validate it against the schema
+			res.Content = []mcp.Content{link}
+			return res, err
+		}
+	}
+}
+
+// resultTextContent joins the text of a result made up only of text content.
+// It reports false if the result has any other kind of content.
+func resultTextContent(res *mcp.CallToolResult) (string, bool) {
+	var b strings.Builder
+	for _, content := range res.Content {
+		text, ok := content.(*mcp.TextContent)
+		if !ok {
+			return "", false
+		}
+		b.WriteString(text.Text)
+	}
+	return b.String(), true
+}
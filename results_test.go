@@ -0,0 +1,122 @@
+package mcpio
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLargeResultAsResource(t *testing.T) {
+	handler, err := NewHandler(
+		WithTool("echo", "Echo input", echoFunc),
+		WithLargeResultAsResource("echo", 100),
+	)
+	require.NoError(t, err)
+	assert.True(t, handler.Capabilities().Resources)
+	session := connectTestClient(t, handler)
+
+	t.Run("below threshold is inline", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "echo",
+			Arguments: map[string]any{"text": "short"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, `{"message":"short"}`, resultText(t, result))
+		assert.NotNil(t, result.StructuredContent)
+	})
+
+	t.Run("above threshold is a resource link", func(t *testing.T) {
+		long := strings.Repeat("x", 200)
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "echo",
+			Arguments: map[string]any{"text": long},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+		// echo declares an output schema, so its structured content must stay
+		assert.Equal(t, map[string]any{"message": long}, result.StructuredContent)
+
+		require.Len(t, result.Content, 1)
+		link, ok := result.Content[0].(*mcp.ResourceLink)
+		require.True(t, ok, "expected resource link, got %T", result.Content[0])
+		assert.True(t, strings.HasPrefix(link.URI, "mcpio://results/echo/"))
+		assert.Equal(t, "application/json", link.MIMEType)
+
+		want := fmt.Sprintf(`{"message":%q}`, long)
+		require.NotNil(t, link.Size)
+		assert.Equal(t, int64(len(want)), *link.Size)
+
+		read, err := session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: link.URI})
+		require.NoError(t, err)
+		require.Len(t, read.Contents, 1)
+		assert.Equal(t, want, read.Contents[0].Text)
+	})
+
+	t.Run("other sessions can't read the result", func(t *testing.T) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "echo",
+			Arguments: map[string]any{"text": strings.Repeat("secret", 50)},
+		})
+		require.NoError(t, err)
+		link, ok := result.Content[0].(*mcp.ResourceLink)
+		require.True(t, ok)
+
+		other := connectTestClient(t, handler)
+		_, err = other.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: link.URI})
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "secret")
+
+		list, err := other.ListResources(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Empty(t, list.Resources)
+
+		_, err = session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: link.URI})
+		require.NoError(t, err)
+	})
+
+	t.Run("option errors", func(t *testing.T) {
+		_, err := NewHandler(WithLargeResultAsResource("", 100))
+		require.ErrorIs(t, err, ErrEmptyToolName)
+
+		_, err = NewHandler(WithTool("echo", "Echo input", echoFunc), WithLargeResultAsResource("echo", 0))
+		require.ErrorIs(t, err, ErrInvalidThreshold)
+
+		_, err = NewHandler(WithLargeResultAsResource("missing", 100))
+		require.ErrorIs(t, err, ErrToolNotFound)
+	})
+}
+
+func TestResultStoreEviction(t *testing.T) {
+	store := newResultStore()
+
+	var first *mcp.ResourceLink
+	for i := range maxStoredResults + 1 {
+		link, err := store.store(nil, "tool", fmt.Sprintf("result %d", i), "text/plain")
+		require.NoError(t, err)
+		if i == 0 {
+			first = link
+		}
+	}
+	assert.Len(t, store.uris, maxStoredResults)
+	assert.Len(t, store.results, maxStoredResults)
+	assert.NotContains(t, store.uris, first.URI)
+	_, err := store.read(nil, first.URI)
+	require.Error(t, err)
+}
+
+func TestResultStoreIDs(t *testing.T) {
+	store := newResultStore()
+	first, err := store.store(nil, "tool", "a", "text/plain")
+	require.NoError(t, err)
+	second, err := store.store(nil, "tool", "b", "text/plain")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, first.URI, second.URI)
+	token := strings.TrimPrefix(first.URI, "mcpio://results/tool/")
+	assert.Len(t, token, 32, "IDs are random 128-bit tokens, not counters")
+}
@@ -1,59 +1,638 @@
 package mcpio
 
 import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
 	"github.com/google/jsonschema-go/jsonschema"
 )
 
-// GenerateSchema is a thin wrapper around jsonschema.For[T]() for convenience
+// GenerateSchema is a thin wrapper around jsonschema.For[T]() for convenience.
+// Like typed tool schemas, it honors the ",required" and ",readOnly" jsonschema tag
+// markers; readOnly fields are kept and annotated.
 func GenerateSchema[T any]() (*jsonschema.Schema, error) {
-	return jsonschema.For[T](nil)
+	schema, err := jsonschema.For[T](nil)
+	if err != nil {
+		return nil, err
+	}
+	applyTagMarkers(reflect.TypeFor[T](), schema, false)
+	return schema, nil
+}
+
+// GenerateSchemaFor is GenerateSchema for a type known only at runtime, such as the
+// input of a handler held as an any. v may be a value, a pointer to one, or a
+// reflect.Type; pointers are dereferenced, so a *T produces T's schema.
+func GenerateSchemaFor(v any) (*jsonschema.Schema, error) {
+	rt, ok := v.(reflect.Type)
+	if !ok {
+		rt = reflect.TypeOf(v)
+	}
+	if rt == nil {
+		return nil, fmt.Errorf("%w: cannot generate a schema for nil", ErrInvalidSchema)
+	}
+	rt = derefType(rt)
+
+	schema, err := jsonschema.ForType(rt, &jsonschema.ForOptions{})
+	if err != nil {
+		return nil, err
+	}
+	applyTagMarkers(rt, schema, false)
+	return schema, nil
+}
+
+// Tag markers are suffixes of a jsonschema struct tag that set schema keywords
+// instead of being part of the description, e.g. `jsonschema:"User name,required"`.
+// A tag may also consist of a marker alone, e.g. `jsonschema:"readOnly"`.
+const (
+	requiredTagMarker = "required"
+	readOnlyTagMarker = "readOnly"
+)
+
+// parseSchemaTag splits a jsonschema struct tag into its description and markers
+func parseSchemaTag(tag string) (description string, required, readOnly bool) {
+	for {
+		switch {
+		case tag == requiredTagMarker:
+			return "", true, readOnly
+		case tag == readOnlyTagMarker:
+			return "", required, true
+		}
+		if rest, ok := strings.CutSuffix(tag, ","+requiredTagMarker); ok {
+			tag, required = rest, true
+			continue
+		}
+		if rest, ok := strings.CutSuffix(tag, ","+readOnlyTagMarker); ok {
+			tag, readOnly = rest, true
+			continue
+		}
+		return tag, required, readOnly
+	}
+}
+
+// applyTagMarkers honors the markers in jsonschema struct tags. Markers are
+// stripped from property descriptions. If any field of a struct is marked
+// required, that struct's required list becomes exactly the marked fields; structs
+// without markers keep the default omitempty-based list. Fields marked readOnly are
+// output-only: they are removed from input schemas and annotated as readOnly
+// otherwise.
+func applyTagMarkers(rt reflect.Type, schema *jsonschema.Schema, input bool) {
+	if schema == nil {
+		return
+	}
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+
+	switch rt.Kind() {
+	case reflect.Slice, reflect.Array:
+		applyTagMarkers(rt.Elem(), schema.Items, input)
+	case reflect.Map:
+		applyTagMarkers(rt.Elem(), schema.AdditionalProperties, input)
+	case reflect.Struct:
+		var required, readOnly []string
+		for i := range rt.NumField() {
+			field := rt.Field(i)
+			if !field.IsExported() || field.Tag.Get("json") == "-" {
+				continue
+			}
+			name := jsonFieldName(field)
+			prop, ok := schema.Properties[name]
+			if !ok {
+				continue
+			}
+			if tag, ok := field.Tag.Lookup("jsonschema"); ok {
+				description, isRequired, isReadOnly := parseSchemaTag(tag)
+				if isRequired || isReadOnly {
+					prop.Description = description
+				}
+				if isRequired {
+					required = append(required, name)
+				}
+				if isReadOnly {
+					readOnly = append(readOnly, name)
+					prop.ReadOnly = true
+				}
+			}
+			applyTagMarkers(field.Type, prop, input)
+		}
+		if required != nil {
+			schema.Required = required
+		}
+		if input {
+			for _, name := range readOnly {
+				delete(schema.Properties, name)
+				schema.Required = slices.DeleteFunc(schema.Required, func(r string) bool { return r == name })
+			}
+		}
+	}
+}
+
+// hasReadOnlyTags reports whether rt, or any type nested in it, has a field marked
+// readOnly
+func hasReadOnlyTags(rt reflect.Type) bool {
+	return hasReadOnlyTagsSeen(rt, map[reflect.Type]bool{})
+}
+
+func hasReadOnlyTagsSeen(rt reflect.Type, seen map[reflect.Type]bool) bool {
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	if seen[rt] {
+		return false
+	}
+	seen[rt] = true
+
+	switch rt.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return hasReadOnlyTagsSeen(rt.Elem(), seen)
+	case reflect.Struct:
+		for i := range rt.NumField() {
+			field := rt.Field(i)
+			if !field.IsExported() || field.Tag.Get("json") == "-" {
+				continue
+			}
+			if tag, ok := field.Tag.Lookup("jsonschema"); ok {
+				if _, _, readOnly := parseSchemaTag(tag); readOnly {
+					return true
+				}
+			}
+			if hasReadOnlyTagsSeen(field.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonFieldName returns the JSON property name for a struct field
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// generateInputSchema builds the input schema for a typed tool the same way the SDK
+// would, so it can be inspected and checked before registration instead of panicking
+// inside mcp.AddTool
+func generateInputSchema[T any]() (*jsonschema.Schema, error) {
+	return generateInputSchemaFor(reflect.TypeFor[T]())
+}
+
+// generateInputSchemaFor is generateInputSchema for a type known only at runtime
+func generateInputSchemaFor(rt reflect.Type) (*jsonschema.Schema, error) {
+	if rt == reflect.TypeFor[any]() {
+		// An "any" input accepts any object
+		return &jsonschema.Schema{Type: "object"}, nil
+	}
+	rt = derefType(rt)
+
+	schema, err := jsonschema.ForType(rt, &jsonschema.ForOptions{})
+	if err != nil {
+		return nil, err
+	}
+	applyTagMarkers(rt, schema, true)
+	if schema.Type != "object" {
+		return nil, fmt.Errorf("input schema must have type \"object\", got %q", schema.Type)
+	}
+	return schema, nil
+}
+
+// generateOutputSchemaFor builds the output schema for a typed tool's output type,
+// keeping readOnly fields. It returns nil if the type doesn't produce an object
+// schema, since structured output must be an object.
+func generateOutputSchemaFor(rt reflect.Type) *jsonschema.Schema {
+	rt = derefType(rt)
+	schema, err := jsonschema.ForType(rt, &jsonschema.ForOptions{})
+	if err != nil || schema.Type != "object" {
+		return nil
+	}
+	applyTagMarkers(rt, schema, false)
+	return schema
+}
+
+// derefType returns the element type of a pointer type, or rt itself
+func derefType(rt reflect.Type) reflect.Type {
+	if rt.Kind() == reflect.Pointer {
+		return rt.Elem()
+	}
+	return rt
+}
+
+// inferSchemaFromSample builds a schema describing a sample value. Structs (and
+// pointers to structs) use type reflection so their tags are honored; any other
+// value is inferred from its JSON form, which captures the keys of map samples.
+func inferSchemaFromSample(sample any) (*jsonschema.Schema, error) {
+	rt := reflect.TypeOf(sample)
+	if rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	if rt.Kind() == reflect.Struct {
+		schema, err := jsonschema.ForType(rt, &jsonschema.ForOptions{})
+		if err != nil {
+			return nil, err
+		}
+		applyTagMarkers(rt, schema, false)
+		return schema, nil
+	}
+
+	data, err := json.Marshal(sample)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return inferSchemaFromJSON(value), nil
+}
+
+// inferSchemaFromJSON builds a schema from a decoded JSON value. Array items are
+// inferred from the first element, and every object key found in the sample is
+// treated as required.
+func inferSchemaFromJSON(value any) *jsonschema.Schema {
+	switch v := value.(type) {
+	case map[string]any:
+		schema := &jsonschema.Schema{
+			Type:       "object",
+			Properties: make(map[string]*jsonschema.Schema, len(v)),
+		}
+		for key, child := range v {
+			schema.Properties[key] = inferSchemaFromJSON(child)
+			schema.Required = append(schema.Required, key)
+		}
+		slices.Sort(schema.Required)
+		return schema
+	case []any:
+		schema := &jsonschema.Schema{Type: "array"}
+		if len(v) > 0 {
+			schema.Items = inferSchemaFromJSON(v[0])
+		}
+		return schema
+	case string:
+		return &jsonschema.Schema{Type: "string"}
+	case float64:
+		return &jsonschema.Schema{Type: "number"}
+	case bool:
+		return &jsonschema.Schema{Type: "boolean"}
+	default:
+		return &jsonschema.Schema{Type: "null"}
+	}
 }
 
 // FieldDef defines a field for dynamic schema construction
 type FieldDef struct {
 	Name        string
-	Type        string // "string", "number", "boolean", "object", "array"
+	Type        string // "string", "number", "integer", "boolean", "object", "array"
 	Description string
 	Required    bool
-	Enum        []string // Optional enum values
+	Enum        []string   // Optional enum values
+	Const       any        // Optional fixed value the field must equal (e.g. a discriminator)
+	Items       *FieldDef  // Element definition for "array" fields; Name and Required are ignored
+	Properties  []FieldDef // Child fields for "object" fields
+	Nullable    bool       // Accept an explicit null in addition to Type
+
+	// AdditionalProperties defines the values of keys not listed in Properties for
+	// "object" fields, making the field a map; Name and Required are ignored
+	AdditionalProperties *FieldDef
+
+	// Optional constraints; nil pointers leave the keyword unset so zero is a valid bound
+	Minimum   *float64 // Inclusive lower bound for "number" and "integer" fields
+	Maximum   *float64 // Inclusive upper bound for "number" and "integer" fields
+	MinLength *int     // Minimum length for "string" fields
+	MaxLength *int     // Maximum length for "string" fields
+	Pattern   string   // Regular expression "string" fields must match
+	Format    string   // Format hint for "string" fields, e.g. "email", "uri", "uuid"
+
+	MinProperties *int // Minimum number of properties for "object" fields
+	MaxProperties *int // Maximum number of properties for "object" fields
+
+	UniqueItems bool // Require all elements of "array" fields to be distinct
+
+	Not *jsonschema.Schema // Optional schema the field's value must not match
 }
 
 // CreateDynamicSchema constructs a JSON schema from field definitions
 // This is useful for runtime-determined schemas (e.g., from Lua script inspection)
 func CreateDynamicSchema(fields []FieldDef) *jsonschema.Schema {
-	properties := make(map[string]*jsonschema.Schema)
-	var required []string
+	properties, required := fieldProperties(fields)
 
+	return &jsonschema.Schema{
+		Type:       "object",
+		Properties: properties,
+		Required:   required,
+	}
+}
+
+// CreateDynamicSchemaValidated is CreateDynamicSchema for field definitions that
+// may contain mistakes, such as ones read from configuration. It returns an error
+// wrapping ErrInvalidSchema, naming the field, when a field or any nested
+// definition has a type JSON Schema doesn't define. An empty type leaves the field
+// untyped.
+func CreateDynamicSchemaValidated(fields []FieldDef) (*jsonschema.Schema, error) {
+	if err := validateFieldTypes(fields, ""); err != nil {
+		return nil, err
+	}
+	return CreateDynamicSchema(fields), nil
+}
+
+// jsonSchemaTypes are the type names JSON Schema defines
+var jsonSchemaTypes = map[string]bool{
+	"string": true, "number": true, "integer": true, "boolean": true,
+	"object": true, "array": true, "null": true,
+}
+
+// validateFieldTypes checks the type of each field and its nested definitions.
+// Errors name the field by its path, e.g. "address.street", "tags[]" for array
+// elements, or "labels.*" for map values.
+func validateFieldTypes(fields []FieldDef, prefix string) error {
 	for _, field := range fields {
-		schema := &jsonschema.Schema{
-			Type:        field.Type,
-			Description: field.Description,
+		if err := validateFieldType(field, prefix+field.Name); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		if len(field.Enum) > 0 {
-			enum := make([]any, len(field.Enum))
-			for i, v := range field.Enum {
-				enum[i] = v
-			}
-			schema.Enum = enum
+// validateFieldType checks one field definition, identified by path
+func validateFieldType(field FieldDef, path string) error {
+	if field.Type != "" && !jsonSchemaTypes[field.Type] {
+		return fmt.Errorf("%w: field %q has unknown type %q", ErrInvalidSchema, path, field.Type)
+	}
+	if field.Items != nil {
+		if err := validateFieldType(*field.Items, path+"[]"); err != nil {
+			return err
+		}
+	}
+	if field.AdditionalProperties != nil {
+		if err := validateFieldType(*field.AdditionalProperties, path+".*"); err != nil {
+			return err
 		}
+	}
+	return validateFieldTypes(field.Properties, path+".")
+}
 
-		properties[field.Name] = schema
+// CreateDynamicSchemaWithDependencies constructs a JSON schema from field definitions
+// with dependentRequired constraints: when the key field is present in the input,
+// every field listed for it must also be present
+func CreateDynamicSchemaWithDependencies(fields []FieldDef, dependentRequired map[string][]string) *jsonschema.Schema {
+	schema := CreateDynamicSchema(fields)
+	if len(dependentRequired) > 0 {
+		schema.DependentRequired = dependentRequired
+	}
+	return schema
+}
+
+// Conditional is a JSON Schema if/then/else rule: input matching If must also match
+// Then, and input that doesn't must match Else. Then and Else are optional.
+type Conditional struct {
+	If   *jsonschema.Schema
+	Then *jsonschema.Schema
+	Else *jsonschema.Schema
+}
+
+// CreateConditionalSchema returns a copy of schema with conditional rules added, e.g.
+// requiring a field only when another field has a given value. A single rule is set
+// with the if/then/else keywords; several are combined with allOf. A nil schema is
+// treated as an empty one.
+func CreateConditionalSchema(schema *jsonschema.Schema, conditions ...Conditional) *jsonschema.Schema {
+	var result jsonschema.Schema
+	if schema != nil {
+		result = *schema
+	}
+	switch len(conditions) {
+	case 0:
+	case 1:
+		result.If, result.Then, result.Else = conditions[0].If, conditions[0].Then, conditions[0].Else
+	default:
+		result.AllOf = slices.Clone(result.AllOf)
+		for _, condition := range conditions {
+			result.AllOf = append(result.AllOf, &jsonschema.Schema{
+				If:   condition.If,
+				Then: condition.Then,
+				Else: condition.Else,
+			})
+		}
+	}
+	return &result
+}
+
+// CreateFieldEqualsSchema creates a schema matching objects whose field is present
+// and equal to value, for use as the If of a Conditional
+func CreateFieldEqualsSchema(field string, value any) *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Properties: map[string]*jsonschema.Schema{field: {Const: &value}},
+		Required:   []string{field},
+	}
+}
+
+// fieldProperties builds the property schemas and required list for a set of fields
+func fieldProperties(fields []FieldDef) (map[string]*jsonschema.Schema, []string) {
+	properties := make(map[string]*jsonschema.Schema)
+	var required []string
+
+	for _, field := range fields {
+		properties[field.Name] = fieldSchema(field)
 
 		if field.Required {
 			required = append(required, field.Name)
 		}
 	}
 
-	return &jsonschema.Schema{
-		Type:       "object",
-		Properties: properties,
-		Required:   required,
+	return properties, required
+}
+
+// fieldSchema builds the schema for a single field, recursing into array items
+// and nested object properties
+func fieldSchema(field FieldDef) *jsonschema.Schema {
+	schema := &jsonschema.Schema{
+		Type:        field.Type,
+		Description: field.Description,
+	}
+
+	if len(field.Enum) > 0 {
+		enum := make([]any, len(field.Enum))
+		for i, v := range field.Enum {
+			enum[i] = v
+		}
+		schema.Enum = enum
+	}
+
+	if field.Const != nil {
+		constValue := field.Const
+		schema.Const = &constValue
+	}
+
+	if field.Nullable && field.Type != "" {
+		// A type array lets the client send null (distinct from omitting the field)
+		schema.Types = []string{field.Type, "null"}
+		schema.Type = ""
+		if schema.Enum != nil {
+			schema.Enum = append(schema.Enum, nil)
+		}
+	}
+
+	if field.Type == "array" && field.Items != nil {
+		schema.Items = fieldSchema(*field.Items)
 	}
+	if field.Type == "array" {
+		schema.UniqueItems = field.UniqueItems
+	}
+
+	if field.Type == "object" && len(field.Properties) > 0 {
+		schema.Properties, schema.Required = fieldProperties(field.Properties)
+	}
+	if field.Type == "object" && field.AdditionalProperties != nil {
+		schema.AdditionalProperties = fieldSchema(*field.AdditionalProperties)
+	}
+	if field.Type == "object" {
+		applyObjectConstraints(schema, ObjectConstraints{
+			MinProperties: field.MinProperties,
+			MaxProperties: field.MaxProperties,
+		})
+	}
+
+	schema.Not = field.Not
+	schema.Minimum = field.Minimum
+	schema.Maximum = field.Maximum
+	applyStringConstraints(schema, StringConstraints{
+		MinLength: field.MinLength,
+		MaxLength: field.MaxLength,
+		Pattern:   field.Pattern,
+		Format:    field.Format,
+	})
+
+	return schema
+}
+
+// StringConstraints holds optional validation constraints for string schemas.
+// Nil pointers and an empty pattern leave the corresponding keyword unset.
+type StringConstraints struct {
+	MinLength *int
+	MaxLength *int
+	Pattern   string
+	Format    string // e.g. "email", "uri", "uuid"; these three are validated for raw tools
+}
+
+// applyStringConstraints copies string constraints onto a schema
+func applyStringConstraints(schema *jsonschema.Schema, constraints StringConstraints) {
+	schema.MinLength = constraints.MinLength
+	schema.MaxLength = constraints.MaxLength
+	schema.Pattern = constraints.Pattern
+	schema.Format = constraints.Format
+}
+
+// CreateOneOfSchema creates a schema that accepts input matching exactly one of the
+// given variants, for tools that accept a union of shapes. When every variant is an
+// object the union is typed as an object too, so it can be used as a tool input schema.
+func CreateOneOfSchema(description string, variants ...*jsonschema.Schema) *jsonschema.Schema {
+	schema := &jsonschema.Schema{
+		Description: description,
+		OneOf:       variants,
+	}
+
+	allObjects := len(variants) > 0
+	for _, variant := range variants {
+		if variant == nil || variant.Type != "object" {
+			allObjects = false
+			break
+		}
+	}
+	if allObjects {
+		schema.Type = "object"
+	}
+
+	return schema
+}
+
+// CreateDiscriminatedUnionSchema creates a oneOf schema whose object variants are
+// told apart by a string discriminator field, e.g. {"type": "move", ...} or
+// {"type": "delete", ...}. Each variant is keyed by its discriminator value and is
+// copied with the discriminator added as a required property fixed to that value.
+// Variants are listed in order of their discriminator values; a nil variant accepts
+// the discriminator alone.
+func CreateDiscriminatedUnionSchema(description, discriminator string, variants map[string]*jsonschema.Schema) *jsonschema.Schema {
+	oneOf := make([]*jsonschema.Schema, 0, len(variants))
+	for _, value := range slices.Sorted(maps.Keys(variants)) {
+		variant := &jsonschema.Schema{Type: "object"}
+		if variants[value] != nil {
+			copied := *variants[value]
+			variant = &copied
+		}
+		variant.Properties = maps.Clone(variant.Properties)
+		if variant.Properties == nil {
+			variant.Properties = make(map[string]*jsonschema.Schema, 1)
+		}
+		var constValue any = value
+		variant.Properties[discriminator] = &jsonschema.Schema{Type: "string", Const: &constValue}
+		if !slices.Contains(variant.Required, discriminator) {
+			variant.Required = append(slices.Clone(variant.Required), discriminator)
+		}
+		oneOf = append(oneOf, variant)
+	}
+	return CreateOneOfSchema(description, oneOf...)
+}
+
+// CreateAllOfSchema creates a schema that accepts input matching every one of the
+// given schemas, for tools composed from several trait schemas. When every schema is
+// an object the result is typed as an object too, so it can be used as a tool input
+// schema. Components must not set additionalProperties to false, or they would
+// reject the properties the other components define.
+func CreateAllOfSchema(schemas ...*jsonschema.Schema) *jsonschema.Schema {
+	schema := &jsonschema.Schema{AllOf: schemas}
+
+	allObjects := len(schemas) > 0
+	for _, component := range schemas {
+		if component == nil || component.Type != "object" {
+			allObjects = false
+			break
+		}
+	}
+	if allObjects {
+		schema.Type = "object"
+	}
+
+	return schema
+}
+
+// CreateNotSchema creates a schema that accepts input not matching the given schema,
+// for forbidding particular values or shapes. When the forbidden schema is an object
+// the result is typed as an object too and accepts only objects that don't match it,
+// so it can be combined with CreateAllOfSchema into a tool input schema.
+func CreateNotSchema(schema *jsonschema.Schema) *jsonschema.Schema {
+	not := &jsonschema.Schema{Not: schema}
+	if schema != nil && schema.Type == "object" {
+		not.Type = "object"
+	}
+	return not
+}
+
+// ObjectConstraints holds optional validation constraints for object schemas.
+// Nil pointers leave the corresponding keyword unset.
+type ObjectConstraints struct {
+	MinProperties *int
+	MaxProperties *int
+}
+
+// applyObjectConstraints copies object constraints onto a schema
+func applyObjectConstraints(schema *jsonschema.Schema, constraints ObjectConstraints) {
+	schema.MinProperties = constraints.MinProperties
+	schema.MaxProperties = constraints.MaxProperties
 }
 
 // CreateStringSchema creates a simple string schema with optional constraints
 func CreateStringSchema(description string, enum []string) *jsonschema.Schema {
+	return CreateStringSchemaWithConstraints(description, enum, StringConstraints{})
+}
+
+// CreateStringSchemaWithConstraints creates a string schema with optional enum values
+// and length/pattern constraints
+func CreateStringSchemaWithConstraints(description string, enum []string, constraints StringConstraints) *jsonschema.Schema {
 	schema := &jsonschema.Schema{
 		Type:        "string",
 		Description: description,
@@ -67,6 +646,26 @@ func CreateStringSchema(description string, enum []string) *jsonschema.Schema {
 		schema.Enum = enumAny
 	}
 
+	applyStringConstraints(schema, constraints)
+
+	return schema
+}
+
+// CreateIntegerSchema creates a schema for whole numbers, e.g. page sizes, with
+// optional inclusive bounds. Values with a fractional part are rejected.
+func CreateIntegerSchema(description string, minimum, maximum *int64) *jsonschema.Schema {
+	schema := &jsonschema.Schema{
+		Type:        "integer",
+		Description: description,
+	}
+	if minimum != nil {
+		bound := float64(*minimum)
+		schema.Minimum = &bound
+	}
+	if maximum != nil {
+		bound := float64(*maximum)
+		schema.Maximum = &bound
+	}
 	return schema
 }
 
@@ -88,3 +687,78 @@ func CreateObjectSchema(description string, properties map[string]string, requir
 		Required:    required,
 	}
 }
+
+// ArrayConstraints holds optional validation constraints for array schemas
+type ArrayConstraints struct {
+	UniqueItems bool // Reject arrays containing duplicate elements
+
+	// Contains requires at least one element to match the schema. MinContains and
+	// MaxContains, when set, bound how many elements must match instead.
+	Contains    *jsonschema.Schema
+	MinContains *int
+	MaxContains *int
+}
+
+// CreateArraySchema creates an array schema whose elements match the given schema.
+// When uniqueItems is set, arrays containing duplicate elements are rejected.
+func CreateArraySchema(description string, items *jsonschema.Schema, uniqueItems bool) *jsonschema.Schema {
+	return CreateArraySchemaWithConstraints(description, items, ArrayConstraints{UniqueItems: uniqueItems})
+}
+
+// CreateArraySchemaWithConstraints creates an array schema like CreateArraySchema
+// with additional constraints, e.g. requiring an element that matches a schema
+func CreateArraySchemaWithConstraints(description string, items *jsonschema.Schema, constraints ArrayConstraints) *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        "array",
+		Description: description,
+		Items:       items,
+		UniqueItems: constraints.UniqueItems,
+		Contains:    constraints.Contains,
+		MinContains: constraints.MinContains,
+		MaxContains: constraints.MaxContains,
+	}
+}
+
+// CreateMapSchema creates an object schema whose arbitrary keys all map to values
+// matching the given schema, e.g. a map of label names to strings
+func CreateMapSchema(description string, values *jsonschema.Schema) *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:                 "object",
+		Description:          description,
+		AdditionalProperties: values,
+	}
+}
+
+// CreateMapSchemaWithConstraints creates a map schema like CreateMapSchema that
+// also bounds how many keys the object may have
+func CreateMapSchemaWithConstraints(description string, values *jsonschema.Schema, constraints ObjectConstraints) *jsonschema.Schema {
+	schema := CreateMapSchema(description, values)
+	applyObjectConstraints(schema, constraints)
+	return schema
+}
+
+// CreatePatternPropertiesSchema creates an object schema whose keys are matched
+// against regular expressions, each mapped to the schema for the values of matching
+// keys, e.g. {"^header_": a string schema}. A key matching several patterns must
+// satisfy all of their schemas. Keys matching no pattern are rejected unless
+// allowOtherKeys is set.
+func CreatePatternPropertiesSchema(description string, patterns map[string]*jsonschema.Schema, allowOtherKeys bool) *jsonschema.Schema {
+	schema := &jsonschema.Schema{
+		Type:              "object",
+		Description:       description,
+		PatternProperties: patterns,
+	}
+	if !allowOtherKeys {
+		// The schema that matches nothing
+		schema.AdditionalProperties = &jsonschema.Schema{Not: &jsonschema.Schema{}}
+	}
+	return schema
+}
+
+// CreateObjectSchemaFromFields creates an object schema with typed properties built
+// from field definitions, using the same rules as CreateDynamicSchema
+func CreateObjectSchemaFromFields(description string, fields []FieldDef) *jsonschema.Schema {
+	schema := CreateDynamicSchema(fields)
+	schema.Description = description
+	return schema
+}
@@ -1,9 +1,12 @@
 package mcpio
 
 import (
+	"context"
+	"reflect"
 	"testing"
 
 	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -21,6 +24,46 @@ func TestGenerateSchema(t *testing.T) {
 	assert.NotNil(t, schema.Properties)
 }
 
+func TestGenerateSchemaFor(t *testing.T) {
+	type Profile struct {
+		Name  string   `json:"name"  jsonschema:"User name,required"`
+		Email string   `json:"email" jsonschema:"required"`
+		Tags  []string `json:"tags"  jsonschema:"Labels"`
+		ID    string   `json:"id"    jsonschema:"Assigned ID,readOnly"`
+	}
+	want, err := GenerateSchema[Profile]()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{"struct instance", Profile{Name: "alice"}},
+		{"pointer", &Profile{}},
+		{"nil pointer", (*Profile)(nil)},
+		{"reflect type", reflect.TypeFor[Profile]()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := GenerateSchemaFor(tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, want, schema)
+			assert.Equal(t, []string{"name", "email"}, schema.Required)
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		_, err := GenerateSchemaFor(nil)
+		require.ErrorIs(t, err, ErrInvalidSchema)
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		_, err := GenerateSchemaFor(make(chan int))
+		require.Error(t, err)
+	})
+}
+
 func TestCreateDynamicSchema(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -83,6 +126,136 @@ func TestCreateDynamicSchema(t *testing.T) {
 				assert.Contains(t, nameSchema.Enum, "pending")
 			},
 		},
+		{
+			name: "field with const value",
+			fields: []FieldDef{
+				{Name: "kind", Type: "string", Description: "Discriminator", Required: true, Const: "circle"},
+				{Name: "radius", Type: "number", Description: "Radius", Required: true},
+			},
+			expected: func(t *testing.T, s *jsonschema.Schema) {
+				t.Helper()
+				kindSchema := s.Properties["kind"]
+				require.NotNil(t, kindSchema)
+				require.NotNil(t, kindSchema.Const)
+				assert.Equal(t, "circle", *kindSchema.Const)
+				assert.Nil(t, s.Properties["radius"].Const)
+			},
+		},
+		{
+			name: "array field with string items",
+			fields: []FieldDef{
+				{Name: "tags", Type: "array", Description: "Tags", Items: &FieldDef{Type: "string", Description: "A tag"}},
+			},
+			expected: func(t *testing.T, s *jsonschema.Schema) {
+				t.Helper()
+				tagsSchema := s.Properties["tags"]
+				require.NotNil(t, tagsSchema)
+				assert.Equal(t, "array", tagsSchema.Type)
+				require.NotNil(t, tagsSchema.Items)
+				assert.Equal(t, "string", tagsSchema.Items.Type)
+				assert.Equal(t, "A tag", tagsSchema.Items.Description)
+			},
+		},
+		{
+			name: "nested array items",
+			fields: []FieldDef{
+				{Name: "matrix", Type: "array", Items: &FieldDef{Type: "array", Items: &FieldDef{Type: "number"}}},
+			},
+			expected: func(t *testing.T, s *jsonschema.Schema) {
+				t.Helper()
+				matrixSchema := s.Properties["matrix"]
+				require.NotNil(t, matrixSchema.Items)
+				assert.Equal(t, "array", matrixSchema.Items.Type)
+				require.NotNil(t, matrixSchema.Items.Items)
+				assert.Equal(t, "number", matrixSchema.Items.Items.Type)
+			},
+		},
+		{
+			name: "array field without items",
+			fields: []FieldDef{
+				{Name: "anything", Type: "array"},
+			},
+			expected: func(t *testing.T, s *jsonschema.Schema) {
+				t.Helper()
+				assert.Equal(t, "array", s.Properties["anything"].Type)
+				assert.Nil(t, s.Properties["anything"].Items)
+			},
+		},
+		{
+			name: "number field with bounds",
+			fields: []FieldDef{
+				{Name: "percent", Type: "number", Minimum: ptr(0.0), Maximum: ptr(100.0)},
+			},
+			expected: func(t *testing.T, s *jsonschema.Schema) {
+				t.Helper()
+				percentSchema := s.Properties["percent"]
+				require.NotNil(t, percentSchema.Minimum)
+				require.NotNil(t, percentSchema.Maximum)
+				assert.InDelta(t, 0.0, *percentSchema.Minimum, 0)
+				assert.InDelta(t, 100.0, *percentSchema.Maximum, 0)
+			},
+		},
+		{
+			name: "string field with length and pattern",
+			fields: []FieldDef{
+				{Name: "code", Type: "string", MinLength: ptr(2), MaxLength: ptr(4), Pattern: "^[A-Z]+$"},
+			},
+			expected: func(t *testing.T, s *jsonschema.Schema) {
+				t.Helper()
+				codeSchema := s.Properties["code"]
+				assert.Equal(t, 2, *codeSchema.MinLength)
+				assert.Equal(t, 4, *codeSchema.MaxLength)
+				assert.Equal(t, "^[A-Z]+$", codeSchema.Pattern)
+				assert.Nil(t, codeSchema.Minimum)
+			},
+		},
+		{
+			name: "nested object fields",
+			fields: []FieldDef{
+				{Name: "name", Type: "string", Required: true},
+				{
+					Name:     "address",
+					Type:     "object",
+					Required: true,
+					Properties: []FieldDef{
+						{Name: "street", Type: "string", Required: true},
+						{Name: "city", Type: "string", Required: true},
+						{Name: "unit", Type: "string"},
+					},
+				},
+			},
+			expected: func(t *testing.T, s *jsonschema.Schema) {
+				t.Helper()
+				assert.ElementsMatch(t, []string{"name", "address"}, s.Required)
+
+				addressSchema := s.Properties["address"]
+				require.NotNil(t, addressSchema)
+				assert.Equal(t, "object", addressSchema.Type)
+				require.Len(t, addressSchema.Properties, 3)
+				assert.Equal(t, "string", addressSchema.Properties["street"].Type)
+				assert.ElementsMatch(t, []string{"street", "city"}, addressSchema.Required)
+			},
+		},
+		{
+			name: "array of objects",
+			fields: []FieldDef{
+				{Name: "points", Type: "array", Items: &FieldDef{
+					Type: "object",
+					Properties: []FieldDef{
+						{Name: "x", Type: "number", Required: true},
+						{Name: "y", Type: "number", Required: true},
+					},
+				}},
+			},
+			expected: func(t *testing.T, s *jsonschema.Schema) {
+				t.Helper()
+				items := s.Properties["points"].Items
+				require.NotNil(t, items)
+				assert.Equal(t, "object", items.Type)
+				assert.Len(t, items.Properties, 2)
+				assert.ElementsMatch(t, []string{"x", "y"}, items.Required)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -148,6 +321,63 @@ func TestCreateStringSchema(t *testing.T) {
 	}
 }
 
+func TestCreateStringSchemaWithConstraints(t *testing.T) {
+	schema := CreateStringSchemaWithConstraints("Country code", nil, StringConstraints{
+		MinLength: ptr(2),
+		MaxLength: ptr(2),
+		Pattern:   "^[A-Z]+$",
+	})
+	assert.Equal(t, "string", schema.Type)
+	assert.Equal(t, "Country code", schema.Description)
+	assert.Equal(t, 2, *schema.MinLength)
+	assert.Equal(t, 2, *schema.MaxLength)
+	assert.Equal(t, "^[A-Z]+$", schema.Pattern)
+	assert.Empty(t, schema.Format)
+
+	email := CreateStringSchemaWithConstraints("Contact email", nil, StringConstraints{Format: "email"})
+	assert.Equal(t, "email", email.Format)
+
+	fields := CreateDynamicSchema([]FieldDef{{Name: "id", Type: "string", Format: "uuid"}})
+	assert.Equal(t, "uuid", fields.Properties["id"].Format)
+}
+
+func TestSchemaConstraintValidation(t *testing.T) {
+	schema := CreateDynamicSchema([]FieldDef{
+		{Name: "percent", Type: "number", Minimum: ptr(0.0), Maximum: ptr(100.0)},
+		{Name: "code", Type: "string", Pattern: "^[A-Z]{2}$"},
+	})
+	resolved, err := schema.Resolve(nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		input   map[string]any
+		wantErr bool
+	}{
+		{"within bounds", map[string]any{"percent": 50.0, "code": "US"}, false},
+		{"lower bound inclusive", map[string]any{"percent": 0.0}, false},
+		{"upper bound inclusive", map[string]any{"percent": 100.0}, false},
+		{"below minimum", map[string]any{"percent": -1.0}, true},
+		{"above maximum", map[string]any{"percent": 100.5}, true},
+		{"pattern mismatch", map[string]any{"code": "usa"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := resolved.Validate(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
 func TestCreateObjectSchema(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -214,3 +444,887 @@ func TestCreateObjectSchema(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateObjectSchemaFromFields(t *testing.T) {
+	schema := CreateObjectSchemaFromFields("Order line", []FieldDef{
+		{Name: "sku", Type: "string", Description: "Product SKU", Required: true},
+		{Name: "quantity", Type: "number", Description: "Units ordered", Required: true, Minimum: ptr(1.0)},
+		{Name: "gift", Type: "boolean", Description: "Gift wrap"},
+		{Name: "notes", Type: "array", Items: &FieldDef{Type: "string"}},
+	})
+
+	assert.Equal(t, "object", schema.Type)
+	assert.Equal(t, "Order line", schema.Description)
+	assert.ElementsMatch(t, []string{"sku", "quantity"}, schema.Required)
+
+	require.Len(t, schema.Properties, 4)
+	assert.Equal(t, "string", schema.Properties["sku"].Type)
+	assert.Equal(t, "Product SKU", schema.Properties["sku"].Description)
+	assert.Equal(t, "number", schema.Properties["quantity"].Type)
+	assert.InDelta(t, 1.0, *schema.Properties["quantity"].Minimum, 0)
+	assert.Equal(t, "boolean", schema.Properties["gift"].Type)
+	assert.Equal(t, "array", schema.Properties["notes"].Type)
+	assert.Equal(t, "string", schema.Properties["notes"].Items.Type)
+}
+
+func TestGenerateSchemaRequiredTags(t *testing.T) {
+	type Address struct {
+		Street string `json:"street" jsonschema:"Street name,required"`
+		Unit   string `json:"unit"   jsonschema:"Apartment or unit"`
+	}
+	type Profile struct {
+		Name    string  `json:"name"    jsonschema:"User name,required"`
+		Email   string  `json:"email"   jsonschema:"required"`
+		Nick    string  `json:"nick"    jsonschema:"Optional nickname"`
+		Address Address `json:"address" jsonschema:"Mailing address"`
+	}
+	type Untagged struct {
+		Name string `json:"name"           jsonschema:"User name"`
+		Note string `json:"note,omitempty" jsonschema:"Optional note"`
+	}
+
+	t.Run("explicit required markers", func(t *testing.T) {
+		schema, err := GenerateSchema[Profile]()
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"name", "email"}, schema.Required)
+		assert.Equal(t, "User name", schema.Properties["name"].Description)
+		assert.Empty(t, schema.Properties["email"].Description)
+		assert.Equal(t, "Optional nickname", schema.Properties["nick"].Description)
+
+		address := schema.Properties["address"]
+		assert.Equal(t, []string{"street"}, address.Required)
+		assert.Equal(t, "Street name", address.Properties["street"].Description)
+	})
+
+	t.Run("default required list without markers", func(t *testing.T) {
+		schema, err := GenerateSchema[Untagged]()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"name"}, schema.Required)
+	})
+
+	t.Run("typed tool input schema", func(t *testing.T) {
+		schema, err := generateInputSchema[*Profile]()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"name", "email"}, schema.Required)
+	})
+}
+
+func TestReadOnlyTags(t *testing.T) {
+	type Document struct {
+		ID        string `json:"id"         jsonschema:"Document ID,readOnly"`
+		Title     string `json:"title"      jsonschema:"Document title,required"`
+		Body      string `json:"body"       jsonschema:"Document body"`
+		CreatedAt string `json:"created_at" jsonschema:"readOnly"`
+		Revision  int    `json:"revision"   jsonschema:"Revision number,required,readOnly"`
+	}
+
+	t.Run("parse markers", func(t *testing.T) {
+		tests := []struct {
+			tag          string
+			wantDesc     string
+			wantRequired bool
+			wantReadOnly bool
+		}{
+			{"Plain description", "Plain description", false, false},
+			{"required", "", true, false},
+			{"readOnly", "", false, true},
+			{"Name,required", "Name", true, false},
+			{"Name,readOnly", "Name", false, true},
+			{"Name,required,readOnly", "Name", true, true},
+			{"Name,readOnly,required", "Name", true, true},
+			{"required,readOnly", "", true, true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.tag, func(t *testing.T) {
+				desc, required, readOnly := parseSchemaTag(tt.tag)
+				assert.Equal(t, tt.wantDesc, desc)
+				assert.Equal(t, tt.wantRequired, required)
+				assert.Equal(t, tt.wantReadOnly, readOnly)
+			})
+		}
+	})
+
+	t.Run("absent from input schema", func(t *testing.T) {
+		schema, err := generateInputSchema[Document]()
+		require.NoError(t, err)
+		assert.NotContains(t, schema.Properties, "id")
+		assert.NotContains(t, schema.Properties, "created_at")
+		assert.NotContains(t, schema.Properties, "revision")
+		assert.Contains(t, schema.Properties, "title")
+		assert.Contains(t, schema.Properties, "body")
+		assert.Equal(t, []string{"title"}, schema.Required)
+	})
+
+	t.Run("present in output schema", func(t *testing.T) {
+		schema := generateOutputSchemaFor(reflect.TypeFor[Document]())
+		require.NotNil(t, schema)
+		require.Contains(t, schema.Properties, "id")
+		assert.True(t, schema.Properties["id"].ReadOnly)
+		assert.Equal(t, "Document ID", schema.Properties["id"].Description)
+		assert.True(t, schema.Properties["created_at"].ReadOnly)
+		assert.Empty(t, schema.Properties["created_at"].Description)
+		assert.False(t, schema.Properties["title"].ReadOnly)
+		assert.Equal(t, []string{"title", "revision"}, schema.Required)
+	})
+
+	t.Run("typed tool schemas", func(t *testing.T) {
+		save := func(ctx context.Context, doc Document) (Document, error) {
+			doc.ID = "doc-1"
+			doc.Revision = 1
+			return doc, nil
+		}
+		handler, err := NewHandler(WithTool("save", "Save a document", save))
+		require.NoError(t, err)
+		session := connectTestClient(t, handler)
+
+		list, err := session.ListTools(context.Background(), nil)
+		require.NoError(t, err)
+		require.Len(t, list.Tools, 1)
+
+		input, output := list.Tools[0].InputSchema, list.Tools[0].OutputSchema
+		require.NotNil(t, output)
+		assert.NotContains(t, input.Properties, "id")
+		require.Contains(t, output.Properties, "id")
+		assert.True(t, output.Properties["id"].ReadOnly)
+
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "save",
+			Arguments: map[string]any{"title": "Notes", "body": "..."},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError, resultText(t, result))
+		assert.JSONEq(t, `{"id": "doc-1", "title": "Notes", "body": "...", "created_at": "", "revision": 1}`, resultText(t, result))
+
+		// Output-only fields are unknown input fields
+		result, err = session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "save",
+			Arguments: map[string]any{"title": "Notes", "id": "forged"},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+	})
+}
+
+func TestCreateDynamicSchemaNullable(t *testing.T) {
+	schema := CreateDynamicSchema([]FieldDef{
+		{Name: "nickname", Type: "string", Required: true, Nullable: true},
+		{Name: "name", Type: "string", Required: true},
+		{Name: "status", Type: "string", Nullable: true, Enum: []string{"active", "inactive"}},
+	})
+
+	nickname := schema.Properties["nickname"]
+	assert.Empty(t, nickname.Type)
+	assert.Equal(t, []string{"string", "null"}, nickname.Types)
+	assert.Equal(t, "string", schema.Properties["name"].Type)
+	assert.Nil(t, schema.Properties["name"].Types)
+
+	resolved, err := schema.Resolve(nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		input   map[string]any
+		wantErr bool
+	}{
+		{"nullable field accepts null", map[string]any{"nickname": nil, "name": "Ada"}, false},
+		{"nullable field accepts value", map[string]any{"nickname": "ada", "name": "Ada"}, false},
+		{"nullable enum accepts null", map[string]any{"nickname": nil, "name": "Ada", "status": nil}, false},
+		{"nullable enum rejects other values", map[string]any{"nickname": nil, "name": "Ada", "status": "gone"}, true},
+		{"non-nullable field rejects null", map[string]any{"nickname": "ada", "name": nil}, true},
+		{"nullable field is still required", map[string]any{"name": "Ada"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := resolved.Validate(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCreateDynamicSchemaWithDependencies(t *testing.T) {
+	schema := CreateDynamicSchemaWithDependencies(
+		[]FieldDef{
+			{Name: "name", Type: "string", Required: true},
+			{Name: "credit_card", Type: "string"},
+			{Name: "billing_address", Type: "string"},
+		},
+		map[string][]string{"credit_card": {"billing_address"}},
+	)
+	assert.Equal(t, map[string][]string{"credit_card": {"billing_address"}}, schema.DependentRequired)
+
+	resolved, err := schema.Resolve(nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		input   map[string]any
+		wantErr bool
+	}{
+		{"neither field", map[string]any{"name": "a"}, false},
+		{"both fields", map[string]any{"name": "a", "credit_card": "4111", "billing_address": "1 Main St"}, false},
+		{"dependent field alone", map[string]any{"name": "a", "billing_address": "1 Main St"}, false},
+		{"missing dependent field", map[string]any{"name": "a", "credit_card": "4111"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := resolved.Validate(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("raw tool rejects missing dependent field", func(t *testing.T) {
+		handler, err := NewHandler(WithRawTool("checkout", "Check out", schema, rawFunc))
+		require.NoError(t, err)
+		session := connectTestClient(t, handler)
+
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "checkout",
+			Arguments: map[string]any{"name": "a", "credit_card": "4111"},
+		})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), "billing_address")
+	})
+
+	t.Run("nil dependencies", func(t *testing.T) {
+		schema := CreateDynamicSchemaWithDependencies([]FieldDef{{Name: "a", Type: "string"}}, nil)
+		assert.Nil(t, schema.DependentRequired)
+	})
+}
+
+func TestCreateOneOfSchema(t *testing.T) {
+	byID := CreateDynamicSchema([]FieldDef{
+		{Name: "id", Type: "string", Required: true},
+	})
+	byEmail := CreateDynamicSchema([]FieldDef{
+		{Name: "email", Type: "string", Required: true, Format: "email"},
+	})
+	byID.AdditionalProperties = &jsonschema.Schema{Not: &jsonschema.Schema{}}
+	byEmail.AdditionalProperties = &jsonschema.Schema{Not: &jsonschema.Schema{}}
+
+	schema := CreateOneOfSchema("Look up a user by ID or email", byID, byEmail)
+	assert.Equal(t, "object", schema.Type)
+	assert.Equal(t, "Look up a user by ID or email", schema.Description)
+	assert.Len(t, schema.OneOf, 2)
+
+	handler, err := NewHandler(WithRawTool("lookup", "Look up a user", schema, rawFunc))
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	tests := []struct {
+		name      string
+		args      map[string]any
+		wantError bool
+	}{
+		{"first variant", map[string]any{"id": "u-123"}, false},
+		{"second variant", map[string]any{"email": "a@example.com"}, false},
+		{"matches no variant", map[string]any{"name": "alice"}, true},
+		{"fields from both variants", map[string]any{"id": "u-123", "email": "a@example.com"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+				Name:      "lookup",
+				Arguments: tt.args,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantError, result.IsError, resultText(t, result))
+		})
+	}
+
+	t.Run("mixed variants are untyped", func(t *testing.T) {
+		schema := CreateOneOfSchema("", CreateStringSchema("", nil), byID)
+		assert.Empty(t, schema.Type)
+
+		_, err := NewHandler(WithRawTool("mixed", "Mixed union", schema, rawFunc))
+		require.ErrorIs(t, err, ErrInvalidSchema)
+	})
+}
+
+func TestCreateDiscriminatedUnionSchema(t *testing.T) {
+	move := CreateDynamicSchema([]FieldDef{
+		{Name: "to", Type: "string", Required: true},
+	})
+	action := CreateDiscriminatedUnionSchema("Action to apply to the file", "type", map[string]*jsonschema.Schema{
+		"move":   move,
+		"delete": nil,
+	})
+
+	assert.Equal(t, "object", action.Type)
+	assert.Equal(t, "Action to apply to the file", action.Description)
+	require.Len(t, action.OneOf, 2)
+	for i, want := range []string{"delete", "move"} {
+		variant := action.OneOf[i]
+		require.Contains(t, variant.Properties, "type")
+		require.NotNil(t, variant.Properties["type"].Const)
+		assert.Equal(t, want, *variant.Properties["type"].Const)
+		assert.Contains(t, variant.Required, "type")
+	}
+	assert.Equal(t, []string{"to", "type"}, action.OneOf[1].Required)
+	assert.Equal(t, []string{"to"}, move.Required, "variants are copied, not modified")
+	assert.NotContains(t, move.Properties, "type")
+
+	schema := CreateDynamicSchema([]FieldDef{{Name: "path", Type: "string", Required: true}})
+	schema.Properties["action"] = action
+	schema.Required = append(schema.Required, "action")
+
+	handler, err := NewHandler(WithRawTool("apply", "Apply an action to a file", schema, rawFunc))
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	tests := []struct {
+		name      string
+		action    map[string]any
+		wantError bool
+	}{
+		{"move", map[string]any{"type": "move", "to": "/archive"}, false},
+		{"delete", map[string]any{"type": "delete"}, false},
+		{"move missing its field", map[string]any{"type": "move"}, true},
+		{"unknown discriminator", map[string]any{"type": "copy", "to": "/tmp"}, true},
+		{"missing discriminator", map[string]any{"to": "/archive"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+				Name:      "apply",
+				Arguments: map[string]any{"path": "/a.txt", "action": tt.action},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantError, result.IsError, resultText(t, result))
+		})
+	}
+}
+
+func TestCreateAllOfSchema(t *testing.T) {
+	named := CreateDynamicSchema([]FieldDef{
+		{Name: "name", Type: "string", Required: true, MinLength: ptr(1)},
+	})
+	timestamped := CreateDynamicSchema([]FieldDef{
+		{Name: "created_at", Type: "string", Required: true, Format: "date-time"},
+	})
+	owned := CreateDynamicSchema([]FieldDef{
+		{Name: "owner", Type: "string", Required: true, Format: "email"},
+	})
+
+	schema := CreateAllOfSchema(named, timestamped, owned)
+	assert.Equal(t, "object", schema.Type)
+	assert.Len(t, schema.AllOf, 3)
+
+	handler, err := NewHandler(WithRawTool("create", "Create a record", schema, rawFunc))
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	valid := map[string]any{"name": "report", "created_at": "2025-01-02T03:04:05Z", "owner": "a@example.com"}
+	tests := []struct {
+		name      string
+		args      map[string]any
+		wantError bool
+	}{
+		{"satisfies every component", valid, false},
+		{"missing one component's field", map[string]any{"name": "report", "created_at": "2025-01-02T03:04:05Z"}, true},
+		{"violates one component's format", map[string]any{"name": "report", "created_at": "2025-01-02T03:04:05Z", "owner": "alice"}, true},
+		{"violates one component's constraint", map[string]any{"name": "", "created_at": "2025-01-02T03:04:05Z", "owner": "a@example.com"}, true},
+		{"satisfies no component", map[string]any{"other": "x"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+				Name:      "create",
+				Arguments: tt.args,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantError, result.IsError, resultText(t, result))
+		})
+	}
+
+	t.Run("non-object components are untyped", func(t *testing.T) {
+		schema := CreateAllOfSchema(CreateStringSchema("", nil), named)
+		assert.Empty(t, schema.Type)
+
+		_, err := NewHandler(WithRawTool("mixed", "Mixed composition", schema, rawFunc))
+		require.ErrorIs(t, err, ErrInvalidSchema)
+	})
+}
+
+func TestCreateNotSchema(t *testing.T) {
+	reserved := CreateStringSchema("", []string{"admin", "root"})
+	account := CreateDynamicSchema([]FieldDef{
+		{Name: "username", Type: "string", Required: true, Not: reserved},
+		{Name: "email", Type: "string"},
+		{Name: "phone", Type: "string"},
+	})
+	// Accounts may give an email or a phone number, but not both
+	bothContacts := CreateDynamicSchema([]FieldDef{
+		{Name: "email", Type: "string", Required: true},
+		{Name: "phone", Type: "string", Required: true},
+	})
+
+	forbidden := CreateNotSchema(bothContacts)
+	assert.Equal(t, "object", forbidden.Type)
+	assert.Same(t, bothContacts, forbidden.Not)
+	assert.Same(t, reserved, account.Properties["username"].Not)
+
+	handler, err := NewHandler(WithRawTool("register", "Register an account", CreateAllOfSchema(account, forbidden), rawFunc))
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	tests := []struct {
+		name      string
+		args      map[string]any
+		wantError bool
+	}{
+		{"allowed username", map[string]any{"username": "alice"}, false},
+		{"one contact", map[string]any{"username": "alice", "email": "a@example.com"}, false},
+		{"forbidden property value", map[string]any{"username": "root"}, true},
+		{"forbidden object shape", map[string]any{"username": "alice", "email": "a@example.com", "phone": "555-0100"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+				Name:      "register",
+				Arguments: tt.args,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantError, result.IsError, resultText(t, result))
+		})
+	}
+
+	t.Run("non-object schemas are untyped", func(t *testing.T) {
+		assert.Empty(t, CreateNotSchema(reserved).Type)
+		assert.Empty(t, CreateNotSchema(nil).Type)
+	})
+}
+
+func TestCreateDynamicSchemaValidated(t *testing.T) {
+	t.Run("valid types", func(t *testing.T) {
+		fields := []FieldDef{
+			{Name: "name", Type: "string", Required: true},
+			{Name: "count", Type: "integer"},
+			{Name: "tags", Type: "array", Items: &FieldDef{Type: "string"}},
+			{Name: "anything"},
+		}
+		schema, err := CreateDynamicSchemaValidated(fields)
+		require.NoError(t, err)
+		assert.Equal(t, CreateDynamicSchema(fields), schema)
+	})
+
+	tests := []struct {
+		name     string
+		fields   []FieldDef
+		wantPath string
+	}{
+		{
+			name:     "top-level field",
+			fields:   []FieldDef{{Name: "name", Type: "string"}, {Name: "age", Type: "strng"}},
+			wantPath: `"age"`,
+		},
+		{
+			name: "nested property",
+			fields: []FieldDef{{
+				Name:       "address",
+				Type:       "object",
+				Properties: []FieldDef{{Name: "street", Type: "text"}},
+			}},
+			wantPath: `"address.street"`,
+		},
+		{
+			name:     "array items",
+			fields:   []FieldDef{{Name: "tags", Type: "array", Items: &FieldDef{Type: "str"}}},
+			wantPath: `"tags[]"`,
+		},
+		{
+			name:     "map values",
+			fields:   []FieldDef{{Name: "labels", Type: "object", AdditionalProperties: &FieldDef{Type: "float"}}},
+			wantPath: `"labels.*"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := CreateDynamicSchemaValidated(tt.fields)
+			require.ErrorIs(t, err, ErrInvalidSchema)
+			assert.Nil(t, schema)
+			assert.Contains(t, err.Error(), "field "+tt.wantPath)
+		})
+	}
+}
+
+func TestIntegerSchemas(t *testing.T) {
+	pagination := CreateDynamicSchema([]FieldDef{
+		{Name: "page", Type: "integer", Required: true, Minimum: ptr(1.0)},
+		{Name: "cursor", Type: "integer", Nullable: true},
+	})
+	assert.Equal(t, "integer", pagination.Properties["page"].Type)
+	assert.Equal(t, []string{"integer", "null"}, pagination.Properties["cursor"].Types)
+
+	limit := CreateIntegerSchema("Results per page", ptr[int64](1), ptr[int64](100))
+	assert.Equal(t, "integer", limit.Type)
+	assert.Equal(t, "Results per page", limit.Description)
+	assert.Equal(t, ptr(1.0), limit.Minimum)
+	assert.Equal(t, ptr(100.0), limit.Maximum)
+	pagination.Properties["limit"] = limit
+
+	unbounded := CreateIntegerSchema("", nil, nil)
+	assert.Nil(t, unbounded.Minimum)
+	assert.Nil(t, unbounded.Maximum)
+
+	handler, err := NewHandler(WithRawTool("list", "List records", pagination, rawFunc))
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	tests := []struct {
+		name      string
+		args      map[string]any
+		wantError bool
+	}{
+		{"whole numbers", map[string]any{"page": 2, "limit": 50}, false},
+		{"whole number written as float", map[string]any{"page": 2.0}, false},
+		{"null cursor", map[string]any{"page": 1, "cursor": nil}, false},
+		{"fractional page", map[string]any{"page": 1.5}, true},
+		{"below minimum", map[string]any{"page": 0}, true},
+		{"above maximum", map[string]any{"page": 1, "limit": 101}, true},
+		{"fractional limit", map[string]any{"page": 1, "limit": 10.5}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+				Name:      "list",
+				Arguments: tt.args,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantError, result.IsError, resultText(t, result))
+		})
+	}
+}
+
+func TestCreatePatternPropertiesSchema(t *testing.T) {
+	headers := CreatePatternPropertiesSchema("Request headers", map[string]*jsonschema.Schema{
+		"^header_":  CreateStringSchemaWithConstraints("", nil, StringConstraints{MaxLength: ptr(8)}),
+		"^contact_": CreateStringSchemaWithConstraints("", nil, StringConstraints{Format: "email"}),
+		"_count$":   {Type: "integer"},
+	}, false)
+	assert.Equal(t, "object", headers.Type)
+	assert.Len(t, headers.PatternProperties, 3)
+
+	open := CreatePatternPropertiesSchema("", map[string]*jsonschema.Schema{
+		"^header_": {Type: "string"},
+	}, true)
+	assert.Nil(t, open.AdditionalProperties)
+
+	handler, err := NewHandler(
+		WithRawTool("send", "Send a request", headers, rawFunc),
+		WithRawTool("send_open", "Send a request", open, rawFunc),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	tests := []struct {
+		name      string
+		tool      string
+		args      map[string]any
+		wantError bool
+	}{
+		{"matching keys", "send", map[string]any{"header_auth": "token", "retry_count": 3}, false},
+		{"matching key with invalid value", "send", map[string]any{"header_auth": "far too long"}, true},
+		{"key matching several patterns", "send", map[string]any{"header_count": "1"}, true},
+		{"format in pattern schema", "send", map[string]any{"contact_owner": "alice"}, true},
+		{"valid format in pattern schema", "send", map[string]any{"contact_owner": "a@example.com"}, false},
+		{"non-matching key", "send", map[string]any{"cookie": "x"}, true},
+		{"non-matching key allowed", "send_open", map[string]any{"cookie": 1, "header_auth": "token"}, false},
+		{"matching key still validated", "send_open", map[string]any{"header_auth": 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+				Name:      tt.tool,
+				Arguments: tt.args,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantError, result.IsError, resultText(t, result))
+		})
+	}
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		schema := CreatePatternPropertiesSchema("", map[string]*jsonschema.Schema{"[": {Type: "string"}}, true)
+		_, err := NewHandler(WithRawTool("bad", "Bad pattern", schema, rawFunc))
+		require.ErrorIs(t, err, ErrInvalidSchema)
+	})
+}
+
+func TestCreateMapSchema(t *testing.T) {
+	minCount := 0.0
+	counts := CreateMapSchema("Item counts by SKU", &jsonschema.Schema{Type: "integer", Minimum: &minCount})
+	assert.Equal(t, "object", counts.Type)
+	require.NotNil(t, counts.AdditionalProperties)
+	assert.Equal(t, "integer", counts.AdditionalProperties.Type)
+
+	schema := CreateDynamicSchema([]FieldDef{
+		{Name: "order_id", Type: "string", Required: true},
+		{
+			Name: "labels",
+			Type: "object",
+			AdditionalProperties: &FieldDef{
+				Type:      "string",
+				MaxLength: ptr(8),
+			},
+		},
+	})
+	labels := schema.Properties["labels"]
+	require.NotNil(t, labels.AdditionalProperties)
+	assert.Equal(t, "string", labels.AdditionalProperties.Type)
+
+	handler, err := NewHandler(
+		WithRawTool("counts", "Record item counts", counts, rawFunc),
+		WithRawTool("order", "Label an order", schema, rawFunc),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	tests := []struct {
+		name      string
+		tool      string
+		args      map[string]any
+		wantError bool
+	}{
+		{"map values match", "counts", map[string]any{"sku-1": 2, "sku-2": 0}, false},
+		{"empty map", "counts", map[string]any{}, false},
+		{"map value wrong type", "counts", map[string]any{"sku-1": "two"}, true},
+		{"map value out of range", "counts", map[string]any{"sku-1": -1}, true},
+		{"nested map values match", "order", map[string]any{"order_id": "o-1", "labels": map[string]any{"env": "prod"}}, false},
+		{"nested map value too long", "order", map[string]any{"order_id": "o-1", "labels": map[string]any{"env": "production"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: tt.tool, Arguments: tt.args})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantError, result.IsError, resultText(t, result))
+		})
+	}
+}
+
+func TestObjectPropertyCountConstraints(t *testing.T) {
+	tags := CreateMapSchemaWithConstraints(
+		"Resource tags",
+		&jsonschema.Schema{Type: "string"},
+		ObjectConstraints{MinProperties: ptr(1), MaxProperties: ptr(3)},
+	)
+	assert.Equal(t, ptr(1), tags.MinProperties)
+	assert.Equal(t, ptr(3), tags.MaxProperties)
+
+	schema := CreateDynamicSchema([]FieldDef{
+		{
+			Name:                 "headers",
+			Type:                 "object",
+			AdditionalProperties: &FieldDef{Type: "string"},
+			MaxProperties:        ptr(2),
+		},
+	})
+	assert.Equal(t, ptr(2), schema.Properties["headers"].MaxProperties)
+	assert.Nil(t, schema.Properties["headers"].MinProperties)
+
+	handler, err := NewHandler(
+		WithRawTool("tag", "Tag a resource", tags, rawFunc),
+		WithRawTool("request", "Send a request", schema, rawFunc),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	tests := []struct {
+		name      string
+		tool      string
+		args      map[string]any
+		wantError bool
+	}{
+		{"within bounds", "tag", map[string]any{"env": "prod", "team": "core"}, false},
+		{"at maximum", "tag", map[string]any{"a": "1", "b": "2", "c": "3"}, false},
+		{"too few", "tag", map[string]any{}, true},
+		{"too many", "tag", map[string]any{"a": "1", "b": "2", "c": "3", "d": "4"}, true},
+		{"nested within bounds", "request", map[string]any{"headers": map[string]any{"Accept": "json"}}, false},
+		{"nested too many", "request", map[string]any{"headers": map[string]any{"A": "1", "B": "2", "C": "3"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: tt.tool, Arguments: tt.args})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantError, result.IsError, resultText(t, result))
+		})
+	}
+}
+
+func TestCreateArraySchema(t *testing.T) {
+	tags := CreateArraySchema("Tags", &jsonschema.Schema{Type: "string"}, true)
+	assert.Equal(t, "array", tags.Type)
+	assert.True(t, tags.UniqueItems)
+	require.NotNil(t, tags.Items)
+	assert.Equal(t, "string", tags.Items.Type)
+
+	assert.False(t, CreateArraySchema("Readings", &jsonschema.Schema{Type: "number"}, false).UniqueItems)
+
+	schema := &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"tags":     tags,
+			"readings": CreateArraySchema("Readings", &jsonschema.Schema{Type: "number"}, false),
+		},
+	}
+	dynamic := CreateDynamicSchema([]FieldDef{
+		{Name: "ids", Type: "array", Items: &FieldDef{Type: "number"}, UniqueItems: true},
+	})
+	assert.True(t, dynamic.Properties["ids"].UniqueItems)
+
+	handler, err := NewHandler(
+		WithRawTool("tag", "Tag a resource", schema, rawFunc),
+		WithRawTool("lookup", "Look up records", dynamic, rawFunc),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	tests := []struct {
+		name      string
+		tool      string
+		args      map[string]any
+		wantError bool
+	}{
+		{"unique elements", "tag", map[string]any{"tags": []any{"a", "b"}}, false},
+		{"duplicate elements", "tag", map[string]any{"tags": []any{"a", "b", "a"}}, true},
+		{"duplicates allowed without uniqueItems", "tag", map[string]any{"readings": []any{1, 1}}, false},
+		{"field def unique elements", "lookup", map[string]any{"ids": []any{1, 2, 3}}, false},
+		{"field def duplicate elements", "lookup", map[string]any{"ids": []any{1, 2, 1}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: tt.tool, Arguments: tt.args})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantError, result.IsError, resultText(t, result))
+		})
+	}
+}
+
+func TestCreateArraySchemaContains(t *testing.T) {
+	admin := &jsonschema.Schema{Type: "string", Const: jsonschema.Ptr[any]("admin")}
+	roles := CreateArraySchemaWithConstraints("Roles", &jsonschema.Schema{Type: "string"}, ArrayConstraints{
+		Contains: admin,
+	})
+	assert.Same(t, admin, roles.Contains)
+	assert.False(t, roles.UniqueItems)
+
+	reviewers := CreateArraySchemaWithConstraints("Reviewers", &jsonschema.Schema{Type: "string"}, ArrayConstraints{
+		UniqueItems: true,
+		Contains:    &jsonschema.Schema{Type: "string", Pattern: "^lead-"},
+		MinContains: ptr(1),
+		MaxContains: ptr(2),
+	})
+
+	schema := &jsonschema.Schema{
+		Type:       "object",
+		Properties: map[string]*jsonschema.Schema{"roles": roles, "reviewers": reviewers},
+	}
+	handler, err := NewHandler(WithRawTool("assign", "Assign roles", schema, rawFunc))
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	tests := []struct {
+		name      string
+		args      map[string]any
+		wantError bool
+	}{
+		{"contains a matching element", map[string]any{"roles": []any{"viewer", "admin"}}, false},
+		{"no matching element", map[string]any{"roles": []any{"viewer", "editor"}}, true},
+		{"empty array", map[string]any{"roles": []any{}}, true},
+		{"within contains bounds", map[string]any{"reviewers": []any{"lead-ana", "bob", "lead-cy"}}, false},
+		{"too many matching elements", map[string]any{"reviewers": []any{"lead-ana", "lead-bo", "lead-cy"}}, true},
+		{"duplicates still rejected", map[string]any{"reviewers": []any{"lead-ana", "lead-ana"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "assign", Arguments: tt.args})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantError, result.IsError, resultText(t, result))
+		})
+	}
+}
+
+func TestCreateConditionalSchema(t *testing.T) {
+	base := CreateDynamicSchema([]FieldDef{
+		{Name: "operation", Type: "string", Required: true, Enum: []string{"add", "divide", "round"}},
+		{Name: "a", Type: "number", Required: true},
+		{Name: "b", Type: "number"},
+		{Name: "precision", Type: "number"},
+	})
+	nonZeroB := &jsonschema.Schema{
+		Properties: map[string]*jsonschema.Schema{"b": {Not: &jsonschema.Schema{Const: jsonschema.Ptr[any](0)}}},
+		Required:   []string{"b"},
+	}
+
+	single := CreateConditionalSchema(base, Conditional{
+		If:   CreateFieldEqualsSchema("operation", "divide"),
+		Then: nonZeroB,
+		Else: &jsonschema.Schema{Required: []string{"b"}},
+	})
+	assert.NotNil(t, single.If)
+	assert.Nil(t, base.If, "the base schema should not be modified")
+
+	multiple := CreateConditionalSchema(base,
+		Conditional{If: CreateFieldEqualsSchema("operation", "divide"), Then: nonZeroB},
+		Conditional{If: CreateFieldEqualsSchema("operation", "round"), Then: &jsonschema.Schema{Required: []string{"precision"}}},
+	)
+	assert.Nil(t, multiple.If)
+	assert.Len(t, multiple.AllOf, 2)
+
+	fromNil := CreateConditionalSchema(nil, Conditional{If: CreateFieldEqualsSchema("operation", "divide"), Then: nonZeroB})
+	require.NotNil(t, fromNil)
+	assert.Equal(t, nonZeroB, fromNil.Then)
+
+	handler, err := NewHandler(
+		WithRawTool("single", "Single condition", single, rawFunc),
+		WithRawTool("multiple", "Multiple conditions", multiple, rawFunc),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	tests := []struct {
+		name      string
+		tool      string
+		args      map[string]any
+		wantError bool
+	}{
+		{"then branch satisfied", "single", map[string]any{"operation": "divide", "a": 1, "b": 2}, false},
+		{"then branch zero divisor", "single", map[string]any{"operation": "divide", "a": 1, "b": 0}, true},
+		{"then branch missing divisor", "single", map[string]any{"operation": "divide", "a": 1}, true},
+		{"else branch satisfied", "single", map[string]any{"operation": "add", "a": 1, "b": 0}, false},
+		{"else branch missing field", "single", map[string]any{"operation": "add", "a": 1}, true},
+		{"first rule enforced", "multiple", map[string]any{"operation": "divide", "a": 1, "b": 0}, true},
+		{"second rule enforced", "multiple", map[string]any{"operation": "round", "a": 1.5}, true},
+		{"second rule satisfied", "multiple", map[string]any{"operation": "round", "a": 1.5, "precision": 0}, false},
+		{"no rule applies", "multiple", map[string]any{"operation": "add", "a": 1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: tt.tool, Arguments: tt.args})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantError, result.IsError, resultText(t, result))
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package mcpio
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// callTracker tracks in-flight tool calls, so shutdown can wait for them to drain
+// and CancelCall can find them. It is safe for concurrent use.
+type callTracker struct {
+	mu       sync.Mutex
+	draining bool
+	lastID   uint64
+	active   map[string]context.CancelCauseFunc // Keyed by call ID
+	// running counts entered calls whose tool function hasn't returned, which can
+	// outlast the call itself when a timeout gives up on the tool
+	running int
+	idle    chan struct{} // Closed once draining and nothing is running
+}
+
+func newCallTracker() *callTracker {
+	return &callTracker{
+		active: make(map[string]context.CancelCauseFunc),
+		idle:   make(chan struct{}),
+	}
+}
+
+// enter registers a new call, returning its ID and a context CancelCall can cancel.
+// It reports false once draining has started.
+func (t *callTracker) enter(ctx context.Context) (context.Context, string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return ctx, "", false
+	}
+	t.lastID++
+	id := strconv.FormatUint(t.lastID, 10)
+	ctx, cancel := context.WithCancelCause(context.WithValue(ctx, callIDContextKey{}, id))
+	t.active[id] = cancel
+	t.running++
+	return ctx, id, true
+}
+
+// exit marks a call registered with enter as finished, so it can no longer be
+// cancelled. Drains wait for done instead.
+func (t *callTracker) exit(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if cancel, ok := t.active[id]; ok {
+		cancel(nil)
+		delete(t.active, id)
+	}
+}
+
+// done marks the tool function of a call registered with enter as returned
+func (t *callTracker) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running--
+	if t.draining && t.running == 0 {
+		close(t.idle)
+	}
+}
+
+// cancel cancels the context of the in-flight call with the given ID, reporting
+// whether it was found
+func (t *callTracker) cancel(id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cancel, ok := t.active[id]
+	if ok {
+		cancel(ErrCallCancelled)
+	}
+	return ok
+}
+
+// drain stops new calls from entering and waits until the tool functions of
+// entered calls return or ctx is done
+func (t *callTracker) drain(ctx context.Context) error {
+	t.mu.Lock()
+	if !t.draining {
+		t.draining = true
+		if t.running == 0 {
+			close(t.idle)
+		}
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-t.idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown stops the handler from starting new tool calls and waits for calls
+// already running to finish, including tool functions still running after their
+// timeout. New calls fail with a tool error coded
+// "SHUTTING_DOWN". It returns ctx's error if the deadline passes first, in which
+// case calls may still be running. Sessions stay open, so pair it with
+// http.Server.Shutdown and then Close.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	return h.chain.calls.drain(ctx)
+}
+
+// callIDContextKey is the context key for the ID of the current tool call
+type callIDContextKey struct{}
+
+// CallIDFromContext returns the ID the handler assigned to the current tool call.
+// The IDs appear as "call_id" in WithLogger's records, and can be passed to
+// CancelCall. They are assigned by the handler because the MCP SDK doesn't expose
+// JSON-RPC request IDs to tools.
+func CallIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(callIDContextKey{}).(string)
+	return id, ok
+}
+
+// CancelCall cancels the context of the in-flight tool call with the given ID,
+// reporting whether such a call was found. If the tool then returns an error, the
+// call fails with a tool error coded "CANCELLED". Tools that ignore their context
+// keep running until they finish.
+func (h *Handler) CancelCall(callID string) bool {
+	return h.chain.calls.cancel(callID)
+}
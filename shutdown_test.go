@@ -0,0 +1,194 @@
+package mcpio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	slowFunc := func(ctx context.Context, input EchoInput) (EchoOutput, error) {
+		close(started)
+		<-release
+		return EchoOutput{Message: input.Text}, nil
+	}
+
+	handler, err := NewHandler(
+		WithTool("slow", "Blocks until released", slowFunc),
+		WithTool("echo", "Echo input", echoFunc),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	slowResult := make(chan *mcp.CallToolResult, 1)
+	go func() {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "slow",
+			Arguments: map[string]any{"text": "in flight"},
+		})
+		assert.NoError(t, err)
+		slowResult <- result
+	}()
+	<-started
+
+	shutdownDone := make(chan error, 1)
+	go func() {
+		shutdownDone <- handler.Shutdown(context.Background())
+	}()
+
+	// New calls are rejected while the slow call drains
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "echo",
+			Arguments: map[string]any{"text": "hi"},
+		})
+		if assert.NoError(c, err) {
+			assert.True(c, result.IsError)
+			assert.Equal(c, "SHUTTING_DOWN", result.Meta["errorCode"])
+		}
+	}, time.Second, 10*time.Millisecond)
+
+	select {
+	case <-shutdownDone:
+		t.Fatal("Shutdown returned while a call was in flight")
+	default:
+	}
+
+	close(release)
+	result := <-slowResult
+	assert.False(t, result.IsError)
+	assert.JSONEq(t, `{"message": "in flight"}`, resultText(t, result))
+	require.NoError(t, <-shutdownDone)
+
+	// Shutdown can be called again once drained
+	require.NoError(t, handler.Shutdown(context.Background()))
+}
+
+func TestHandlerShutdownWaitsForTimedOutTools(t *testing.T) {
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	// The tool ignores cancellation, so it keeps running after its timeout
+	stuckFunc := func(ctx context.Context, input EchoInput) (EchoOutput, error) {
+		defer close(finished)
+		<-release
+		return EchoOutput{Message: input.Text}, nil
+	}
+
+	handler, err := NewHandler(
+		WithTool("stuck", "Ignores cancellation", stuckFunc),
+		WithToolTimeout("stuck", 20*time.Millisecond),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "stuck",
+		Arguments: map[string]any{"text": "hi"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "TIMEOUT", result.Meta["errorCode"])
+
+	shutdownDone := make(chan error, 1)
+	go func() {
+		shutdownDone <- handler.Shutdown(context.Background())
+	}()
+
+	select {
+	case err := <-shutdownDone:
+		t.Fatalf("Shutdown returned while a timed out tool was running: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-shutdownDone)
+	select {
+	case <-finished:
+	default:
+		t.Fatal("Shutdown returned before the tool finished")
+	}
+}
+
+func TestHandlerShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	slowFunc := func(ctx context.Context, input EchoInput) (EchoOutput, error) {
+		close(started)
+		<-release
+		return EchoOutput{}, nil
+	}
+
+	handler, err := NewHandler(WithTool("slow", "Blocks until released", slowFunc))
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	go func() {
+		_, _ = session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "slow",
+			Arguments: map[string]any{"text": "stuck"},
+		})
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, handler.Shutdown(ctx), context.DeadlineExceeded)
+}
+
+func TestHandlerCancelCall(t *testing.T) {
+	callIDs := make(chan string, 1)
+	stuckFunc := func(ctx context.Context, input EchoInput) (EchoOutput, error) {
+		id, ok := CallIDFromContext(ctx)
+		if !ok {
+			return EchoOutput{}, NewToolError("no call ID")
+		}
+		callIDs <- id
+		<-ctx.Done()
+		return EchoOutput{}, ctx.Err()
+	}
+
+	handler, err := NewHandler(
+		WithTool("stuck", "Blocks until cancelled", stuckFunc),
+		WithTool("echo", "Echo input", echoFunc),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	stuckResult := make(chan *mcp.CallToolResult, 1)
+	go func() {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "stuck",
+			Arguments: map[string]any{"text": "hi"},
+		})
+		assert.NoError(t, err)
+		stuckResult <- result
+	}()
+	id := <-callIDs
+
+	assert.False(t, handler.CancelCall("unknown"))
+	assert.True(t, handler.CancelCall(id))
+
+	select {
+	case result := <-stuckResult:
+		assert.True(t, result.IsError)
+		assert.Equal(t, "CANCELLED", result.Meta["errorCode"])
+		assert.Contains(t, resultText(t, result), "call "+id+" was cancelled")
+	case <-time.After(time.Second):
+		t.Fatal("cancelled call did not return")
+	}
+	assert.False(t, handler.CancelCall(id), "finished calls can't be cancelled")
+
+	// Other calls are unaffected
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "echo",
+		Arguments: map[string]any{"text": "hi"},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+}
@@ -0,0 +1,177 @@
+package mcpio
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// SlowClientAction is what happens when a session's write buffer is full
+type SlowClientAction int
+
+const (
+	// SlowClientDropOldest discards the oldest queued notification, e.g. a progress
+	// or log message, to make room. Responses and requests are never dropped, since
+	// the peer waiting on them would hang; when only those are queued the session
+	// is closed as with SlowClientDisconnect.
+	SlowClientDropOldest SlowClientAction = iota + 1
+	// SlowClientDisconnect closes the session
+	SlowClientDisconnect
+)
+
+// SlowClientPolicy bounds how many messages may be queued for a client that reads
+// slower than the server writes
+type SlowClientPolicy struct {
+	BufferSize int // Messages queued per session before Action applies
+	Action     SlowClientAction
+}
+
+// validate reports whether the policy can be applied
+func (p SlowClientPolicy) validate() error {
+	if p.BufferSize <= 0 {
+		return fmt.Errorf("%w: buffer size %d", ErrInvalidSlowClientPolicy, p.BufferSize)
+	}
+	if p.Action != SlowClientDropOldest && p.Action != SlowClientDisconnect {
+		return fmt.Errorf("%w: unknown action %d", ErrInvalidSlowClientPolicy, p.Action)
+	}
+	return nil
+}
+
+// bufferedTransport applies a slow client policy to the connections of a transport
+type bufferedTransport struct {
+	mcp.Transport
+	policy SlowClientPolicy
+}
+
+// Connect implements mcp.Transport
+func (t *bufferedTransport) Connect(ctx context.Context) (mcp.Connection, error) {
+	conn, err := t.Transport.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newBufferedConn(conn, t.policy), nil
+}
+
+// bufferedConn queues outgoing messages so a slow client never blocks the server.
+// A single goroutine writes queued messages to the underlying connection in order.
+type bufferedConn struct {
+	mcp.Connection
+	action  SlowClientAction
+	size    int
+	pending chan struct{} // Signals the write loop that the queue is non-empty
+	done    chan struct{}
+
+	mu    sync.Mutex
+	queue []jsonrpc.Message
+	err   error // Set once writing has failed; later writes return it
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func newBufferedConn(conn mcp.Connection, policy SlowClientPolicy) *bufferedConn {
+	c := &bufferedConn{
+		Connection: conn,
+		action:     policy.Action,
+		size:       policy.BufferSize,
+		pending:    make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	go c.writeLoop()
+	return c
+}
+
+// writeLoop writes queued messages until the connection is closed or a write fails
+func (c *bufferedConn) writeLoop() {
+	for {
+		select {
+		case <-c.pending:
+		case <-c.done:
+			return
+		}
+		for {
+			msg, ok := c.next()
+			if !ok {
+				break
+			}
+			if err := c.Connection.Write(context.Background(), msg); err != nil {
+				c.fail(err)
+				return
+			}
+		}
+	}
+}
+
+// next removes and returns the oldest queued message
+func (c *bufferedConn) next() (jsonrpc.Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.queue) == 0 {
+		return nil, false
+	}
+	msg := c.queue[0]
+	c.queue[0] = nil
+	c.queue = c.queue[1:]
+	return msg, true
+}
+
+// Write implements mcp.Connection by queuing msg, applying the slow client policy
+// when the queue is full
+func (c *bufferedConn) Write(_ context.Context, msg jsonrpc.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+
+	if len(c.queue) >= c.size && !c.dropOldestNotification() {
+		c.err = ErrSlowClient
+		_ = c.Close()
+		return c.err
+	}
+
+	c.queue = append(c.queue, msg)
+	select {
+	case c.pending <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// dropOldestNotification removes the oldest queued notification when the policy
+// allows it, reporting whether one was removed. The caller must hold mu.
+func (c *bufferedConn) dropOldestNotification() bool {
+	if c.action != SlowClientDropOldest {
+		return false
+	}
+	for i, msg := range c.queue {
+		if req, ok := msg.(*jsonrpc.Request); ok && !req.ID.IsValid() {
+			c.queue = append(c.queue[:i], c.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fail records a write error and closes the connection
+func (c *bufferedConn) fail(err error) {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = err
+	}
+	c.mu.Unlock()
+	_ = c.Close()
+}
+
+// Close implements mcp.Connection. Queued messages that haven't been written are
+// discarded.
+func (c *bufferedConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.closeErr = c.Connection.Close()
+	})
+	return c.closeErr
+}
@@ -0,0 +1,217 @@
+package mcpio
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowConn is a connection whose writes block until the test releases them,
+// simulating a client that isn't reading
+type slowConn struct {
+	release chan struct{}
+	closed  chan struct{}
+	writing chan struct{} // Receives each time a write starts blocking
+
+	mu      sync.Mutex
+	written []jsonrpc.Message
+}
+
+func newSlowConn() *slowConn {
+	return &slowConn{
+		release: make(chan struct{}),
+		closed:  make(chan struct{}),
+		writing: make(chan struct{}, 16),
+	}
+}
+
+func (c *slowConn) Read(ctx context.Context) (jsonrpc.Message, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (c *slowConn) Write(_ context.Context, msg jsonrpc.Message) error {
+	select {
+	case c.writing <- struct{}{}:
+	default:
+	}
+	select {
+	case <-c.release:
+	case <-c.closed:
+		return ErrSlowClient
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.written = append(c.written, msg)
+	return nil
+}
+
+func (c *slowConn) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func (c *slowConn) SessionID() string { return "" }
+
+// waitWriting blocks until the writer is stuck on the client
+func (c *slowConn) waitWriting(t *testing.T) {
+	t.Helper()
+	select {
+	case <-c.writing:
+	case <-time.After(time.Second):
+		t.Fatal("no write started")
+	}
+}
+
+// writtenLabels names each written message by its label, see notification and
+// response
+func (c *slowConn) writtenLabels() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	labels := make([]string, len(c.written))
+	for i, msg := range c.written {
+		switch msg := msg.(type) {
+		case *jsonrpc.Request:
+			labels[i] = string(msg.Params)
+		case *jsonrpc.Response:
+			labels[i] = string(msg.Result)
+		}
+	}
+	return labels
+}
+
+// notification returns a progress notification labeled by its params
+func notification(label string) jsonrpc.Message {
+	return &jsonrpc.Request{Method: "notifications/progress", Params: json.RawMessage(label)}
+}
+
+// response returns a response labeled by its result
+func response(t *testing.T, id int64, label string) jsonrpc.Message {
+	t.Helper()
+	msgID, err := jsonrpc.MakeID(float64(id))
+	require.NoError(t, err)
+	return &jsonrpc.Response{ID: msgID, Result: json.RawMessage(label)}
+}
+
+func TestSlowClientPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("drop oldest", func(t *testing.T) {
+		inner := newSlowConn()
+		conn := newBufferedConn(inner, SlowClientPolicy{BufferSize: 2, Action: SlowClientDropOldest})
+
+		// The first message is taken by the writer, which then blocks on the client
+		require.NoError(t, conn.Write(ctx, notification("1")))
+		inner.waitWriting(t)
+
+		for _, label := range []string{"2", "3", "4", "5"} {
+			require.NoError(t, conn.Write(ctx, notification(label)))
+		}
+
+		close(inner.release)
+		require.Eventually(t, func() bool { return len(inner.writtenLabels()) == 3 }, time.Second, time.Millisecond)
+		assert.Equal(t, []string{"1", "4", "5"}, inner.writtenLabels())
+		require.NoError(t, conn.Close())
+	})
+
+	t.Run("drop oldest keeps responses", func(t *testing.T) {
+		inner := newSlowConn()
+		conn := newBufferedConn(inner, SlowClientPolicy{BufferSize: 2, Action: SlowClientDropOldest})
+
+		require.NoError(t, conn.Write(ctx, notification("1")))
+		inner.waitWriting(t)
+
+		// The response at the head of the queue survives; the notification behind
+		// it is dropped instead
+		require.NoError(t, conn.Write(ctx, response(t, 1, "2")))
+		require.NoError(t, conn.Write(ctx, notification("3")))
+		require.NoError(t, conn.Write(ctx, notification("4")))
+
+		close(inner.release)
+		require.Eventually(t, func() bool { return len(inner.writtenLabels()) == 3 }, time.Second, time.Millisecond)
+		assert.Equal(t, []string{"1", "2", "4"}, inner.writtenLabels())
+		require.NoError(t, conn.Close())
+	})
+
+	t.Run("drop oldest disconnects when only responses are queued", func(t *testing.T) {
+		inner := newSlowConn()
+		conn := newBufferedConn(inner, SlowClientPolicy{BufferSize: 2, Action: SlowClientDropOldest})
+
+		require.NoError(t, conn.Write(ctx, notification("1")))
+		inner.waitWriting(t)
+		require.NoError(t, conn.Write(ctx, response(t, 1, "2")))
+		require.NoError(t, conn.Write(ctx, response(t, 2, "3")))
+
+		err := conn.Write(ctx, notification("4"))
+		require.ErrorIs(t, err, ErrSlowClient)
+
+		select {
+		case <-inner.closed:
+		case <-time.After(time.Second):
+			t.Fatal("connection was not closed")
+		}
+		assert.Empty(t, inner.writtenLabels())
+	})
+
+	t.Run("disconnect", func(t *testing.T) {
+		inner := newSlowConn()
+		conn := newBufferedConn(inner, SlowClientPolicy{BufferSize: 1, Action: SlowClientDisconnect})
+
+		require.NoError(t, conn.Write(ctx, notification("1")))
+		inner.waitWriting(t)
+		require.NoError(t, conn.Write(ctx, notification("2")))
+
+		err := conn.Write(ctx, notification("3"))
+		require.ErrorIs(t, err, ErrSlowClient)
+		assert.ErrorIs(t, conn.Write(ctx, notification("4")), ErrSlowClient)
+
+		select {
+		case <-inner.closed:
+		case <-time.After(time.Second):
+			t.Fatal("connection was not closed")
+		}
+		assert.Empty(t, inner.writtenLabels())
+	})
+
+	t.Run("option errors", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			policy SlowClientPolicy
+		}{
+			{"zero buffer", SlowClientPolicy{BufferSize: 0, Action: SlowClientDropOldest}},
+			{"negative buffer", SlowClientPolicy{BufferSize: -1, Action: SlowClientDisconnect}},
+			{"missing action", SlowClientPolicy{BufferSize: 8}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := NewHandler(WithSlowClientPolicy(tt.policy))
+				require.ErrorIs(t, err, ErrInvalidSlowClientPolicy)
+			})
+		}
+	})
+
+	t.Run("websocket session", func(t *testing.T) {
+		handler, err := NewHandler(
+			WithTool("echo", "Echo input", echoFunc),
+			WithSlowClientPolicy(SlowClientPolicy{BufferSize: 4, Action: SlowClientDisconnect}),
+		)
+		require.NoError(t, err)
+		serverURL, _ := serveWebSocket(t, handler)
+		session := dialWebSocket(t, serverURL)
+
+		result, err := session.CallTool(ctx, &mcp.CallToolParams{
+			Name:      "echo",
+			Arguments: map[string]any{"text": "hi"},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+	})
+}
@@ -0,0 +1,117 @@
+package mcpio
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// latencyWindowSize is the number of recent calls per tool used to compute latency percentiles
+const latencyWindowSize = 1024
+
+// ToolStats summarizes the calls made to a single tool
+type ToolStats struct {
+	Calls   int64          // Total number of calls since the handler was created
+	Errors  int64          // Calls that returned a tool or protocol error
+	Latency LatencySummary // Latency percentiles over the most recent calls
+
+	LastError   string    // Message of the most recent error; empty if none
+	LastErrorAt time.Time // When the most recent error was recorded
+}
+
+// LatencySummary holds latency percentiles computed over a rolling window of recent calls
+type LatencySummary struct {
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+}
+
+// toolRecorder collects per-tool call statistics. It is safe for concurrent use.
+type toolRecorder struct {
+	mu    sync.Mutex
+	tools map[string]*toolCounters
+}
+
+// toolCounters holds the statistics for a single tool
+type toolCounters struct {
+	calls       int64
+	errors      int64
+	lastError   string
+	lastErrorAt time.Time
+	latencies   []time.Duration // Ring buffer of the most recent latencies
+	next        int             // Index the next latency is written to once the buffer is full
+}
+
+func newToolRecorder() *toolRecorder {
+	return &toolRecorder{tools: make(map[string]*toolCounters)}
+}
+
+// record adds a completed call to the named tool's statistics, including its error
+// if it failed
+func (r *toolRecorder) record(name string, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counters, ok := r.tools[name]
+	if !ok {
+		counters = &toolCounters{}
+		r.tools[name] = counters
+	}
+	counters.calls++
+	if err != nil {
+		counters.errors++
+		counters.lastError = err.Error()
+		counters.lastErrorAt = time.Now()
+	}
+	if len(counters.latencies) < latencyWindowSize {
+		counters.latencies = append(counters.latencies, latency)
+		return
+	}
+	counters.latencies[counters.next] = latency
+	counters.next = (counters.next + 1) % latencyWindowSize
+}
+
+// snapshot returns a copy of the statistics for every tool that has been called
+func (r *toolRecorder) snapshot() map[string]ToolStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make(map[string]ToolStats, len(r.tools))
+	for name, counters := range r.tools {
+		sorted := slices.Clone(counters.latencies)
+		slices.Sort(sorted)
+		stats[name] = ToolStats{
+			Calls:  counters.calls,
+			Errors: counters.errors,
+			Latency: LatencySummary{
+				P50: percentile(sorted, 50),
+				P95: percentile(sorted, 95),
+				P99: percentile(sorted, 99),
+			},
+			LastError:   counters.lastError,
+			LastErrorAt: counters.lastErrorAt,
+		}
+	}
+	return stats
+}
+
+// lastError returns the most recent error recorded for the named tool
+func (r *toolRecorder) lastError(name string) (string, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counters, ok := r.tools[name]
+	if !ok {
+		return "", time.Time{}
+	}
+	return counters.lastError, counters.lastErrorAt
+}
+
+// percentile returns the nearest-rank percentile p of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100 // ceil(p/100 * n)
+	return sorted[max(rank, 1)-1]
+}
@@ -0,0 +1,165 @@
+package mcpio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolRecorderPercentiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		latencies []time.Duration
+		want      LatencySummary
+	}{
+		{
+			name: "no calls",
+			want: LatencySummary{},
+		},
+		{
+			name:      "single call",
+			latencies: []time.Duration{5 * time.Millisecond},
+			want:      LatencySummary{P50: 5 * time.Millisecond, P95: 5 * time.Millisecond, P99: 5 * time.Millisecond},
+		},
+		{
+			name:      "one through one hundred",
+			latencies: millisecondRange(1, 100),
+			want:      LatencySummary{P50: 50 * time.Millisecond, P95: 95 * time.Millisecond, P99: 99 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := newToolRecorder()
+			for _, latency := range tt.latencies {
+				recorder.record("tool", latency, nil)
+			}
+
+			stats := recorder.snapshot()
+			if len(tt.latencies) == 0 {
+				assert.Empty(t, stats)
+				return
+			}
+			assert.Equal(t, int64(len(tt.latencies)), stats["tool"].Calls)
+			assert.Equal(t, tt.want, stats["tool"].Latency)
+		})
+	}
+
+	t.Run("window keeps most recent calls", func(t *testing.T) {
+		recorder := newToolRecorder()
+		for range latencyWindowSize {
+			recorder.record("tool", time.Second, nil)
+		}
+		for range latencyWindowSize {
+			recorder.record("tool", time.Millisecond, nil)
+		}
+
+		stats := recorder.snapshot()
+		assert.Equal(t, int64(2*latencyWindowSize), stats["tool"].Calls)
+		assert.Equal(t, time.Millisecond, stats["tool"].Latency.P99)
+	})
+}
+
+func millisecondRange(from, to int) []time.Duration {
+	latencies := make([]time.Duration, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	return latencies
+}
+
+func TestHandlerStats(t *testing.T) {
+	sleepFunc := func(ctx context.Context, input EchoInput) (EchoOutput, error) {
+		delay, err := time.ParseDuration(input.Text)
+		if err != nil {
+			return EchoOutput{}, ValidationError(err.Error())
+		}
+		time.Sleep(delay)
+		return EchoOutput{Message: input.Text}, nil
+	}
+
+	handler, err := NewHandler(
+		WithTool("sleep", "Sleep for the given duration", sleepFunc),
+		WithTool("echo", "Echo input", echoFunc),
+	)
+	require.NoError(t, err)
+	assert.Empty(t, handler.Stats())
+
+	session := connectTestClient(t, handler)
+	// Mostly fast calls with a slow tail
+	delays := []string{"1ms", "1ms", "1ms", "1ms", "1ms", "1ms", "1ms", "1ms", "1ms", "50ms"}
+	for _, delay := range delays {
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "sleep",
+			Arguments: map[string]any{"text": delay},
+		})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+	}
+
+	stats := handler.Stats()
+	require.Contains(t, stats, "sleep")
+	assert.NotContains(t, stats, "echo")
+
+	sleep := stats["sleep"]
+	assert.Equal(t, int64(len(delays)), sleep.Calls)
+	assert.GreaterOrEqual(t, sleep.Latency.P50, time.Millisecond)
+	assert.Less(t, sleep.Latency.P50, 40*time.Millisecond)
+	assert.GreaterOrEqual(t, sleep.Latency.P95, 50*time.Millisecond)
+	assert.GreaterOrEqual(t, sleep.Latency.P99, sleep.Latency.P95)
+}
+
+func TestHandlerLastError(t *testing.T) {
+	failFunc := func(ctx context.Context, input EchoInput) (EchoOutput, error) {
+		if input.Text == "ok" {
+			return EchoOutput{Message: input.Text}, nil
+		}
+		return EchoOutput{}, ProcessingError("failed on " + input.Text)
+	}
+
+	handler, err := NewHandler(
+		WithTool("flaky", "Fails unless given ok", failFunc),
+		WithTool("echo", "Echo input", echoFunc),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	call := func(t *testing.T, text string) {
+		t.Helper()
+		_, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "flaky",
+			Arguments: map[string]any{"text": text},
+		})
+		require.NoError(t, err)
+	}
+
+	message, at := handler.LastError("flaky")
+	assert.Empty(t, message)
+	assert.True(t, at.IsZero())
+
+	before := time.Now()
+	call(t, "first")
+	message, firstAt := handler.LastError("flaky")
+	assert.Equal(t, "[PROCESSING_ERROR] failed on first", message)
+	assert.False(t, firstAt.Before(before))
+
+	call(t, "second")
+	message, secondAt := handler.LastError("flaky")
+	assert.Equal(t, "[PROCESSING_ERROR] failed on second", message)
+	assert.False(t, secondAt.Before(firstAt))
+
+	// A successful call leaves the last error in place
+	call(t, "ok")
+	stats := handler.Stats()["flaky"]
+	assert.Equal(t, int64(3), stats.Calls)
+	assert.Equal(t, int64(2), stats.Errors)
+	assert.Equal(t, "[PROCESSING_ERROR] failed on second", stats.LastError)
+	assert.Equal(t, secondAt, stats.LastErrorAt)
+
+	message, _ = handler.LastError("echo")
+	assert.Empty(t, message, "tools that were never called have no error")
+}
@@ -0,0 +1,245 @@
+package mcpio
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// tsIdentifier matches property names that can be written unquoted in TypeScript
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// ExportTypeScript writes TypeScript interfaces for the input and output of every
+// registered tool, in registration order, e.g. CalculateInput and CalculateOutput
+// for a tool named "calculate". Fields not listed as required are optional, enums
+// become unions of literal types, and readOnly fields are marked readonly. Schema
+// keywords with no TypeScript equivalent, such as patterns and bounds, are dropped,
+// and schemas that can't be expressed become unknown.
+//
+// It returns ErrTypeNameCollision, writing nothing, if two tool names map to the
+// same type name, e.g. "get-user" and "get_user".
+func (h *Handler) ExportTypeScript(w io.Writer) error {
+	h.mu.RLock()
+	var b strings.Builder
+	b.WriteString("// Code generated by mcp-io. DO NOT EDIT.\n")
+	toolsByType := make(map[string]string, len(h.tools))
+	for _, info := range h.tools {
+		base := tsTypeName(info.Name)
+		if other, ok := toolsByType[base]; ok {
+			h.mu.RUnlock()
+			return fmt.Errorf("%w: %q and %q are both %s", ErrTypeNameCollision, other, info.Name, base)
+		}
+		toolsByType[base] = info.Name
+
+		writeTSInterface(&b, base+"Input", info.Description, info.InputSchema)
+		if outputSchema := h.outputSchema(info.Name); outputSchema != nil {
+			writeTSInterface(&b, base+"Output", "", outputSchema)
+		}
+	}
+	h.mu.RUnlock()
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// outputSchema returns the output schema of the named tool, generating it for typed
+// tools whose schema is left to the SDK. The caller must hold h.mu.
+func (h *Handler) outputSchema(name string) *jsonschema.Schema {
+	entry := h.entries[name]
+	switch {
+	case entry == nil:
+		return nil
+	case entry.tool.OutputSchema != nil:
+		return entry.tool.OutputSchema
+	case entry.outputType != nil:
+		return generateOutputSchemaFor(entry.outputType)
+	}
+	return nil
+}
+
+// writeTSInterface writes an exported interface for an object schema, or a type
+// alias for any other schema
+func writeTSInterface(b *strings.Builder, name, description string, schema *jsonschema.Schema) {
+	b.WriteString("\n")
+	writeTSComment(b, description, "")
+	if schema != nil && schema.Type == "object" && len(schema.Properties) > 0 &&
+		len(schema.OneOf) == 0 && len(schema.AnyOf) == 0 && len(schema.AllOf) == 0 {
+		fmt.Fprintf(b, "export interface %s ", name)
+		writeTSObject(b, schema, "")
+		b.WriteString("\n")
+		return
+	}
+	fmt.Fprintf(b, "export type %s = %s;\n", name, tsType(schema, ""))
+}
+
+// writeTSObject writes the members of an object schema as a brace-delimited type
+// literal, with properties sorted by name
+func writeTSObject(b *strings.Builder, schema *jsonschema.Schema, indent string) {
+	b.WriteString("{\n")
+	inner := indent + "  "
+	for _, name := range slices.Sorted(maps.Keys(schema.Properties)) {
+		property := schema.Properties[name]
+		description := ""
+		if property != nil {
+			description = property.Description
+		}
+		writeTSComment(b, description, inner)
+
+		b.WriteString(inner)
+		if property != nil && property.ReadOnly {
+			b.WriteString("readonly ")
+		}
+		if tsIdentifier.MatchString(name) {
+			b.WriteString(name)
+		} else {
+			quoted, _ := json.Marshal(name)
+			b.Write(quoted)
+		}
+		if !slices.Contains(schema.Required, name) {
+			b.WriteString("?")
+		}
+		fmt.Fprintf(b, ": %s;\n", tsType(property, inner))
+	}
+	b.WriteString(indent + "}")
+}
+
+// writeTSComment writes a description as a doc comment
+func writeTSComment(b *strings.Builder, description, indent string) {
+	if description == "" {
+		return
+	}
+	description = strings.ReplaceAll(description, "*/", `*\/`)
+	fmt.Fprintf(b, "%s/** %s */\n", indent, strings.ReplaceAll(description, "\n", " "))
+}
+
+// tsType returns the TypeScript type for a schema. indent is the indentation of the
+// line the type starts on, for nested object literals.
+func tsType(schema *jsonschema.Schema, indent string) string {
+	if schema == nil {
+		return "unknown"
+	}
+	if schema.Const != nil {
+		return tsLiteral(*schema.Const)
+	}
+	if len(schema.Enum) > 0 {
+		literals := make([]string, len(schema.Enum))
+		for i, value := range schema.Enum {
+			literals[i] = tsLiteral(value)
+		}
+		return strings.Join(literals, " | ")
+	}
+	if variants := append(slices.Clone(schema.OneOf), schema.AnyOf...); len(variants) > 0 {
+		return tsCombine(variants, " | ", indent)
+	}
+	if len(schema.AllOf) > 0 {
+		return tsCombine(schema.AllOf, " & ", indent)
+	}
+	if len(schema.Types) > 0 {
+		types := make([]string, len(schema.Types))
+		for i, typ := range schema.Types {
+			single := *schema
+			single.Types, single.Type = nil, typ
+			types[i] = tsType(&single, indent)
+		}
+		return strings.Join(types, " | ")
+	}
+
+	switch schema.Type {
+	case "string":
+		return "string"
+	case "number", "integer":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "null":
+		return "null"
+	case "array":
+		items := tsType(schema.Items, indent)
+		if tsIsCompound(schema.Items) {
+			items = "(" + items + ")"
+		}
+		return items + "[]"
+	case "object":
+		if len(schema.Properties) > 0 {
+			var b strings.Builder
+			writeTSObject(&b, schema, indent)
+			return b.String()
+		}
+		// Without an additionalProperties schema, keys matching no pattern may hold
+		// any value
+		if len(schema.PatternProperties) > 0 && schema.AdditionalProperties != nil {
+			values := make([]*jsonschema.Schema, 0, len(schema.PatternProperties)+1)
+			for _, pattern := range slices.Sorted(maps.Keys(schema.PatternProperties)) {
+				values = append(values, schema.PatternProperties[pattern])
+			}
+			if schema.AdditionalProperties.Not == nil {
+				values = append(values, schema.AdditionalProperties)
+			}
+			return "Record<string, " + tsCombine(values, " | ", indent) + ">"
+		}
+		return "Record<string, " + tsType(schema.AdditionalProperties, indent) + ">"
+	default:
+		return "unknown"
+	}
+}
+
+// tsCombine joins the types of several schemas with a union or intersection operator
+func tsCombine(schemas []*jsonschema.Schema, operator, indent string) string {
+	types := make([]string, len(schemas))
+	for i, schema := range schemas {
+		types[i] = tsType(schema, indent)
+		if strings.Contains(types[i], " | ") && operator != " | " {
+			types[i] = "(" + types[i] + ")"
+		}
+	}
+	return strings.Join(types, operator)
+}
+
+// tsIsCompound reports whether a schema's type is a union or intersection, which
+// needs parentheses as an array element type
+func tsIsCompound(schema *jsonschema.Schema) bool {
+	if schema == nil || schema.Const != nil {
+		return false
+	}
+	return len(schema.Enum) > 1 || len(schema.Types) > 1 ||
+		len(schema.OneOf)+len(schema.AnyOf) > 1 || len(schema.AllOf) > 1
+}
+
+// tsLiteral returns a JSON value as a TypeScript literal type
+func tsLiteral(value any) string {
+	literal, err := json.Marshal(value)
+	if err != nil {
+		return "unknown"
+	}
+	return string(literal)
+}
+
+// tsTypeName converts a tool name such as "get_user" or "get-user" to a TypeScript
+// type name such as "GetUser"
+func tsTypeName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	typeName := b.String()
+	if typeName == "" || unicode.IsDigit(rune(typeName[0])) {
+		typeName = "Tool" + typeName
+	}
+	return typeName
+}
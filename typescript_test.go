@@ -0,0 +1,135 @@
+package mcpio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingWriter rejects every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestExportTypeScript(t *testing.T) {
+	order := CreateDynamicSchema([]FieldDef{
+		{Name: "id", Type: "string", Description: "Order ID", Required: true},
+		{Name: "status", Type: "string", Enum: []string{"open", "closed"}},
+		{Name: "quantity", Type: "integer", Required: true},
+		{Name: "tags", Type: "array", Items: &FieldDef{Type: "string"}},
+		{Name: "note", Type: "string", Nullable: true},
+		{Name: "address", Type: "object", Required: true, Properties: []FieldDef{
+			{Name: "street", Type: "string", Required: true},
+			{Name: "postal-code", Type: "string"},
+		}},
+		{Name: "labels", Type: "object", AdditionalProperties: &FieldDef{Type: "number"}},
+		{Name: "sizes", Type: "array", Items: &FieldDef{Type: "string", Enum: []string{"s", "m"}}},
+	})
+	handler, err := NewHandler(
+		WithTool("calculate", "Perform arithmetic", calculateFunc),
+		WithRawTool("place-order", "Place an order", order, rawFunc),
+		WithRawTool("process", "", CreateObjectSchema("Raw input", nil, nil), rawFunc),
+	)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, handler.ExportTypeScript(&buf))
+	assert.Equal(t, `// Code generated by mcp-io. DO NOT EDIT.
+
+/** Perform arithmetic */
+export interface CalculateInput {
+  /** First number */
+  a: number;
+  /** Second number */
+  b: number;
+  /** Operation to perform */
+  operation: string;
+}
+
+export interface CalculateOutput {
+  /** Calculation result */
+  result: number;
+}
+
+/** Place an order */
+export interface PlaceOrderInput {
+  address: {
+    "postal-code"?: string;
+    street: string;
+  };
+  /** Order ID */
+  id: string;
+  labels?: Record<string, number>;
+  note?: string | null;
+  quantity: number;
+  sizes?: ("s" | "m")[];
+  status?: "open" | "closed";
+  tags?: string[];
+}
+
+export type ProcessInput = Record<string, unknown>;
+`, buf.String())
+
+	t.Run("unions", func(t *testing.T) {
+		action := CreateDiscriminatedUnionSchema("", "type", map[string]*jsonschema.Schema{
+			"move":   CreateDynamicSchema([]FieldDef{{Name: "to", Type: "string", Required: true}}),
+			"delete": nil,
+		})
+		assert.Equal(t, `{
+  type: "delete";
+} | {
+  to: string;
+  type: "move";
+}`, tsType(action, ""))
+		assert.Equal(t, "(number | null)[]", tsType(&jsonschema.Schema{Type: "array", Items: &jsonschema.Schema{Types: []string{"number", "null"}}}, ""))
+		assert.Equal(t, "unknown", tsType(&jsonschema.Schema{}, ""))
+	})
+
+	t.Run("pattern properties", func(t *testing.T) {
+		patterns := map[string]*jsonschema.Schema{"^a_": {Type: "string"}, "^b_": {Type: "number"}}
+		assert.Equal(t, "Record<string, string | number>", tsType(CreatePatternPropertiesSchema("", patterns, false), ""))
+		assert.Equal(t, "Record<string, unknown>", tsType(CreatePatternPropertiesSchema("", patterns, true), ""))
+	})
+
+	t.Run("type names", func(t *testing.T) {
+		assert.Equal(t, "GetUser", tsTypeName("get_user"))
+		assert.Equal(t, "UsersList", tsTypeName("users.list"))
+		assert.Equal(t, "Tool2fa", tsTypeName("2fa"))
+	})
+
+	t.Run("colliding type names", func(t *testing.T) {
+		handler, err := NewHandler(
+			WithTool("get-user", "Get a user", echoFunc),
+			WithTool("get_user", "Get a user", echoFunc),
+		)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.ErrorIs(t, handler.ExportTypeScript(&buf), ErrTypeNameCollision)
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("pointer output", func(t *testing.T) {
+		pointerFunc := func(ctx context.Context, input EchoInput) (*EchoOutput, error) {
+			return &EchoOutput{Message: input.Text}, nil
+		}
+		handler, err := NewHandler(WithTool("echo", "", pointerFunc))
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, handler.ExportTypeScript(&buf))
+		assert.Contains(t, buf.String(), "export interface EchoOutput {\n")
+		assert.Contains(t, buf.String(), "  message: string;\n")
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		require.Error(t, handler.ExportTypeScript(failingWriter{}))
+	})
+}
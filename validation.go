@@ -0,0 +1,217 @@
+package mcpio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// validateInput checks raw tool arguments against a resolved input schema.
+// Missing arguments are treated as an empty object so required fields are reported.
+// If the schema itself can't be applied, e.g. because it was modified after it was
+// resolved, the result is a processing error wrapping ErrSchemaResolution, so the
+// cause isn't shown to the client.
+func validateInput(inputSchema *jsonschema.Resolved, inputJSON []byte) (toolErr *ToolError) {
+	// The validator panics on schemas that don't match their resolved form
+	defer func() {
+		if r := recover(); r != nil {
+			toolErr = &ToolError{
+				Message: "input could not be validated because the tool's input schema is invalid",
+				Code:    "PROCESSING_ERROR",
+				Err:     fmt.Errorf("%w: %v", ErrSchemaResolution, r),
+			}
+		}
+	}()
+
+	var input any
+	if err := json.Unmarshal(inputJSON, &input); err != nil {
+		return ValidationError(fmt.Sprintf("invalid input JSON: %v", err))
+	}
+	if input == nil {
+		input = map[string]any{}
+	}
+	if err := inputSchema.Validate(input); err != nil {
+		return ValidationError(fmt.Sprintf("invalid input: %v", err))
+	}
+	if err := validateFormats(inputSchema.Schema(), input, ""); err != nil {
+		return ValidationError(fmt.Sprintf("invalid input: %v", err))
+	}
+	return nil
+}
+
+// semverPattern matches a semantic version with an optional "v" prefix
+var semverPattern = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// validateServerInfo checks the server name and version. Problems are errors under
+// strict validation, and otherwise logged as warnings when a logger is configured.
+func validateServerInfo(cfg *handlerConfig) error {
+	var problems []error
+	if strings.ContainsFunc(cfg.name, unicode.IsControl) {
+		problems = append(problems, fmt.Errorf("%w: %q", ErrInvalidName, cfg.name))
+	}
+	if !semverPattern.MatchString(cfg.version) {
+		problems = append(problems, fmt.Errorf("%w: %q", ErrInvalidVersion, cfg.version))
+	}
+
+	if cfg.strictValidation && len(problems) > 0 {
+		return problems[0]
+	}
+	if cfg.logger != nil {
+		for _, problem := range problems {
+			cfg.logger.LogAttrs(context.Background(), slog.LevelWarn, "invalid server info", slog.String("error", problem.Error()))
+		}
+	}
+	return nil
+}
+
+// uuidPattern matches the canonical 8-4-4-4-12 hex form of a UUID
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// formatCheckers validate the string formats that raw tool input is checked against.
+// The JSON Schema validator treats "format" as an annotation only; any format not
+// listed here is advertised to clients but not enforced.
+var formatCheckers = map[string]func(string) bool{
+	"email": func(s string) bool {
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	},
+	"uri": func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.IsAbs()
+	},
+	"uuid": uuidPattern.MatchString,
+}
+
+// validateFormats walks a schema alongside a decoded JSON instance and checks string
+// values against the known formats. It returns an error naming the first bad field.
+func validateFormats(schema *jsonschema.Schema, instance any, path string) error {
+	if schema == nil {
+		return nil
+	}
+
+	// Every allOf component applies to the whole instance
+	for _, component := range schema.AllOf {
+		if err := validateFormats(component, instance, path); err != nil {
+			return err
+		}
+	}
+
+	switch v := instance.(type) {
+	case string:
+		if check, ok := formatCheckers[schema.Format]; ok && !check(v) {
+			return fmt.Errorf("%s: %q is not a valid %s", path, v, schema.Format)
+		}
+	case map[string]any:
+		for key, child := range v {
+			for _, childSchema := range propertySchemas(schema, key) {
+				if err := validateFormats(childSchema, child, path+"/"+key); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		for i, child := range v {
+			if err := validateFormats(schema.Items, child, fmt.Sprintf("%s/%d", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// propertySchemas returns the schemas that apply to the value of an object key: its
+// property schema and those of every matching pattern, or else the schema for
+// additional properties
+func propertySchemas(schema *jsonschema.Schema, key string) []*jsonschema.Schema {
+	var schemas []*jsonschema.Schema
+	if property, ok := schema.Properties[key]; ok {
+		schemas = append(schemas, property)
+	}
+	for pattern, patternSchema := range schema.PatternProperties {
+		// Patterns were compiled when the schema was resolved
+		if re, err := regexp.Compile(pattern); err == nil && re.MatchString(key) {
+			schemas = append(schemas, patternSchema)
+		}
+	}
+	if len(schemas) == 0 {
+		schemas = append(schemas, schema.AdditionalProperties)
+	}
+	return schemas
+}
+
+// validateExamples checks every example in schema and its subschemas against the
+// schema it is attached to, returning an error naming one that doesn't conform
+func validateExamples(schema *jsonschema.Schema, path string) error {
+	if schema == nil {
+		return nil
+	}
+
+	if len(schema.Examples) > 0 {
+		resolved, err := schema.Resolve(nil)
+		if err != nil {
+			return fmt.Errorf("%s: %w", schemaPath(path), err)
+		}
+		for i, example := range schema.Examples {
+			if err := resolved.Validate(example); err != nil {
+				return fmt.Errorf("%s: example %d: %w", schemaPath(path), i, err)
+			}
+		}
+	}
+
+	for name, prop := range schema.Properties {
+		if err := validateExamples(prop, path+"/properties/"+name); err != nil {
+			return err
+		}
+	}
+	for pattern, prop := range schema.PatternProperties {
+		if err := validateExamples(prop, path+"/patternProperties/"+pattern); err != nil {
+			return err
+		}
+	}
+	for name, def := range schema.Defs {
+		if err := validateExamples(def, path+"/$defs/"+name); err != nil {
+			return err
+		}
+	}
+	subschemas := map[string][]*jsonschema.Schema{
+		"allOf":       schema.AllOf,
+		"anyOf":       schema.AnyOf,
+		"oneOf":       schema.OneOf,
+		"prefixItems": schema.PrefixItems,
+	}
+	for keyword, list := range subschemas {
+		for i, sub := range list {
+			if err := validateExamples(sub, fmt.Sprintf("%s/%s/%d", path, keyword, i)); err != nil {
+				return err
+			}
+		}
+	}
+	single := map[string]*jsonschema.Schema{
+		"items":                schema.Items,
+		"additionalProperties": schema.AdditionalProperties,
+		"not":                  schema.Not,
+	}
+	for keyword, sub := range single {
+		if err := validateExamples(sub, path+"/"+keyword); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// schemaPath formats a JSON pointer into a schema for error messages
+func schemaPath(path string) string {
+	if path == "" {
+		return "schema root"
+	}
+	return "schema " + path
+}
@@ -0,0 +1,279 @@
+package mcpio
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateInputFormats(t *testing.T) {
+	schema := CreateDynamicSchema([]FieldDef{
+		{Name: "email", Type: "string", Format: "email"},
+		{Name: "homepage", Type: "string", Format: "uri"},
+		{Name: "id", Type: "string", Format: "uuid"},
+		{Name: "contacts", Type: "array", Items: &FieldDef{Type: "string", Format: "email"}},
+		{Name: "when", Type: "string", Format: "date-time"},
+	})
+	resolved, err := schema.Resolve(nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "valid email", input: `{"email": "ada@example.com"}`},
+		{name: "invalid email", input: `{"email": "not-an-email"}`, wantErr: "/email"},
+		{name: "email with display name", input: `{"email": "Ada <ada@example.com>"}`, wantErr: "/email"},
+		{name: "valid uri", input: `{"homepage": "https://example.com/ada"}`},
+		{name: "relative uri", input: `{"homepage": "/ada"}`, wantErr: "/homepage"},
+		{name: "valid uuid", input: `{"id": "123e4567-e89b-12d3-a456-426614174000"}`},
+		{name: "invalid uuid", input: `{"id": "123e4567"}`, wantErr: "/id"},
+		{name: "invalid email in array", input: `{"contacts": ["ada@example.com", "nope"]}`, wantErr: "/contacts/1"},
+		{name: "unknown formats are not enforced", input: `{"when": "sometime"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toolErr := validateInput(resolved, []byte(tt.input))
+			if tt.wantErr == "" {
+				assert.Nil(t, toolErr)
+				return
+			}
+			require.NotNil(t, toolErr)
+			assert.Equal(t, "VALIDATION_ERROR", toolErr.Code)
+			assert.Contains(t, toolErr.Message, tt.wantErr)
+		})
+	}
+}
+
+func TestValidateInputNullArguments(t *testing.T) {
+	schema := CreateDynamicSchema([]FieldDef{
+		{Name: "name", Type: "string", Required: true},
+	})
+	resolved, err := schema.Resolve(nil)
+	require.NoError(t, err)
+
+	toolErr := validateInput(resolved, []byte("null"))
+	require.NotNil(t, toolErr)
+	assert.Contains(t, toolErr.Message, "name")
+}
+
+func TestWithValidateExamples(t *testing.T) {
+	schemaWithExamples := func(nameExamples, rootExamples []any) *jsonschema.Schema {
+		schema := CreateDynamicSchema([]FieldDef{
+			{Name: "name", Type: "string", Required: true, MinLength: ptr(2)},
+			{Name: "age", Type: "number"},
+		})
+		schema.Properties["name"].Examples = nameExamples
+		schema.Examples = rootExamples
+		return schema
+	}
+
+	tests := []struct {
+		name    string
+		schema  *jsonschema.Schema
+		wantErr string
+	}{
+		{
+			name:   "valid examples",
+			schema: schemaWithExamples([]any{"Ada"}, []any{map[string]any{"name": "Ada", "age": 36.0}}),
+		},
+		{
+			name:    "invalid property example",
+			schema:  schemaWithExamples([]any{"Ada", "A"}, nil),
+			wantErr: "/properties/name: example 1",
+		},
+		{
+			name:    "invalid root example",
+			schema:  schemaWithExamples(nil, []any{map[string]any{"age": 36.0}}),
+			wantErr: "schema root: example 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHandler(
+				WithRawTool("greet", "Greet a person", tt.schema, rawFunc),
+				WithValidateExamples(),
+			)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrInvalidExample)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			assert.Contains(t, err.Error(), `tool "greet"`)
+		})
+	}
+
+	t.Run("examples unchecked without the option", func(t *testing.T) {
+		_, err := NewHandler(WithRawTool("greet", "Greet a person", schemaWithExamples([]any{"A"}, nil), rawFunc))
+		require.NoError(t, err)
+	})
+}
+
+func TestWithStrictValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		wantErr  error
+		wantWarn int
+	}{
+		{"valid", []Option{WithName("files"), WithVersion("1.2.0")}, nil, 0},
+		{"prefixed prerelease version", []Option{WithVersion("v2.0.0-beta.1+build.5")}, nil, 0},
+		{"name with newline", []Option{WithName("files\nserver")}, ErrInvalidName, 1},
+		{"non-semver version", []Option{WithVersion("abc")}, ErrInvalidVersion, 1},
+		{"both invalid", []Option{WithName("files\t"), WithVersion("1.0")}, ErrInvalidName, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Run("lax", func(t *testing.T) {
+				logs := &recordingLogHandler{}
+				opts := append([]Option{WithLogger(slog.New(logs))}, tt.opts...)
+				handler, err := NewHandler(opts...)
+				require.NoError(t, err)
+				assert.NotNil(t, handler)
+
+				records := logs.attrs()
+				require.Len(t, records, tt.wantWarn)
+				for _, record := range records {
+					assert.Equal(t, "invalid server info", record["msg"].String())
+				}
+			})
+
+			t.Run("strict", func(t *testing.T) {
+				opts := append([]Option{WithStrictValidation(true)}, tt.opts...)
+				handler, err := NewHandler(opts...)
+				if tt.wantErr != nil {
+					require.ErrorIs(t, err, tt.wantErr)
+					assert.Nil(t, handler)
+					return
+				}
+				require.NoError(t, err)
+			})
+		})
+	}
+}
+
+func TestSchemaResolutionFailure(t *testing.T) {
+	schema := CreateObjectSchema("Lookup input", map[string]string{"id": "Record ID"}, nil)
+	logs := &recordingLogHandler{}
+	handler, err := NewHandler(
+		WithLogger(slog.New(logs)),
+		WithRawTool("lookup", "Look up a record", schema, rawFunc),
+	)
+	require.NoError(t, err)
+
+	// Changing the schema after it was resolved leaves the new property without the
+	// compiled pattern the validator expects
+	schema.Properties["code"] = &jsonschema.Schema{Type: "string", Pattern: "^[A-Z]+$"}
+
+	session := connectTestClient(t, handler)
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "lookup",
+		Arguments: map[string]any{"id": "1", "code": "ABC"},
+	})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Equal(t, "PROCESSING_ERROR", result.Meta["errorCode"])
+
+	text := resultText(t, result)
+	assert.Contains(t, text, "input schema is invalid")
+	assert.NotContains(t, text, "nil pointer", "the cause should not reach the client")
+
+	var logged []map[string]slog.Value
+	for _, record := range logs.attrs() {
+		if record["msg"].String() == "tool input schema could not be applied" {
+			logged = append(logged, record)
+		}
+	}
+	require.Len(t, logged, 1)
+	assert.Equal(t, "lookup", logged[0]["tool"].String())
+	assert.Contains(t, logged[0]["error"].String(), "nil pointer")
+
+	t.Run("validateInput wraps the cause", func(t *testing.T) {
+		resolved, err := CreateObjectSchema("Input", map[string]string{"id": "Record ID"}, nil).Resolve(nil)
+		require.NoError(t, err)
+		resolved.Schema().Properties["code"] = &jsonschema.Schema{Pattern: "^[A-Z]+$"}
+
+		toolErr := validateInput(resolved, []byte(`{"code": "ABC"}`))
+		require.NotNil(t, toolErr)
+		assert.Equal(t, "PROCESSING_ERROR", toolErr.Code)
+		assert.ErrorIs(t, toolErr, ErrSchemaResolution)
+	})
+}
+
+func TestWithFieldMaxBytes(t *testing.T) {
+	schema := CreateObjectSchema("Raw input", map[string]string{"data": "Input data"}, []string{"data"})
+	handler, err := NewHandler(
+		WithTool("echo", "Echo input", echoFunc),
+		WithRawTool("process", "Process raw data", schema, rawFunc),
+		WithFieldMaxBytes("echo", "text", 6),
+		WithFieldMaxBytes("process", "data", 3),
+	)
+	require.NoError(t, err)
+	session := connectTestClient(t, handler)
+
+	tests := []struct {
+		name    string
+		tool    string
+		args    map[string]any
+		wantErr bool
+	}{
+		{"ascii under limit", "echo", map[string]any{"text": "hello"}, false},
+		{"two-byte runes at limit", "echo", map[string]any{"text": "héllo"}, false},
+		{"three-byte runes at limit", "echo", map[string]any{"text": "日本"}, false},
+		{"three-byte runes over limit", "echo", map[string]any{"text": "日本語"}, true},
+		{"fewer characters than limit but more bytes", "echo", map[string]any{"text": "héllo!"}, true},
+		{"raw tool at limit", "process", map[string]any{"data": "€"}, false},
+		{"raw tool over limit", "process", map[string]any{"data": "€€"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+				Name:      tt.tool,
+				Arguments: tt.args,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantErr, result.IsError, resultText(t, result))
+			if tt.wantErr {
+				assert.Equal(t, "VALIDATION_ERROR", result.Meta["errorCode"])
+				assert.Contains(t, resultText(t, result), "exceeding the limit")
+			}
+		})
+	}
+
+	t.Run("option errors", func(t *testing.T) {
+		optionTests := []struct {
+			name    string
+			opt     Option
+			wantErr error
+		}{
+			{"empty tool name", WithFieldMaxBytes("", "text", 1), ErrEmptyToolName},
+			{"empty field name", WithFieldMaxBytes("echo", "", 1), ErrEmptyFieldName},
+			{"zero limit", WithFieldMaxBytes("echo", "text", 0), ErrInvalidByteLimit},
+			{"unknown tool", WithFieldMaxBytes("missing", "text", 1), ErrToolNotFound},
+			{"unknown field", WithFieldMaxBytes("echo", "missing", 1), ErrFieldNotFound},
+			{"non-string field", WithFieldMaxBytes("calculate", "a", 1), ErrInvalidSchema},
+		}
+
+		for _, tt := range optionTests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := NewHandler(
+					WithTool("echo", "Echo input", echoFunc),
+					WithTool("calculate", "Perform arithmetic", calculateFunc),
+					tt.opt,
+				)
+				require.ErrorIs(t, err, tt.wantErr)
+			})
+		}
+	})
+}
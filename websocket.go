@@ -0,0 +1,143 @@
+package mcpio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"golang.org/x/net/websocket"
+)
+
+// ServeWebSocket upgrades the request to a WebSocket connection and serves one MCP
+// session over it, with each JSON-RPC message sent as a text frame. It blocks until
+// the client disconnects or the handler is closed. Ping frames are answered
+// automatically. Requests without a valid Origin header are rejected with 403, as
+// are origins WithCORS doesn't allow, since browsers don't preflight WebSocket
+// handshakes. Incoming messages are limited to WithMaxRequestBytes; a larger one
+// ends the session.
+//
+// It returns ErrWebSocketHandshake if the connection could not be upgraded, or
+// ErrUnauthorized if WithHTTPAuth rejected the request, in which case an error
+// response has already been written.
+func (h *Handler) ServeWebSocket(w http.ResponseWriter, r *http.Request) error {
+	if h.closed.Load() {
+		http.Error(w, "mcp handler is closed", http.StatusServiceUnavailable)
+		return ErrHandlerClosed
+	}
+	r, ok := h.authorize(w, r)
+	if !ok {
+		return ErrUnauthorized
+	}
+
+	var (
+		upgraded bool
+		serveErr error
+	)
+	websocket.Server{
+		Handshake: h.checkWebSocketOrigin,
+		Handler: func(conn *websocket.Conn) {
+			upgraded = true
+			if h.maxRequestBytes > 0 {
+				conn.MaxPayloadBytes = int(h.maxRequestBytes)
+			}
+			serveErr = h.serveWebSocketConn(r.Context(), conn)
+		},
+	}.ServeHTTP(w, r)
+
+	if !upgraded {
+		return ErrWebSocketHandshake
+	}
+	return serveErr
+}
+
+// checkWebSocketOrigin requires a valid Origin header, as websocket.Handler does,
+// that the CORS policy allows when one is set
+func (h *Handler) checkWebSocketOrigin(config *websocket.Config, r *http.Request) error {
+	origin, err := websocket.Origin(config, r)
+	if err != nil {
+		return err
+	}
+	if origin == nil {
+		return errors.New("missing origin")
+	}
+	config.Origin = origin
+	if h.cors != nil && !h.cors.allowsOrigin(r.Header.Get("Origin")) {
+		return fmt.Errorf("origin %q is not allowed", r.Header.Get("Origin"))
+	}
+	return nil
+}
+
+// serveWebSocketConn runs a server session over an upgraded connection until it ends
+func (h *Handler) serveWebSocketConn(ctx context.Context, conn *websocket.Conn) error {
+	var transport mcp.Transport = &WebSocketTransport{Conn: conn}
+	if h.slowClientPolicy != nil {
+		transport = &bufferedTransport{Transport: transport, policy: *h.slowClientPolicy}
+	}
+	session, err := h.server.Connect(ctx, transport, nil)
+	if err != nil {
+		return err
+	}
+	return session.Wait()
+}
+
+// WebSocketTransport is an mcp.Transport over an established WebSocket connection.
+// The server side of ServeWebSocket uses it, and clients can use it with
+// mcp.Client.Connect after dialing with websocket.Dial.
+type WebSocketTransport struct {
+	Conn *websocket.Conn
+}
+
+// Connect implements mcp.Transport
+func (t *WebSocketTransport) Connect(context.Context) (mcp.Connection, error) {
+	if t.Conn == nil {
+		return nil, ErrNilConnection
+	}
+	return &websocketConn{conn: t.Conn}, nil
+}
+
+// websocketConn is an mcp.Connection that exchanges one JSON-RPC message per frame
+type websocketConn struct {
+	conn      *websocket.Conn
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Read implements mcp.Connection. Closing the connection unblocks a pending Read.
+func (c *websocketConn) Read(context.Context) (jsonrpc.Message, error) {
+	var data []byte
+	if err := websocket.Message.Receive(c.conn, &data); err != nil {
+		return nil, err
+	}
+	return jsonrpc.DecodeMessage(data)
+}
+
+// Write implements mcp.Connection
+func (c *websocketConn) Write(_ context.Context, msg jsonrpc.Message) error {
+	data, err := jsonrpc.EncodeMessage(msg)
+	if err != nil {
+		return err
+	}
+	// Send as a text frame; x/net/websocket serializes concurrent writers
+	return websocket.Message.Send(c.conn, string(data))
+}
+
+// Close implements mcp.Connection
+func (c *websocketConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.conn.Close()
+		if errors.Is(c.closeErr, net.ErrClosed) {
+			c.closeErr = nil
+		}
+	})
+	return c.closeErr
+}
+
+// SessionID implements mcp.Connection. WebSocket sessions have no transport-level ID.
+func (c *websocketConn) SessionID() string {
+	return ""
+}
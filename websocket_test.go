@@ -0,0 +1,145 @@
+package mcpio
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// serveWebSocket serves the handler's WebSocket endpoint and reports the result of
+// each ServeWebSocket call on the returned channel
+func serveWebSocket(t *testing.T, h *Handler) (string, <-chan error) {
+	t.Helper()
+	results := make(chan error, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		results <- h.ServeWebSocket(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, results
+}
+
+// dialWebSocket connects an MCP client session to a WebSocket endpoint
+func dialWebSocket(t *testing.T, serverURL string) *mcp.ClientSession {
+	t.Helper()
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(serverURL, "http"), "", serverURL)
+	require.NoError(t, err)
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "ws-client", Version: "1.0.0"}, nil)
+	session, err := client.Connect(context.Background(), &WebSocketTransport{Conn: conn}, nil)
+	require.NoError(t, err)
+	return session
+}
+
+func TestServeWebSocket(t *testing.T) {
+	handler, err := NewHandler(
+		WithName("ws-server"),
+		WithTool("echo", "Echo input", echoFunc),
+	)
+	require.NoError(t, err)
+	serverURL, results := serveWebSocket(t, handler)
+
+	t.Run("initialize and call a tool", func(t *testing.T) {
+		session := dialWebSocket(t, serverURL)
+		assert.Equal(t, "ws-server", session.InitializeResult().ServerInfo.Name)
+
+		require.NoError(t, session.Ping(context.Background(), nil))
+
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "echo",
+			Arguments: map[string]any{"text": "over a socket"},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+		assert.JSONEq(t, `{"message": "over a socket"}`, resultText(t, result))
+
+		// Disconnecting ends the server side of the session
+		require.NoError(t, session.Close())
+		select {
+		case err := <-results:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("ServeWebSocket did not return after the client disconnected")
+		}
+	})
+
+	t.Run("plain HTTP request", func(t *testing.T) {
+		resp, err := http.Get(serverURL)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.ErrorIs(t, <-results, ErrWebSocketHandshake)
+	})
+
+	t.Run("handler closed", func(t *testing.T) {
+		session := dialWebSocket(t, serverURL)
+		require.NoError(t, handler.Close())
+
+		select {
+		case <-results:
+		case <-time.After(5 * time.Second):
+			t.Fatal("ServeWebSocket did not return after the handler was closed")
+		}
+		assert.Error(t, session.Ping(context.Background(), nil))
+
+		_, err := websocket.Dial("ws"+strings.TrimPrefix(serverURL, "http"), "", serverURL)
+		require.Error(t, err)
+		assert.ErrorIs(t, <-results, ErrHandlerClosed)
+	})
+}
+
+func TestServeWebSocketOrigin(t *testing.T) {
+	handler, err := NewHandler(
+		WithTool("echo", "Echo input", echoFunc),
+		WithCORS(CORSOptions{AllowedOrigins: []string{"https://app.example.com"}}),
+	)
+	require.NoError(t, err)
+	serverURL, results := serveWebSocket(t, handler)
+	wsURL := "ws" + strings.TrimPrefix(serverURL, "http")
+
+	t.Run("allowed origin", func(t *testing.T) {
+		conn, err := websocket.Dial(wsURL, "", "https://app.example.com")
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+		<-results
+	})
+
+	t.Run("other origin", func(t *testing.T) {
+		_, err := websocket.Dial(wsURL, "", "https://evil.example.com")
+		require.Error(t, err)
+		assert.ErrorIs(t, <-results, ErrWebSocketHandshake)
+	})
+}
+
+func TestServeWebSocketMaxRequestBytes(t *testing.T) {
+	handler, err := NewHandler(WithTool("echo", "Echo input", echoFunc), WithMaxRequestBytes(1024))
+	require.NoError(t, err)
+	serverURL, results := serveWebSocket(t, handler)
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(serverURL, "http"), "", serverURL)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	message := fmt.Sprintf(`{"jsonrpc":"2.0","method":"notifications/initialized","params":{"pad":%q}}`, strings.Repeat("x", 2048))
+	require.NoError(t, websocket.Message.Send(conn, message))
+
+	// The oversized message ends the session
+	select {
+	case <-results:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeWebSocket did not return after an oversized message")
+	}
+}
+
+func TestWebSocketTransportNilConn(t *testing.T) {
+	_, err := (&WebSocketTransport{}).Connect(context.Background())
+	require.ErrorIs(t, err, ErrNilConnection)
+}